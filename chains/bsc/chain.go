@@ -95,7 +95,7 @@ func assembleProof(m *chain.Messenger, log *types.Log, proofType int64, toChainI
 		receipts = v
 		m.Log.Info("use cache receipt", "latestBlock ", bigNumber, "txHash", log.TxHash)
 	} else {
-		receipts, err = tx.GetReceiptsByTxsHash(m.Conn.Client(), txsHash)
+		receipts, err = tx.GetReceiptsByBlockNumber(m.Conn.Client(), bigNumber, txsHash)
 		if err != nil {
 			return nil, fmt.Errorf("unable to get receipts hashes Logs: %w", err)
 		}
@@ -112,6 +112,10 @@ func assembleProof(m *chain.Messenger, log *types.Log, proofType int64, toChainI
 		headers[i] = *header
 	}
 
+	if err = m.CheckReceiptsRoot(bigNumber, headers[0].ReceiptHash); err != nil {
+		return nil, err
+	}
+
 	params := make([]bsc.Header, 0, len(headers))
 	for _, h := range headers {
 		params = append(params, bsc.ConvertHeader(h))
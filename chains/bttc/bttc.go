@@ -1,6 +1,7 @@
 package bttc
 
 import (
+	"fmt"
 	"github.com/mapprotocol/compass/internal/constant"
 	"github.com/mapprotocol/compass/internal/proof"
 	"math/big"
@@ -80,6 +81,10 @@ func AssembleProof(headers []BlockHeader, txIndex uint, fId msg.ChainId, allR []
 	if err != nil {
 		return nil, err
 	}
+	// the state sync receipts are not part of the receipts root
+	if err = proof.Verify(cullSys, types.Receipts(cullSys), common.BytesToHash(headers[0].ReceiptsRoot), txIndex, prf); err != nil {
+		return nil, fmt.Errorf("verify receipt proof of block %s: %w", headers[0].Number, err)
+	}
 
 	var key []byte
 	key = rlp.AppendUint64(key[:0], uint64(txIndex))
@@ -8,7 +8,6 @@ import (
 	"io"
 	"math/big"
 	"net/http"
-	"time"
 
 	"github.com/ChainSafe/log15"
 	"github.com/ethereum/go-ethereum/common"
@@ -121,6 +120,10 @@ func mosHandler(m *chain.Messenger, latestBlock *big.Int) (int, error) {
 				headers[i] = tmp
 			}
 
+			if err = m.CheckReceiptsRoot(latestBlock, headers[0].ReceiptHash); err != nil {
+				return 0, err
+			}
+
 			mHeaders := make([]BlockHeader, 0, len(headers))
 			for _, h := range headers {
 				mHeaders = append(mHeaders, convertHeader(h))
@@ -160,9 +163,6 @@ func getReceiptsAndTxs(m *chain.Messenger, txsHash []common.Hash) ([]*types.Rece
 			}
 			return nil, nil, err
 		}
-		if len(txsHash) > 1000 {
-			time.Sleep(time.Millisecond * 10)
-		}
 		rs = append(rs, r)
 
 		oneTx, _, err := m.Conn.Client().TransactionByHash(context.Background(), h)
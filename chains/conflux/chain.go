@@ -25,7 +25,7 @@ var (
 )
 
 func InitializeChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr chan<- error, role mapprotocol.Role) (core.Chain, error) {
-	client, err := conflux.NewClient(chainCfg.Opts[chain.Eth2Url])
+	client, err := conflux.NewClient(chainCfg.Name, chainCfg.Opts[chain.Eth2Url])
 	if err != nil {
 		panic("conflux init client failed" + err.Error())
 	}
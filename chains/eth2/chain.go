@@ -1,13 +1,13 @@
 package eth2
 
 import (
+	"context"
 	"github.com/ChainSafe/log15"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/mapprotocol/compass/chains"
 	"github.com/mapprotocol/compass/connections/eth2"
 	"github.com/mapprotocol/compass/core"
 	"github.com/mapprotocol/compass/internal/chain"
-	"github.com/mapprotocol/compass/keystore"
 	"github.com/mapprotocol/compass/mapprotocol"
 	"github.com/mapprotocol/compass/msg"
 	"github.com/mapprotocol/compass/pkg/abi"
@@ -32,26 +32,32 @@ func InitializeChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr cha
 		return nil, err
 	}
 
-	kpI, err := keystore.KeypairFromEth(cfg.KeystorePath)
+	chain.SetupEndpoints(cfg)
+	keys, err := chain.LoadSigners(context.Background(), cfg)
 	if err != nil {
 		return nil, err
 	}
+	kpI := chain.ConnKey(keys[0])
 	//kp, _ := kpI.(*secp256k1.Keypair)
 	bs, err := chain.SetupBlockStore(cfg, role)
 	if err != nil {
 		return nil, err
 	}
+	jn, err := chain.SetupJournal(cfg, role)
+	if err != nil {
+		return nil, err
+	}
 
 	stop := make(chan int)
 	conn := eth2.NewConnection(cfg.Endpoint, cfg.Eth2Endpoint, cfg.Http, kpI, logger, cfg.GasLimit, cfg.MaxGasPrice,
-		cfg.GasMultiplier)
-	err = conn.Connect()
+		cfg.GasMultiplier, cfg.ConnOpts())
+	err = conn.Connect(context.Background())
 	if err != nil {
 		return nil, err
 	}
 
 	if chainCfg.LatestBlock {
-		curr, err := conn.LatestBlock()
+		curr, err := conn.LatestBlock(context.Background())
 		if err != nil {
 			return nil, err
 		}
@@ -66,6 +72,7 @@ func InitializeChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr cha
 		fn := mapprotocol.Map2EthHeight(cfg.From, cfg.LightNode, conn.Client())
 		height, err := fn()
 		if err != nil {
+			cs.Close()
 			return nil, errors.Wrap(err, "eth2 get init headerHeight failed")
 		}
 		logger.Info("map2eth2 Current situation", "height", height, "lightNode", cfg.LightNode)
@@ -80,7 +87,7 @@ func InitializeChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr cha
 	case mapprotocol.RoleOfOracle:
 		listen = chain.NewOracle(cs)
 	}
-	wri := chain.NewWriter(conn, cfg, logger, stop, sysErr)
+	wri := chain.NewWriter(conn, cfg, keys, jn, logger, stop, sysErr)
 
 	return &Chain{
 		cfg:    chainCfg,
@@ -97,7 +104,12 @@ func (c *Chain) SetRouter(r *core.Router) {
 }
 
 func (c *Chain) Start() error {
-	err := c.listen.Sync()
+	err := c.writer.Start()
+	if err != nil {
+		return err
+	}
+
+	err = c.listen.Sync()
 	if err != nil {
 		return err
 	}
@@ -13,6 +13,9 @@ import (
 	"github.com/mapprotocol/compass/internal/constant"
 	"github.com/mapprotocol/compass/internal/eth2"
 	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/alert"
+	"github.com/mapprotocol/compass/pkg/health"
+	"github.com/mapprotocol/compass/pkg/metrics"
 	"github.com/mapprotocol/compass/pkg/util"
 
 	"github.com/mapprotocol/compass/internal/chain"
@@ -36,7 +39,9 @@ func NewMaintainer(cs *chain.CommonSync, eth2Client *eth2.Client) *Maintainer {
 func (m *Maintainer) Sync() error {
 	m.Log.Debug("Starting listener...")
 	go func() {
+		health.Started(m.Cfg.Id)
 		err := m.sync()
+		health.Stopped(m.Cfg.Id, err)
 		if err != nil {
 			m.Log.Error("Polling blocks failed", "err", err)
 		}
@@ -50,6 +55,7 @@ func (m *Maintainer) Sync() error {
 // a block will be retried up to BlockRetryLimit times before continuing to the next block.
 func (m *Maintainer) sync() error {
 	if !m.Cfg.SyncToMap {
+		health.Idle(m.Cfg.Id)
 		time.Sleep(time.Hour * 2400)
 		return nil
 	}
@@ -94,9 +100,11 @@ func (m *Maintainer) sync() error {
 				if err != nil {
 					m.Log.Error("updateHeaders failed", "err", err)
 					time.Sleep(constant.QueryRetryInterval)
-					util.Alarm(context.Background(), fmt.Sprintf("eth2 sync header failed, err is %s", err.Error()))
+					alert.Fire(context.Background(), alert.Warning, m.Cfg.Name, alert.KeyHeaderSync,
+						fmt.Sprintf("eth2 sync header failed, err is %s", err.Error()))
 					continue
 				}
+				alert.Resolve(context.Background(), m.Cfg.Name, alert.KeyHeaderSync)
 			}
 
 			resp, err := m.eth2Client.BeaconHeaders(context.Background(), constant.FinalBlockIdOfEth2)
@@ -118,28 +126,36 @@ func (m *Maintainer) sync() error {
 				continue
 			}
 
-			latestBlock, err := m.Conn.LatestBlock()
+			if m.Disconnected() {
+				continue
+			}
+			latestBlock, err := m.Conn.LatestBlock(m.Ctx)
 			if err != nil {
 				m.Log.Error("Unable to get latest block", "block", currentBlock, "err", err)
 				time.Sleep(constant.BlockRetryInterval)
 				continue
 			}
+			metrics.Head(m.Cfg.Id, latestBlock)
 
 			err = m.sendRegularLightClientUpdate(lastFinalizedSlotOnContract, lastFinalizedSlotOnEth)
 			if err != nil {
 				m.Log.Error("Failed to listen header for block", "block", currentBlock, "err", err)
 				if !errors.Is(err, constant.ErrUnWantedSync) {
-					util.Alarm(context.Background(), fmt.Sprintf("eth2 sync lightClient failed, err is %s", err.Error()))
+					alert.Fire(context.Background(), alert.Warning, m.Cfg.Name, alert.KeyLightClient,
+						fmt.Sprintf("eth2 sync lightClient failed, err is %s", err.Error()))
 				}
 				time.Sleep(constant.BlockRetryInterval)
 				continue
 			}
+			alert.Resolve(context.Background(), m.Cfg.Name, alert.KeyLightClient)
 
 			// Write to block store. Not a critical operation, no need to retry
 			err = m.BlockStore.StoreBlock(currentBlock)
 			if err != nil {
 				m.Log.Error("Failed to write latest block to blockstore", "block", currentBlock, "err", err)
 			}
+			metrics.Processed(m.Cfg.Id, currentBlock)
+			health.Processed(m.Cfg.Id)
 
 			currentBlock.Add(currentBlock, big.NewInt(1))
 			if latestBlock.Int64()-currentBlock.Int64() <= m.Cfg.BlockConfirmations.Int64() {
@@ -282,18 +298,7 @@ func (m *Maintainer) getFinalityLightClientUpdate(lastFinalizedSlotOnContract *b
 			return nil, err
 		}
 	} else {
-		block, err := m.eth2Client.GetBlocks(context.Background(), resp.Data.FinalizedHeader.Beacon.Slot)
-		if err != nil {
-			return nil, err
-		}
-		branches, txRoot, wdRoot, err := eth2.Generate(resp.Data.FinalizedHeader.Beacon.Slot, m.Cfg.Eth2Endpoint)
-		if err != nil {
-			return nil, err
-		}
-		exeFinalityBranch = branches
-		block.Data.Message.Body.ExecutionPayload.TransactionsRoot = txRoot
-		block.Data.Message.Body.ExecutionPayload.WithdrawalsRoot = wdRoot
-		execution, err = eth2.ConvertExecution(&block.Data.Message.Body.ExecutionPayload)
+		exeFinalityBranch, execution, err = m.executionProof(&resp.Data.FinalizedHeader.Beacon)
 		if err != nil {
 			return nil, err
 		}
@@ -330,6 +335,30 @@ func (m *Maintainer) getFinalityLightClientUpdate(lastFinalizedSlotOnContract *b
 	}, nil
 }
 
+// executionProof proves the execution payload of the finalized block against the body root of its header, for
+// updates whose finalized header carries no execution branch
+func (m *Maintainer) executionProof(beacon *eth2.Beacon) ([][32]byte, *eth2.ContractExecution, error) {
+	block, err := m.eth2Client.GetBlocks(m.Ctx, beacon.Slot)
+	if err != nil {
+		return nil, nil, err
+	}
+	prf, err := eth2.Generate(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	if prf.BodyRoot != common.HexToHash(beacon.BodyRoot) {
+		return nil, nil, fmt.Errorf("body root of slot %s is %s, header has %s", beacon.Slot, prf.BodyRoot, beacon.BodyRoot)
+	}
+	payload := block.Data.Message.Body.ExecutionPayload
+	payload.TransactionsRoot = prf.TransactionsRoot.Hex()
+	payload.WithdrawalsRoot = prf.WithdrawalsRoot.Hex()
+	execution, err := eth2.ConvertExecution(&payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	return prf.Branch, execution, nil
+}
+
 func (m *Maintainer) getSignatureSlot(slot string, sa *eth2.SyncAggregate) (uint64, error) {
 	var CheckSlotsForwardLimit uint64 = 10
 	ahSlot, ok := big.NewInt(0).SetString(slot, 10)
@@ -415,18 +444,7 @@ func (m *Maintainer) getLightClientUpdateForLastPeriod(lastEth2PeriodOnContract
 			return nil, err
 		}
 	} else {
-		block, err := m.eth2Client.GetBlocks(context.Background(), resp.Data.FinalizedHeader.Beacon.Slot)
-		if err != nil {
-			return nil, err
-		}
-		branches, txRoot, wdRoot, err := eth2.Generate(resp.Data.FinalizedHeader.Beacon.Slot, m.Cfg.Eth2Endpoint)
-		if err != nil {
-			return nil, err
-		}
-		exeFinalityBranch = branches
-		block.Data.Message.Body.ExecutionPayload.TransactionsRoot = txRoot
-		block.Data.Message.Body.ExecutionPayload.WithdrawalsRoot = wdRoot
-		execution, err = eth2.ConvertExecution(&block.Data.Message.Body.ExecutionPayload)
+		exeFinalityBranch, execution, err = m.executionProof(&resp.Data.FinalizedHeader.Beacon)
 		if err != nil {
 			return nil, err
 		}
@@ -14,9 +14,11 @@ import (
 	"github.com/mapprotocol/compass/internal/constant"
 	"github.com/mapprotocol/compass/internal/eth2"
 	"github.com/mapprotocol/compass/internal/tx"
-	"github.com/mapprotocol/compass/pkg/util"
+	"github.com/mapprotocol/compass/pkg/alert"
 
 	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/health"
+	"github.com/mapprotocol/compass/pkg/metrics"
 )
 
 type Messenger struct {
@@ -32,7 +34,9 @@ func NewMessenger(cs *chain.CommonSync) *Messenger {
 func (m *Messenger) Sync() error {
 	m.Log.Debug("Starting listener...")
 	go func() {
+		health.Started(m.Cfg.Id)
 		err := m.sync()
+		health.Stopped(m.Cfg.Id, err)
 		if err != nil {
 			m.Log.Error("Polling blocks failed", "err", err)
 		}
@@ -47,6 +51,7 @@ func (m *Messenger) Sync() error {
 // However，an error in synchronizing the log will cause the entire program to block
 func (m *Messenger) sync() error {
 	if !m.Cfg.SyncToMap {
+		health.Idle(m.Cfg.Id)
 		time.Sleep(time.Hour * 2400)
 	}
 	var currentBlock = m.Cfg.StartBlock
@@ -56,23 +61,31 @@ func (m *Messenger) sync() error {
 		case <-m.Stop:
 			return errors.New("polling terminated")
 		default:
-			latestBlock, err := m.Conn.LatestBlock()
+			if m.Disconnected() {
+				continue
+			}
+			latestBlock, err := m.Conn.LatestBlock(m.Ctx)
 			if err != nil {
 				m.Log.Error("Unable to get latest block", "block", currentBlock, "err", err)
 				time.Sleep(constant.RetryLongInterval)
 				continue
 			}
+			metrics.Head(m.Cfg.Id, latestBlock)
 
 			if big.NewInt(0).Sub(latestBlock, currentBlock).Cmp(m.BlockConfirmations) == -1 {
 				m.Log.Debug("Block not ready, will retry", "target", currentBlock, "latest", latestBlock)
 				time.Sleep(constant.BalanceRetryInterval)
 				continue
 			}
+			if m.QuorumPaused(currentBlock, m.Cfg.McsContract) {
+				continue
+			}
 			count, err := m.getEventsForBlock(currentBlock)
 			if err != nil {
 				m.Log.Error("Failed to get events for block", "block", currentBlock, "err", err)
 				time.Sleep(constant.BlockRetryInterval)
-				util.Alarm(context.Background(), fmt.Sprintf("eth2 mos failed, err is %s", err.Error()))
+				alert.Fire(context.Background(), alert.Warning, m.Cfg.Name, alert.KeyListener,
+					fmt.Sprintf("eth2 mos failed on block %s, err is %s", currentBlock, err.Error()))
 				continue
 			}
 
@@ -84,6 +97,9 @@ func (m *Messenger) sync() error {
 			if err != nil {
 				m.Log.Error("Failed to write latest block to blockstore", "block", currentBlock, "err", err)
 			}
+			metrics.Processed(m.Cfg.Id, currentBlock)
+			health.Processed(m.Cfg.Id)
+			alert.Resolve(context.Background(), m.Cfg.Name, alert.KeyListener)
 
 			// Goto next block and reset retry counter
 			currentBlock.Add(currentBlock, big.NewInt(1))
@@ -101,7 +117,7 @@ func (m *Messenger) getEventsForBlock(latestBlock *big.Int) (int, error) {
 	count := 0
 	for idx, addr := range m.Cfg.McsContract {
 		query := m.BuildQuery(addr, m.Cfg.Events, latestBlock, latestBlock)
-		logs, err := m.Conn.Client().FilterLogs(context.Background(), query)
+		logs, err := m.FilterLogs(context.Background(), query)
 		if err != nil {
 			return 0, fmt.Errorf("unable to Filter Logs: %w", err)
 		}
@@ -134,11 +150,15 @@ func (m *Messenger) getEventsForBlock(latestBlock *big.Int) (int, error) {
 			if err != nil {
 				return 0, fmt.Errorf("unable to get tx hashes Logs: %w", err)
 			}
-			receipts, err := tx.GetReceiptsByTxsHash(m.Conn.Client(), txsHash)
+			receipts, err := tx.GetReceiptsByBlockNumber(m.Conn.Client(), latestBlock, txsHash)
 			if err != nil {
 				return 0, fmt.Errorf("unable to get receipts hashes Logs: %w", err)
 			}
-			payload, err := eth2.AssembleProof(*eth2.ConvertHeader(header), log, receipts, method, m.Cfg.Id, constant.ProofTypeOfOracle)
+			bHeader := *eth2.ConvertHeader(header)
+			if err = m.CheckReceiptsRoot(latestBlock, bHeader.ReceiptsRoot); err != nil {
+				return 0, err
+			}
+			payload, err := eth2.AssembleProof(bHeader, log, receipts, method, m.Cfg.Id, constant.ProofTypeOfOracle)
 			if err != nil {
 				return 0, fmt.Errorf("unable to Parse Log: %w", err)
 			}
@@ -201,7 +201,7 @@ func assembleProof(m *chain.Messenger, log *types.Log, proofType int64, toChainI
 		if err != nil {
 			return nil, fmt.Errorf("idSame unable to get tx hashes Logs: %w", err)
 		}
-		receipts, err := tx.GetReceiptsByTxsHash(m.Conn.Client(), txsHash)
+		receipts, err := tx.GetReceiptsByBlockNumber(m.Conn.Client(), bigNumber, txsHash)
 		if err != nil {
 			return nil, fmt.Errorf("unable to get receipts hashes Logs: %w", err)
 		}
@@ -217,6 +217,9 @@ func assembleProof(m *chain.Messenger, log *types.Log, proofType int64, toChainI
 		if toChainID == constant.MerlinChainId {
 			method = mapprotocol.MethodOfVerifyAndStore
 		}
+		if err = m.CheckReceiptsRoot(bigNumber, header.ReceiptHash); err != nil {
+			return nil, err
+		}
 		_, payload, err := mapo.AssembleMapProof(m.Conn.Client(), log, receipts, header, m.Cfg.MapChainID, method, m.Cfg.ApiUrl, proofType)
 		if err != nil {
 			return nil, fmt.Errorf("unable to Parse Log: %w", err)
@@ -232,11 +235,18 @@ func assembleProof(m *chain.Messenger, log *types.Log, proofType int64, toChainI
 		if err != nil {
 			return nil, fmt.Errorf("unable to get tx hashes Logs: %w", err)
 		}
-		receipts, err := tx.GetReceiptsByTxsHash(m.Conn.Client(), txsHash)
+		receipts, err := tx.GetReceiptsByBlockNumber(m.Conn.Client(), bigNumber, txsHash)
 		if err != nil {
 			return nil, fmt.Errorf("unable to get receipts hashes Logs: %w", err)
 		}
-		payload, err := mapo.AssembleEthProof(m.Conn.Client(), log, receipts, method, m.Cfg.Id, proofType)
+		header, err := m.Conn.Client().HeaderByNumber(context.Background(), bigNumber)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get header %d: %w", log.BlockNumber, err)
+		}
+		if err = m.CheckReceiptsRoot(bigNumber, header.ReceiptHash); err != nil {
+			return nil, err
+		}
+		payload, err := mapo.AssembleEthProof(m.Conn.Client(), log, receipts, header, method, m.Cfg.Id, proofType)
 		if err != nil {
 			return nil, fmt.Errorf("unable to Parse Log: %w", err)
 		}
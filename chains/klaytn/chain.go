@@ -28,8 +28,8 @@ var (
 	kClient = &klaytn.Client{}
 )
 
-func connectKClient(endpoint string) error {
-	kc, err := klaytn.DialHttp(endpoint, true)
+func connectKClient(chain, endpoint string) error {
+	kc, err := klaytn.DialHttp(chain, endpoint, true)
 	if err != nil {
 		return err
 	}
@@ -38,7 +38,7 @@ func connectKClient(endpoint string) error {
 }
 
 func InitializeChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr chan<- error, role mapprotocol.Role) (core.Chain, error) {
-	err := connectKClient(chainCfg.Endpoint)
+	err := connectKClient(chainCfg.Name, chainCfg.Endpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -178,6 +178,9 @@ func assembleProof(m *chain.Messenger, log *types.Log, proofType int64, toChainI
 	if err != nil {
 		return nil, err
 	}
+	if err = m.CheckReceiptsRoot(bigNumber, header.ReceiptHash); err != nil {
+		return nil, err
+	}
 	kHeader, err := kClient.BlockByNumber(context.Background(), bigNumber)
 	if err != nil {
 		return nil, err
@@ -122,6 +122,10 @@ func assembleProof(m *chain.Messenger, log *types.Log, proofType int64, toChainI
 		headers[i] = tmp
 	}
 
+	if err = m.CheckReceiptsRoot(bigNumber, headers[0].ReceiptHash); err != nil {
+		return nil, err
+	}
+
 	mHeaders := make([]matic.BlockHeader, 0, len(headers))
 	for _, h := range headers {
 		mHeaders = append(mHeaders, matic.ConvertHeader(h))
@@ -0,0 +1,35 @@
+package near
+
+import (
+	"github.com/mapprotocol/compass/internal/near"
+	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/audit"
+	"github.com/mapprotocol/near-api-go/pkg/client"
+	"github.com/mapprotocol/near-api-go/pkg/types/hash"
+)
+
+// auditTx records the signed function call of message m in the audit log, a tx which can not be recorded is not sent
+func (w *writer) auditTx(m msg.Message, txHash hash.CryptoHash, nonce uint64, toAddress, method string, input []byte) error {
+	return audit.Signed(audit.Entry{
+		ChainId:  w.cfg.id,
+		Tx:       txHash.String(),
+		From:     w.cfg.from,
+		Nonce:    nonce,
+		To:       toAddress,
+		Selector: method,
+		DataHash: audit.DataHash(input),
+		Gas:      uint64(near.NewFunctionCallGas),
+		DryRun:   w.cfg.dryRun,
+	}, m)
+}
+
+// auditReceipt records the final status of the tx in the audit log
+func (w *writer) auditReceipt(res client.FinalExecutionOutcomeView) {
+	status, reason := audit.StatusSuccess, ""
+	if len(res.Status.Failure) != 0 {
+		status, reason = audit.StatusFailed, string(res.Status.Failure)
+	}
+	if err := audit.Receipt(w.cfg.id, res.Transaction.Hash.String(), status, reason); err != nil {
+		w.log.Warn("Audit tx receipt failed", "tx", res.Transaction.Hash, "err", err)
+	}
+}
@@ -15,6 +15,8 @@ import (
 	"github.com/mapprotocol/compass/mapprotocol"
 	"github.com/mapprotocol/compass/msg"
 	"github.com/mapprotocol/compass/pkg/blockstore"
+	"github.com/mapprotocol/compass/pkg/ethclient"
+	"github.com/mapprotocol/compass/pkg/journal"
 	nearclient "github.com/mapprotocol/near-api-go/pkg/client"
 	"github.com/mapprotocol/near-api-go/pkg/types/key"
 )
@@ -70,7 +72,7 @@ func InitializeChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr cha
 		return nil, err
 	}
 
-	kp, err := keystore.NearKeyPairFrom(chainCfg.Network, cfg.keystorePath, cfg.from)
+	kp, err := keystore.NearKeyPairFrom(chainCfg.Network, cfg.keystorePath, cfg.from, cfg.password)
 	if err != nil {
 		return nil, err
 	}
@@ -79,8 +81,15 @@ func InitializeChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr cha
 	if err != nil {
 		return nil, err
 	}
+	jn, err := journal.New(cfg.blockstorePath, cfg.id, kp.PublicKey.ToPublicKey().Hash(), role)
+	if err != nil {
+		return nil, err
+	}
 
 	stop := make(chan int)
+	if cfg.auth != nil {
+		ethclient.SetAuth(cfg.endpoint, cfg.auth)
+	}
 	conn := connection.NewConnection(cfg.endpoint, cfg.http, &kp, logger, cfg.gasLimit, cfg.maxGasPrice,
 		cfg.gasMultiplier, cfg.egsApiKey, cfg.egsSpeed)
 	err = conn.Connect()
@@ -121,7 +130,7 @@ func InitializeChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr cha
 		mapprotocol.Map2OtherHeight[cfg.id] = fn
 		listen = NewMaintainer(cs)
 	}
-	writer := NewWriter(conn, cfg, logger, stop, sysErr)
+	writer := NewWriter(conn, cfg, jn, logger, stop, sysErr)
 
 	return &Chain{
 		cfg:    chainCfg,
@@ -138,12 +147,12 @@ func (c *Chain) SetRouter(r *core.Router) {
 }
 
 func (c *Chain) Start() error {
-	err := c.listen.Sync()
+	err := c.writer.start()
 	if err != nil {
 		return err
 	}
 
-	err = c.writer.start()
+	err = c.listen.Sync()
 	if err != nil {
 		return err
 	}
@@ -15,7 +15,12 @@ import (
 
 	gconfig "github.com/mapprotocol/compass/config"
 	"github.com/mapprotocol/compass/core"
+	"github.com/mapprotocol/compass/internal/near"
+	"github.com/mapprotocol/compass/keystore"
 	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/ethclient"
+	"github.com/mapprotocol/compass/pkg/signer"
+	"github.com/mapprotocol/near-api-go/pkg/types"
 )
 
 type Config struct {
@@ -42,10 +47,22 @@ type Config struct {
 	redisUrl           string
 	events             []string
 	skipError          bool
+	dryRun             bool
+	auth               *ethclient.Auth // Credentials sent to the endpoint, nil if none is configured
+	password           keystore.PasswordSource
+	policy             *signer.Policy // what the relayer key will sign, the storage deposit of calls is allowed
 }
 
 // parseChainConfig uses a core.ChainConfig to construct a corresponding Config
 func parseChainConfig(chainCfg *core.ChainConfig) (*Config, error) {
+	if strings.Contains(chainCfg.From, ",") {
+		return nil, errors.New("near chains support a single relayer key, from must hold one account")
+	}
+	for _, opt := range []string{chain.TxConfirmationsOpt, chain.QuorumOpt} {
+		if chainCfg.Opts[opt] != "" {
+			return nil, fmt.Errorf("near chains do not support opts.%s", opt)
+		}
+	}
 	config := &Config{
 		name:               chainCfg.Name,
 		id:                 chainCfg.Id,
@@ -65,6 +82,7 @@ func parseChainConfig(chainCfg *core.ChainConfig) (*Config, error) {
 		egsSpeed:           "",
 		redisUrl:           "",
 		skipError:          chainCfg.SkipError,
+		dryRun:             chainCfg.DryRun,
 	}
 
 	if contract, ok := chainCfg.Opts[chain.McsOpt]; ok && contract != "" {
@@ -148,6 +166,23 @@ func parseChainConfig(chainCfg *core.ChainConfig) (*Config, error) {
 		delete(chainCfg.Opts, chain.BlockConfirmationsOpt)
 	}
 
+	auth, err := chain.ParseAuth(chainCfg.Opts)
+	if err != nil {
+		return nil, err
+	}
+	config.auth = auth
+	config.password = chain.ParsePassword(chainCfg.Opts)
+	// the mcs option is already removed from the opts
+	contracts := append(config.mcsContract, chainCfg.Opts[chain.LightNode])
+	if config.policy, err = chain.ParsePolicy(config.name, chainCfg.Opts, contracts); err != nil {
+		return nil, err
+	}
+	deposit, _ := types.BalanceFromString(near.Deposit)
+	config.policy.MaxValue, _ = new(big.Int).SetString(deposit.String(), 10)
+	chain.DeleteCredentialOpts(chainCfg.Opts)
+	delete(chainCfg.Opts, chain.SignPolicyOpt)
+	delete(chainCfg.Opts, chain.SignMaxGasPriceOpt)
+
 	if gsnApiKey, ok := chainCfg.Opts[chain.EGSApiKey]; ok && gsnApiKey != "" {
 		config.egsApiKey = gsnApiKey
 		delete(chainCfg.Opts, chain.EGSApiKey)
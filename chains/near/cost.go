@@ -0,0 +1,29 @@
+package near
+
+import (
+	"math/big"
+
+	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/cost"
+	"github.com/mapprotocol/near-api-go/pkg/client"
+)
+
+// recordCost records the gas burnt by the tx and all of its receipts for message m
+func (w *writer) recordCost(m msg.Message, res client.FinalExecutionOutcomeView) {
+	gas := uint64(res.TransactionOutcome.Outcome.GasBurnt)
+	fee, _ := new(big.Int).SetString(res.TransactionOutcome.Outcome.TokensBurnt.String(), 10)
+	if fee == nil {
+		fee = new(big.Int)
+	}
+	for _, r := range res.ReceiptsOutcome {
+		gas += uint64(r.Outcome.GasBurnt)
+		if burnt, ok := new(big.Int).SetString(r.Outcome.TokensBurnt.String(), 10); ok {
+			fee.Add(fee, burnt)
+		}
+	}
+	price := new(big.Int)
+	if gas != 0 {
+		price.Div(fee, new(big.Int).SetUint64(gas))
+	}
+	cost.Add(m, w.cfg.id, res.Transaction.Hash.String(), gas, price, fee, cost.NearDecimals)
+}
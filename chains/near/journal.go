@@ -0,0 +1,99 @@
+package near
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mapprotocol/compass/internal/constant"
+	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/near-api-go/pkg/client"
+	"github.com/mapprotocol/near-api-go/pkg/jsonrpc"
+	"github.com/mapprotocol/near-api-go/pkg/types/hash"
+)
+
+// forgetTx drops the tx from the journal once its outcome is known
+func (w *writer) forgetTx(txHash string) {
+	if err := w.journal.Remove(txHash); err != nil {
+		w.log.Warn("Remove tx from journal failed", "tx", txHash, "err", err)
+	}
+}
+
+// resumeTx broadcasts the journaled tx of the message again and waits for it, near returns the final outcome
+// of a tx which has already been executed. It reports false if there is no usable journaled tx. A tx whose outcome
+// is still unknown, after a timeout or a transport error, is kept and the error returned so the message is retried.
+func (w *writer) resumeTx(ctx context.Context, m msg.Message, identity string) (hash.CryptoHash, bool, error) {
+	e, ok := w.journal.Get(identity)
+	if !ok {
+		return hash.CryptoHash{}, false, nil
+	}
+	w.log.Info("Message has a journaled tx, resume waiting for it", "tx", e.Hash, "nonce", e.Nonce)
+	res, err := w.conn.Client().RPCTransactionSendAwait(ctx, e.Raw)
+	if err != nil {
+		if !txRejected(err) {
+			w.log.Warn("Journaled tx outcome is unknown, keep it", "tx", e.Hash, "err", err)
+			return hash.CryptoHash{}, true, fmt.Errorf("failed to resume txn %s: %w", e.Hash, err)
+		}
+		w.log.Warn("Journaled tx was rejected, drop it", "tx", e.Hash, "err", err)
+		w.forgetTx(e.Hash)
+		return hash.CryptoHash{}, false, nil
+	}
+	w.forgetTx(e.Hash)
+	w.recordCost(m, res)
+	w.auditReceipt(res)
+	if len(res.Status.Failure) != 0 {
+		return hash.CryptoHash{}, true, fmt.Errorf("%s", string(res.Status.Failure))
+	}
+	return res.Transaction.Hash, true, nil
+}
+
+// reconcile checks every journaled tx left by a previous run. Known txs are kept so the message is resumed when
+// it is handled again, txs the node no longer knows are broadcast again.
+func (w *writer) reconcile() error {
+	ctx := client.ContextWithKeyPair(context.Background(), *w.conn.Keypair())
+	for _, e := range w.journal.Entries() {
+		if e.Age() > constant.JournalEntryTTL {
+			w.log.Info("Journaled tx is expired, drop it", "tx", e.Hash)
+			w.forgetTx(e.Hash)
+			continue
+		}
+		txHash, err := hash.NewCryptoHashFromBase58(e.Hash)
+		if err != nil {
+			w.log.Warn("Journaled tx is invalid, drop it", "tx", e.Hash, "err", err)
+			w.forgetTx(e.Hash)
+			continue
+		}
+		if _, err = w.conn.Client().TransactionStatus(ctx, txHash, e.From); err == nil {
+			w.log.Info("Journaled tx is known, wait for its message", "tx", e.Hash, "nonce", e.Nonce)
+			continue
+		}
+		if _, err = w.conn.Client().RPCTransactionSend(ctx, e.Raw); err != nil {
+			if !txRejected(err) {
+				w.log.Warn("Journaled tx can not be broadcast again yet, keep it", "tx", e.Hash, "err", err)
+				continue
+			}
+			w.log.Warn("Journaled tx is lost and was rejected, drop it", "tx", e.Hash, "err", err)
+			w.forgetTx(e.Hash)
+			continue
+		}
+		w.log.Info("Journaled tx broadcast again", "tx", e.Hash, "nonce", e.Nonce)
+	}
+	return nil
+}
+
+// txRejected reports whether the node refused the tx for good, like an expired tx or a nonce already used, so it
+// will never be executed. Timeouts, canceled contexts and transport errors leave the outcome unknown.
+func txRejected(err error) bool {
+	var rpcErr *jsonrpc.Error
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	if rpcErr.Cause.Name == "INVALID_TRANSACTION" {
+		return true
+	}
+	// nodes answering with the legacy error format
+	data := string(rpcErr.Data)
+	return strings.Contains(data, "InvalidTxError") || strings.Contains(data, "InvalidNonce") ||
+		strings.Contains(data, "Expired")
+}
@@ -0,0 +1,38 @@
+package near
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mapprotocol/near-api-go/pkg/jsonrpc"
+)
+
+func TestTxRejected(t *testing.T) {
+	rpcErr := func(raw string) error {
+		e := new(jsonrpc.Error)
+		if err := json.Unmarshal([]byte(raw), e); err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Errorf("failed to do txn: %w", e)
+	}
+	for _, tc := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"invalid nonce", rpcErr(`{"name":"HANDLER_ERROR","cause":{"name":"INVALID_TRANSACTION","info":{}},` +
+			`"data":{"TxExecutionError":{"InvalidTxError":{"InvalidNonce":{"tx_nonce":5,"ak_nonce":6}}}}}`), true},
+		{"expired legacy", rpcErr(`{"code":-32000,"message":"Server error","cause":{"name":"","info":{}},` +
+			`"data":{"TxExecutionError":{"InvalidTxError":"Expired"}}}`), true},
+		{"timeout", rpcErr(`{"name":"HANDLER_ERROR","cause":{"name":"TIMEOUT_ERROR","info":{}}}`), false},
+		{"canceled", context.Canceled, false},
+		{"transport", errors.New("dial tcp: connection refused"), false},
+	} {
+		if got := txRejected(tc.err); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
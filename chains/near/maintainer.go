@@ -8,11 +8,13 @@ import (
 	"time"
 
 	"github.com/mapprotocol/compass/internal/constant"
-	"github.com/mapprotocol/compass/pkg/util"
+	"github.com/mapprotocol/compass/pkg/alert"
 
 	"github.com/mapprotocol/compass/internal/near"
 	"github.com/mapprotocol/compass/mapprotocol"
 	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/health"
+	"github.com/mapprotocol/compass/pkg/metrics"
 	"github.com/mapprotocol/near-api-go/pkg/client/block"
 )
 
@@ -32,7 +34,9 @@ func NewMaintainer(cs *CommonListen) *Maintainer {
 func (m *Maintainer) Sync() error {
 	m.log.Debug("Starting listener...")
 	go func() {
+		health.Started(m.cfg.id)
 		err := m.sync()
+		health.Stopped(m.cfg.id, err)
 		if err != nil {
 			m.log.Error("Polling blocks failed", "err", err)
 		}
@@ -56,6 +60,7 @@ func (m Maintainer) sync() error {
 				time.Sleep(RetryInterval)
 				continue
 			}
+			metrics.Head(m.cfg.id, latestBlock)
 
 			if m.cfg.syncToMap {
 				// listen when catchup
@@ -64,13 +69,16 @@ func (m Maintainer) sync() error {
 				if err != nil {
 					m.log.Error("Failed to listen header for block", "block", latestBlock, "err", err)
 					time.Sleep(constant.QueryRetryInterval)
-					util.Alarm(context.Background(), fmt.Sprintf("near sync header failed, err is %s", err.Error()))
+					alert.Fire(context.Background(), alert.Warning, m.cfg.name, alert.KeyHeaderSync,
+						fmt.Sprintf("near sync header failed, err is %s", err.Error()))
 					continue
 				}
+				alert.Resolve(context.Background(), m.cfg.name, alert.KeyHeaderSync)
 			}
 
 			m.latestBlock.Height = big.NewInt(0).Set(latestBlock)
 			m.latestBlock.LastUpdated = time.Now()
+			health.Processed(m.cfg.id)
 		}
 	}
 }
@@ -10,12 +10,14 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/mapprotocol/compass/internal/constant"
-	"github.com/mapprotocol/compass/pkg/util"
+	"github.com/mapprotocol/compass/pkg/alert"
 
 	rds "github.com/go-redis/redis/v8"
 	"github.com/mapprotocol/compass/internal/near"
 	"github.com/mapprotocol/compass/mapprotocol"
 	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/health"
+	"github.com/mapprotocol/compass/pkg/metrics"
 	"github.com/mapprotocol/compass/pkg/redis"
 	"github.com/mapprotocol/near-api-go/pkg/client"
 	nearclient "github.com/mapprotocol/near-api-go/pkg/client"
@@ -36,7 +38,9 @@ func NewMessenger(cs *CommonListen) *Messenger {
 func (m *Messenger) Sync() error {
 	m.log.Debug("Starting listener...")
 	go func() {
+		health.Started(m.cfg.id)
 		err := m.sync()
+		health.Stopped(m.cfg.id, err)
 		if err != nil {
 			m.log.Error("Polling blocks failed", "err", err)
 		}
@@ -63,6 +67,7 @@ func (m *Messenger) sync() error {
 				time.Sleep(constant.RetryLongInterval)
 				continue
 			}
+			metrics.Head(m.cfg.id, latestBlock)
 
 			// Sleep if the difference is less than BlockDelay; (latest - current) < BlockDelay
 			if big.NewInt(0).Sub(latestBlock, currentBlock).Cmp(m.blockConfirmations) == -1 {
@@ -77,7 +82,8 @@ func (m *Messenger) sync() error {
 			if err != nil {
 				m.log.Error("Failed to get events for block", "block", currentBlock, "err", err)
 				time.Sleep(RetryInterval)
-				util.Alarm(context.Background(), fmt.Sprintf("near mos failed, err is %s", err.Error()))
+				alert.Fire(context.Background(), alert.Warning, m.cfg.name, alert.KeyListener,
+					fmt.Sprintf("near mos failed on block %s, err is %s", currentBlock, err.Error()))
 				continue
 			}
 
@@ -89,6 +95,9 @@ func (m *Messenger) sync() error {
 			if err != nil {
 				m.log.Error("Failed to write latest block to blockstore", "block", currentBlock, "err", err)
 			}
+			metrics.Processed(m.cfg.id, currentBlock)
+			health.Processed(m.cfg.id)
+			alert.Resolve(context.Background(), m.cfg.name, alert.KeyListener)
 			m.latestBlock.LastUpdated = time.Now()
 
 			currentBlock.Add(currentBlock, big.NewInt(1))
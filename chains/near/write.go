@@ -7,32 +7,35 @@ import (
 	"github.com/ChainSafe/log15"
 	"github.com/mapprotocol/compass/core"
 	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/journal"
 )
 
 var _ core.Writer = &writer{}
 
 type writer struct {
-	cfg    Config
-	conn   Connection
-	log    log15.Logger
-	stop   <-chan int
-	sysErr chan<- error // Reports fatal error to core
+	cfg     Config
+	conn    Connection
+	journal *journal.Journal
+	log     log15.Logger
+	stop    <-chan int
+	sysErr  chan<- error // Reports fatal error to core
 }
 
 // NewWriter creates and returns writer
-func NewWriter(conn Connection, cfg *Config, log log15.Logger, stop <-chan int, sysErr chan<- error) *writer {
+func NewWriter(conn Connection, cfg *Config, jn *journal.Journal, log log15.Logger, stop <-chan int, sysErr chan<- error) *writer {
 	return &writer{
-		cfg:    *cfg,
-		conn:   conn,
-		log:    log,
-		stop:   stop,
-		sysErr: sysErr,
+		cfg:     *cfg,
+		conn:    conn,
+		journal: jn,
+		log:     log,
+		stop:    stop,
+		sysErr:  sysErr,
 	}
 }
 
 func (w *writer) start() error {
 	w.log.Debug("Starting ethereum writer...")
-	return nil
+	return w.reconcile()
 }
 
 // ResolveMessage handles any given message based on type
@@ -5,11 +5,12 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/mapprotocol/compass/pkg/util"
+	"github.com/mapprotocol/compass/pkg/alert"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/mapprotocol/compass/internal/constant"
@@ -18,10 +19,13 @@ import (
 
 	"github.com/mapprotocol/compass/internal/near"
 	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/journal"
+	"github.com/mapprotocol/compass/pkg/metrics"
 	"github.com/mapprotocol/near-api-go/pkg/client"
 	"github.com/mapprotocol/near-api-go/pkg/types"
 	"github.com/mapprotocol/near-api-go/pkg/types/action"
 	"github.com/mapprotocol/near-api-go/pkg/types/hash"
+	"github.com/mapprotocol/near-api-go/pkg/types/transaction"
 )
 
 const (
@@ -83,11 +87,12 @@ func (w *writer) exeSyncMapMsg(m msg.Message) bool {
 				return false
 			}
 
-			txHash, err := w.sendTx(w.cfg.lightNode, MethodOfUpdateBlockHeader, m.Payload[0].([]byte))
+			txHash, err := w.sendTx(m, w.cfg.lightNode, MethodOfUpdateBlockHeader, m.Payload[0].([]byte))
 			w.conn.UnlockOpts()
 			if err == nil {
 				// message successfully handled
 				w.log.Info("Sync MapHeader to Near tx execution", "tx", txHash.String(), "src", m.Source, "dst", m.Destination)
+				alert.Resolve(context.Background(), w.cfg.name, alert.KeyLightClient)
 				m.DoneCh <- struct{}{}
 				return true
 			} else if strings.Index(err.Error(), "block header height is incorrect") != -1 {
@@ -98,8 +103,10 @@ func (w *writer) exeSyncMapMsg(m msg.Message) bool {
 				w.log.Warn("Execution failed will retry", "err", err)
 			}
 			errorCount++
+			metrics.Retry(w.cfg.id, m.Type)
 			if errorCount >= 10 {
-				util.Alarm(context.Background(), fmt.Sprintf("map2Near updateHeader failed, err is %s", err.Error()))
+				alert.Fire(context.Background(), alert.Warning, w.cfg.name, alert.KeyLightClient,
+					fmt.Sprintf("map2Near updateHeader failed, err is %s", err.Error()))
 				errorCount = 0
 			}
 			time.Sleep(constant.TxRetryInterval)
@@ -139,7 +146,7 @@ func (w *writer) exeSwapMsg(m msg.Message) bool {
 			w.log.Error("Verify Execution failed, Will retry", "srcHash", inputHash, "err", err)
 			return false
 		}
-		txHash, err := w.sendTx(addr, MethodOfVerifyReceiptProof, verify)
+		txHash, err := w.sendTx(m, addr, MethodOfVerifyReceiptProof, verify)
 		if err == nil {
 			w.log.Info("Verify Success", "mcsTx", txHash.String(), "srcHash", inputHash, "addr", addr)
 			time.Sleep(time.Second)
@@ -154,9 +161,11 @@ func (w *writer) exeSwapMsg(m msg.Message) bool {
 			}
 			w.log.Warn("Verify Execution failed, Will retry", "srcHash", inputHash, "err", err)
 			errorCount++
+			metrics.Retry(w.cfg.id, m.Type)
 			if errorCount >= 3 {
 				if strings.Index(err.Error(), "unexpected end of JSON input") == -1 {
-					util.Alarm(context.Background(), fmt.Sprintf("map2Near mos(verify_receipt_proof) failed, srcHash=%v err is %s", inputHash, err.Error()))
+					alert.Fire(context.Background(), alert.Critical, w.cfg.name, fmt.Sprintf("%s/%v", alert.KeyMos, inputHash),
+						fmt.Sprintf("map2Near mos(verify_receipt_proof) failed, srcHash=%v err is %s", inputHash, err.Error()))
 				}
 				errorCount = 0
 			}
@@ -175,9 +184,10 @@ func (w *writer) exeSwapMsg(m msg.Message) bool {
 				method = MethodOfSwapIn
 			}
 			w.log.Info("Send transaction", "srcHash", inputHash, "method", method, "addr", addr)
-			txHash, err := w.sendTx(addr, method, data)
+			txHash, err := w.sendTx(m, addr, method, data)
 			if err == nil {
 				w.log.Info("Submitted cross tx execution", "mcsTx", txHash.String(), "srcHash", inputHash)
+				alert.Resolve(context.Background(), w.cfg.name, fmt.Sprintf("%s/%v", alert.KeyMos, inputHash))
 				m.DoneCh <- struct{}{}
 				return true
 			} else if strings.Index(err.Error(), OrderIdIsUsed) != -1 && strings.Index(err.Error(), OrderIdIsUsedFlag2) != -1 {
@@ -205,9 +215,11 @@ func (w *writer) exeSwapMsg(m msg.Message) bool {
 				}
 				w.log.Warn("Execution failed, tx may already be complete", "srcHash", inputHash, "err", err)
 				errorCount++
+				metrics.Retry(w.cfg.id, m.Type)
 				if errorCount >= 3 {
 					if strings.Index(err.Error(), "unexpected end of JSON input") == -1 {
-						util.Alarm(context.Background(), fmt.Sprintf("map2Near mos(%s) failed, srcHash=%v err is %s", method, inputHash, err.Error()))
+						alert.Fire(context.Background(), alert.Critical, w.cfg.name, fmt.Sprintf("%s/%v", alert.KeyMos, inputHash),
+							fmt.Sprintf("map2Near mos(%s) failed, srcHash=%v err is %s", method, inputHash, err.Error()))
 					}
 					errorCount = 0
 				}
@@ -217,34 +229,131 @@ func (w *writer) exeSwapMsg(m msg.Message) bool {
 	}
 }
 
-// sendTx send tx to an address with value and input data
-func (w *writer) sendTx(toAddress string, method string, input []byte) (hash.CryptoHash, error) {
+// sendTx send tx to an address with value and input data, the cost of the tx is recorded for message m
+func (w *writer) sendTx(m msg.Message, toAddress string, method string, input []byte) (hash.CryptoHash, error) {
 	w.log.Info("sendTx", "toAddress", toAddress)
 	ctx := client.ContextWithKeyPair(context.Background(), *w.conn.Keypair())
 	b := types.Balance{}
 	if method == MethodOfTransferIn || method == MethodOfSwapIn || method == MethodOfVerifyReceiptProof {
 		b, _ = types.BalanceFromString(near.Deposit)
 	}
-	res, err := w.conn.Client().TransactionSendAwait(
-		ctx,
-		w.cfg.from,
-		toAddress,
-		[]action.Action{
-			action.NewFunctionCall(method, input, near.NewFunctionCallGas, b),
-		},
-		client.WithLatestBlock(),
-		client.WithKeyPair(*w.conn.Keypair()),
-	)
+	if w.cfg.dryRun {
+		return w.simulateTx(ctx, m, toAddress, method, input, b)
+	}
+	identity := journal.Identity(method, toAddress, input)
+	if txHash, ok, err := w.resumeTx(ctx, m, identity); ok {
+		return txHash, err
+	}
+
+	blob, txHash, nonce, err := w.signTx(ctx, toAddress, []action.Action{
+		action.NewFunctionCall(method, input, near.NewFunctionCallGas, b),
+	})
+	if err != nil {
+		return hash.CryptoHash{}, fmt.Errorf("failed to do txn: %w", err)
+	}
+	if err = w.auditTx(m, txHash, nonce, toAddress, method, input); err != nil {
+		return hash.CryptoHash{}, fmt.Errorf("failed to audit txn: %w", err)
+	}
+	err = w.journal.Add(journal.Entry{Hash: txHash.String(), Nonce: nonce, From: w.cfg.from, Message: identity, Raw: blob})
+	if err != nil {
+		return hash.CryptoHash{}, fmt.Errorf("failed to journal txn: %w", err)
+	}
+	start := time.Now()
+	res, err := w.conn.Client().RPCTransactionSendAwait(ctx, blob)
 	if err != nil {
+		// the tx may still be executed, keep it in the journal so the retry resumes it
 		return hash.CryptoHash{}, fmt.Errorf("failed to do txn: %w", err)
 	}
+	w.forgetTx(txHash.String())
 	w.log.Debug("sendTx success", "res", res)
+	w.recordCost(m, res)
+	w.auditReceipt(res)
 	if len(res.Status.Failure) != 0 {
 		return hash.CryptoHash{}, fmt.Errorf("%s", string(res.Status.Failure))
 	}
+	metrics.Confirmed(w.cfg.id, time.Since(start))
 	return res.Transaction.Hash, nil
 }
 
+// signTx builds and signs a tx of the relayer key against the latest final block, it returns the base64 encoded tx.
+// The actions are checked against the signing policy first.
+func (w *writer) signTx(ctx context.Context, toAddress string, actions []action.Action) (string, hash.CryptoHash, uint64, error) {
+	if err := w.checkPolicy(ctx, toAddress, actions); err != nil {
+		return "", hash.CryptoHash{}, 0, err
+	}
+	kp := w.conn.Keypair()
+	accessKey, err := w.conn.Client().AccessKeyView(ctx, w.cfg.from, kp.PublicKey, block.FinalityFinal())
+	if err != nil {
+		return "", hash.CryptoHash{}, 0, err
+	}
+	latest, err := w.conn.Client().BlockDetails(ctx, block.FinalityFinal())
+	if err != nil {
+		return "", hash.CryptoHash{}, 0, err
+	}
+	signed, err := transaction.NewSignedTransaction(*kp, transaction.Transaction{
+		SignerID:   w.cfg.from,
+		PublicKey:  kp.PublicKey.ToPublicKey(),
+		Nonce:      accessKey.Nonce + 1,
+		ReceiverID: toAddress,
+		BlockHash:  latest.Header.Hash,
+		Actions:    actions,
+	})
+	if err != nil {
+		return "", hash.CryptoHash{}, 0, err
+	}
+	blob, err := signed.Serialize()
+	if err != nil {
+		return "", hash.CryptoHash{}, 0, err
+	}
+	return blob, signed.Hash(), accessKey.Nonce + 1, nil
+}
+
+// checkPolicy refuses actions other than function calls allowed by the signing policy
+func (w *writer) checkPolicy(ctx context.Context, toAddress string, actions []action.Action) error {
+	for _, a := range actions {
+		call, ok := a.UnderlyingValue().(*action.ActionFunctionCall)
+		if !ok {
+			return w.cfg.policy.Refuse(ctx, toAddress, "", "only function calls are allowed")
+		}
+		deposit, _ := new(big.Int).SetString(call.Deposit.String(), 10)
+		if err := w.cfg.policy.Check(ctx, toAddress, call.MethodName, deposit, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// simulateTx signs the function call and runs it as a view call instead of broadcasting it, only used in dry run
+// mode. A view call can not change state, calls which do are logged as not simulated.
+func (w *writer) simulateTx(ctx context.Context, m msg.Message, toAddress string, method string, input []byte,
+	deposit types.Balance) (hash.CryptoHash, error) {
+	_, txHash, nonce, err := w.signTx(ctx, toAddress, []action.Action{
+		action.NewFunctionCall(method, input, near.NewFunctionCallGas, deposit),
+	})
+	if err != nil {
+		return hash.CryptoHash{}, fmt.Errorf("dry run failed to sign txn: %w", err)
+	}
+	if err = w.auditTx(m, txHash, nonce, toAddress, method, input); err != nil {
+		return hash.CryptoHash{}, fmt.Errorf("dry run failed to audit txn: %w", err)
+	}
+	res, err := w.conn.Client().ContractViewCallFunction(ctx, toAddress, method,
+		base64.StdEncoding.EncodeToString(input), block.FinalityFinal())
+	if err != nil {
+		return hash.CryptoHash{}, fmt.Errorf("dry run failed to simulate txn: %w", err)
+	}
+	if res.Error != nil {
+		if strings.Contains(*res.Error, "ProhibitedInView") {
+			w.log.Warn("Dry run, tx signed but not simulated as it changes state, not broadcast", "tx", txHash,
+				"to", toAddress, "method", method, "input", string(input))
+			return txHash, nil
+		}
+		return hash.CryptoHash{}, fmt.Errorf("dry run simulated txn failed: %s", *res.Error)
+	}
+	w.log.Info("Dry run, tx simulated and not broadcast", "tx", txHash, "to", toAddress, "method", method,
+		"input", string(input), "result", string(res.Result), "logs", res.Logs)
+	return txHash, nil
+}
+
 func (w *writer) checkOrderId(toAddress string, input []byte) (bool, error) {
 	var fixedOrderId [32]byte
 	for idx, v := range input {
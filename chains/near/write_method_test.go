@@ -32,7 +32,7 @@ func dailRpc() *nearclient.Client {
 }
 
 func Test_writer_sendTx(t *testing.T) {
-	kp, err := keystore.NearKeyPairFrom("local", "/Users/xm", from)
+	kp, err := keystore.NearKeyPairFrom("local", "/Users/xm", from, keystore.PasswordSource{})
 	if err != nil {
 		t.Fatalf("keypair err %v", err)
 	}
@@ -81,7 +81,7 @@ func Test_writer_sendTx(t *testing.T) {
 }
 
 func Test_new(t *testing.T) {
-	kp, err := keystore.NearKeyPairFrom("local", "/Users/xm", from)
+	kp, err := keystore.NearKeyPairFrom("local", "/Users/xm", from, keystore.PasswordSource{})
 	if err != nil {
 		t.Fatalf("keypair err %v", err)
 	}
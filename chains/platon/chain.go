@@ -92,11 +92,14 @@ func assembleProof(m *chain.Messenger, log *types.Log, proofType int64, toChainI
 	if err != nil {
 		return nil, err
 	}
+	if err = m.CheckReceiptsRoot(bigNumber, ethcommon.BytesToHash(headerParam.Header.ReceiptsRoot)); err != nil {
+		return nil, err
+	}
 	txsHash, err := tx.GetTxsHashByBlockNumber(m.Conn.Client(), bigNumber)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get tx hashes Logs: %w", err)
 	}
-	receipts, err := tx.GetReceiptsByTxsHash(m.Conn.Client(), txsHash)
+	receipts, err := tx.GetReceiptsByBlockNumber(m.Conn.Client(), bigNumber, txsHash)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get receipts hashes Logs: %w", err)
 	}
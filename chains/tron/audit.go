@@ -0,0 +1,39 @@
+package tron
+
+import (
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/lbtsm/gotron-sdk/pkg/address"
+	"github.com/lbtsm/gotron-sdk/pkg/proto/core"
+	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/audit"
+)
+
+// auditTx records the signed contract call of message m in the audit log, a tx which can not be recorded is not
+// sent. Tron txs have no nonce, the fee limit is recorded as gas.
+func (w *Writer) auditTx(m msg.Message, txHash string, tx *core.Transaction) error {
+	e := audit.Entry{
+		ChainId: w.cfg.Id,
+		Tx:      txHash,
+		From:    w.cfg.From,
+		Gas:     uint64(tx.GetRawData().GetFeeLimit()),
+		DryRun:  w.cfg.DryRun,
+	}
+	// checkPolicy made sure the tx holds a single contract call
+	var call core.TriggerSmartContract
+	if err := tx.GetRawData().GetContract()[0].GetParameter().UnmarshalTo(&call); err != nil {
+		return err
+	}
+	e.To = address.Address(call.ContractAddress).String()
+	e.DataHash = audit.DataHash(call.Data)
+	if len(call.Data) >= 4 {
+		e.Selector = hexutil.Encode(call.Data[:4])
+	}
+	return audit.Signed(e, m)
+}
+
+// auditReceipt records the final status of a tx in the audit log
+func (w *Writer) auditReceipt(txHash, status, reason string) {
+	if err := audit.Receipt(w.cfg.Id, txHash, status, reason); err != nil {
+		w.log.Warn("Failed to audit tx receipt", "tx", txHash, "err", err)
+	}
+}
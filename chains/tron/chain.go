@@ -1,12 +1,10 @@
 package tron
 
 import (
+	"context"
 	"fmt"
 	connection "github.com/mapprotocol/compass/connections/ethereum"
 	"math/big"
-	"os"
-
-	"github.com/mapprotocol/compass/keystore"
 
 	"github.com/ChainSafe/log15"
 	"github.com/ethereum/go-ethereum/log"
@@ -29,23 +27,23 @@ func createChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr chan<-
 		return nil, err
 	}
 
+	chain.SetupEndpoints(&config.Config)
 	conn := NewConnection(config.Endpoint, logger)
-	err = conn.Connect()
+	err = conn.Connect(context.Background())
 	if err != nil {
 		return nil, err
 	}
 
-	ethConn := connection.NewConnection(config.Eth2Endpoint, true, nil, logger, config.GasLimit, config.MaxGasPrice, 0)
-	err = ethConn.Connect()
+	ethConn := connection.NewConnection(config.Eth2Endpoint, true, nil, logger, config.GasLimit, config.MaxGasPrice, 0,
+		core.ConnOpts{Name: config.Name})
+	err = ethConn.Connect(context.Background())
 	if err != nil {
 		return nil, err
 	}
 
-	var pswd []byte
-	if pswdStr := os.Getenv(keystore.EnvPassword); pswdStr != "" {
-		pswd = []byte(pswdStr)
-	} else {
-		pswd = keystore.GetPassword(fmt.Sprintf("Enter password for key %s:", chainCfg.From))
+	pswd, err := config.Password.Password(config.From, chainCfg.From)
+	if err != nil {
+		return nil, err
 	}
 
 	var (
@@ -56,6 +54,10 @@ func createChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr chan<-
 	if err != nil {
 		return nil, err
 	}
+	jn, err := chain.SetupJournal(&config.Config, role)
+	if err != nil {
+		return nil, err
+	}
 	cs := chain.NewCommonSync(ethConn, &config.Config, logger, stop, sysErr, bs)
 
 	switch role {
@@ -63,6 +65,7 @@ func createChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr chan<-
 		fn := Map2Tron(config.From, config.LightNode, conn.cli)
 		height, err := fn()
 		if err != nil {
+			cs.Close()
 			return nil, errors.Wrap(err, "Map2Tron get init headerHeight failed")
 		}
 		logger.Info("Map2other Current situation", "id", config.Id, "height", height, "lightNode", config.LightNode)
@@ -80,7 +83,7 @@ func createChain(chainCfg *core.ChainConfig, logger log15.Logger, sysErr chan<-
 		stop:   stop,
 		listen: listen,
 		cfg:    chainCfg,
-		writer: newWriter(conn, config, logger, stop, sysErr, pswd),
+		writer: newWriter(conn, config, jn, logger, stop, sysErr, pswd),
 	}, nil
 }
 
@@ -98,7 +101,12 @@ func (c *Chain) SetRouter(r *core.Router) {
 }
 
 func (c *Chain) Start() error {
-	err := c.listen.Sync()
+	err := c.writer.reconcile()
+	if err != nil {
+		return err
+	}
+
+	err = c.listen.Sync()
 	if err != nil {
 		return err
 	}
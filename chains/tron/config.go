@@ -1,6 +1,8 @@
 package tron
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/mapprotocol/compass/core"
@@ -14,10 +16,19 @@ type Config struct {
 }
 
 func parseCfg(chainCfg *core.ChainConfig) (*Config, error) {
+	if strings.Contains(chainCfg.From, ",") {
+		return nil, errors.New("tron chains support a single relayer key, from must hold one address")
+	}
 	cfg, err := chain.ParseConfig(chainCfg)
 	if err != nil {
 		return nil, err
 	}
+	if cfg.TxConfirmations != 0 || cfg.TxFinalized {
+		return nil, fmt.Errorf("tron chains do not support opts.%s", chain.TxConfirmationsOpt)
+	}
+	if cfg.Quorum != 0 {
+		return nil, fmt.Errorf("tron chains do not support opts.%s", chain.QuorumOpt)
+	}
 	ret := Config{
 		Config:      *cfg,
 		LightNode:   "",
@@ -1,24 +1,36 @@
 package tron
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"math/big"
+	"strings"
+	gosync "sync"
 	"time"
 
 	"github.com/ChainSafe/log15"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/lbtsm/gotron-sdk/pkg/client"
+	"github.com/lbtsm/gotron-sdk/pkg/proto/api"
+	"github.com/mapprotocol/compass/core"
 	"github.com/mapprotocol/compass/pkg/ethclient"
+	"github.com/mapprotocol/compass/pkg/instrument"
 )
 
+const grpcTimeout = 5 * time.Second // timeout of the requests of the sdk
+
 type Connection struct {
 	endpoint                  string
 	cli                       *client.GrpcClient
 	log                       log15.Logger
 	stop                      chan int
-	reqTime, cacheBlockNumber int64
+	blockLock                 gosync.Mutex
+	reqTime, cacheBlockNumber int64 // latest block cached for a second, guarded by blockLock
 }
 
 func NewConnection(endpoint string, log log15.Logger) *Connection {
@@ -30,62 +42,96 @@ func NewConnection(endpoint string, log log15.Logger) *Connection {
 }
 
 // Connect starts the ethereum WS connection
-func (c *Connection) Connect() error {
+func (c *Connection) Connect(ctx context.Context) error {
 	c.log.Info("Connecting to tron chain...", "url", c.endpoint)
 	c.cli = client.NewGrpcClient(c.endpoint)
-	err := c.cli.Start(grpc.WithInsecure())
+	opts := []grpc.DialOption{grpc.WithUnaryInterceptor(instrument.UnaryClientInterceptor("tron", c.endpoint))}
+	if auth := ethclient.AuthOf(c.endpoint); auth != nil {
+		if auth.TLS != nil {
+			opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(auth.TLS)))
+		} else {
+			opts = append(opts, grpc.WithInsecure())
+		}
+		opts = append(opts, grpc.WithPerRPCCredentials(rpcCredentials{auth: auth}))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	err := c.cli.Start(opts...)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (c *Connection) Keypair() *keystore.Key {
-	return nil
+// rpcCredentials sends the headers of the endpoint credentials as grpc metadata
+type rpcCredentials struct {
+	auth *ethclient.Auth
 }
 
-func (c *Connection) Client() *ethclient.Client {
-	return nil
+func (r rpcCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	h, err := r.auth.Headers()
+	if err != nil {
+		return nil, err
+	}
+	md := make(map[string]string, len(h))
+	for k := range h {
+		md[strings.ToLower(k)] = h.Get(k)
+	}
+	return md, nil
 }
 
-func (c *Connection) Opts() *bind.TransactOpts {
-	return nil
+func (r rpcCredentials) RequireTransportSecurity() bool {
+	return r.auth.TLS != nil
 }
 
-func (c *Connection) CallOpts() *bind.CallOpts {
+func (c *Connection) Keypair() *keystore.Key {
 	return nil
 }
 
-func (c *Connection) UnlockOpts() {
+func (c *Connection) Client() *ethclient.Client {
+	return nil
 }
 
-func (c *Connection) LockAndUpdateOpts(needNewNonce bool) error {
+func (c *Connection) CallOpts(ctx context.Context) *bind.CallOpts {
 	return nil
 }
 
+// PrepareTx is not supported, tron txs are built by the writer with the tron client
+func (c *Connection) PrepareTx(ctx context.Context) (*core.TxOpts, error) {
+	return nil, errors.New("tron connection does not prepare evm transactions")
+}
+
 // LatestBlock returns the latest block from the current chain
-func (c *Connection) LatestBlock() (*big.Int, error) {
+func (c *Connection) LatestBlock(ctx context.Context) (*big.Int, error) {
 	// 1s req
+	c.blockLock.Lock()
 	if time.Now().Unix()-c.reqTime < 1 {
+		defer c.blockLock.Unlock()
 		return big.NewInt(0).SetInt64(c.cacheBlockNumber), nil
 	}
+	c.blockLock.Unlock()
 
-	bnum, err := c.cli.GetNowBlock()
+	// the sdk calls have their own context, the wallet client is called directly so ctx cancels the request
+	ctx, cancel := context.WithTimeout(ctx, grpcTimeout)
+	defer cancel()
+	bnum, err := c.cli.Client.GetNowBlock2(ctx, new(api.EmptyMessage))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("get block now: %v", err)
 	}
+	c.blockLock.Lock()
 	c.cacheBlockNumber = bnum.GetBlockHeader().GetRawData().Number
 	c.reqTime = time.Now().Unix()
+	c.blockLock.Unlock()
 
 	return big.NewInt(0).SetInt64(bnum.GetBlockHeader().GetRawData().Number), nil
 }
 
 // EnsureHasBytecode asserts if contract code exists at the specified address
-func (c *Connection) EnsureHasBytecode(addr ethcommon.Address) error {
+func (c *Connection) EnsureHasBytecode(ctx context.Context, addr ethcommon.Address) error {
 	return nil
 }
 
-func (c *Connection) WaitForBlock(targetBlock *big.Int, delay *big.Int) error {
+func (c *Connection) WaitForBlock(ctx context.Context, targetBlock *big.Int, delay *big.Int) error {
 	return nil
 }
 
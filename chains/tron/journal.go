@@ -0,0 +1,101 @@
+package tron
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/lbtsm/gotron-sdk/pkg/proto/api"
+	"github.com/lbtsm/gotron-sdk/pkg/proto/core"
+	"github.com/mapprotocol/compass/internal/constant"
+	"github.com/mapprotocol/compass/pkg/journal"
+	"google.golang.org/protobuf/proto"
+)
+
+const methodOfTriggerContract = "triggerContract"
+
+// journalTx records the signed tx of the message before it is broadcast
+func (w *Writer) journalTx(identity, txHash string, signed *core.Transaction) error {
+	raw, err := proto.Marshal(signed)
+	if err != nil {
+		return err
+	}
+	return w.journal.Add(journal.Entry{
+		Hash:    txHash,
+		From:    w.cfg.From,
+		Message: identity,
+		Raw:     common.Bytes2Hex(raw),
+	})
+}
+
+// forgetTx drops the tx from the journal once its outcome is known
+func (w *Writer) forgetTx(txHash string) {
+	if err := w.journal.Remove(txHash); err != nil {
+		w.log.Warn("Remove tx from journal failed", "tx", txHash, "err", err)
+	}
+}
+
+// resumeTx returns the journaled tx hash of the message if it is known by the node or could be broadcast again
+func (w *Writer) resumeTx(identity string) (string, bool) {
+	e, ok := w.journal.Get(identity)
+	if !ok {
+		return "", false
+	}
+	if err := w.rebroadcast(e); err != nil {
+		w.log.Warn("Journaled tx can not be resumed, drop it", "tx", e.Hash, "err", err)
+		w.forgetTx(e.Hash)
+		return "", false
+	}
+	w.log.Info("Message has a journaled tx, resume waiting for it", "tx", e.Hash)
+	return e.Hash, true
+}
+
+// reconcile checks every journaled tx left by a previous run. Known txs are kept so the message is resumed when
+// it is handled again, txs the node no longer knows are broadcast again.
+func (w *Writer) reconcile() error {
+	for _, e := range w.journal.Entries() {
+		if e.Age() > constant.JournalEntryTTL {
+			w.log.Info("Journaled tx is expired, drop it", "tx", e.Hash)
+			w.forgetTx(e.Hash)
+			continue
+		}
+		if err := w.rebroadcast(e); err != nil {
+			w.log.Warn("Journaled tx is lost and can not be broadcast again, drop it", "tx", e.Hash, "err", err)
+			w.forgetTx(e.Hash)
+			continue
+		}
+		w.log.Info("Journaled tx is known, wait for its message", "tx", e.Hash)
+	}
+	return nil
+}
+
+// rebroadcast broadcasts the journaled tx again unless the node already knows it
+func (w *Writer) rebroadcast(e journal.Entry) error {
+	if _, err := w.conn.cli.GetTransactionByID(e.Hash); err == nil {
+		return nil
+	}
+	signed := new(core.Transaction)
+	if err := proto.Unmarshal(common.Hex2Bytes(e.Raw), signed); err != nil {
+		return err
+	}
+	ret, err := w.conn.cli.Broadcast(signed)
+	if ret.GetCode() == api.Return_DUP_TRANSACTION_ERROR {
+		// still pending in the node
+		return nil
+	}
+	return err
+}
+
+// txExpired reports whether the journaled tx is past its expiration, after which it can no longer be included
+func (w *Writer) txExpired(txHash string) bool {
+	for _, e := range w.journal.Entries() {
+		if e.Hash != txHash {
+			continue
+		}
+		signed := new(core.Transaction)
+		if err := proto.Unmarshal(common.Hex2Bytes(e.Raw), signed); err != nil {
+			return false
+		}
+		return time.Now().UnixMilli() > signed.GetRawData().GetExpiration()
+	}
+	return false
+}
@@ -1,14 +1,19 @@
 package tron
 
 import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 	"github.com/mapprotocol/compass/internal/constant"
 	"github.com/mapprotocol/compass/internal/proof"
 	"github.com/mapprotocol/compass/mapprotocol"
 	"github.com/mapprotocol/compass/msg"
 	"github.com/mapprotocol/compass/pkg/util"
-	"math/big"
 )
 
 func assembleProof(log *types.Log, receipts []*types.Receipt, method string, fId msg.ChainId, proofType int64) ([]byte, error) {
@@ -23,6 +28,15 @@ func assembleProof(log *types.Log, receipts []*types.Receipt, method string, fId
 	if err != nil {
 		return nil, err
 	}
+	// tron blocks carry no receipts root, the oracle proposes the root of the same receipts
+	tr, err := trie.New(common.Hash{}, trie.NewDatabase(memorydb.New()))
+	if err != nil {
+		return nil, err
+	}
+	root := proof.DeriveTire(types.Receipts(receipts), tr).Hash()
+	if err = proof.Verify(receipts, types.Receipts(receipts), root, log.TxIndex, prf); err != nil {
+		return nil, fmt.Errorf("verify receipt proof of block %d: %w", log.BlockNumber, err)
+	}
 
 	var key []byte
 	key = rlp.AppendUint64(key[:0], uint64(log.TxIndex))
@@ -15,8 +15,10 @@ import (
 	"github.com/mapprotocol/compass/internal/tx"
 	"github.com/mapprotocol/compass/mapprotocol"
 	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/alert"
 	"github.com/mapprotocol/compass/pkg/ethclient"
-	"github.com/mapprotocol/compass/pkg/util"
+	"github.com/mapprotocol/compass/pkg/health"
+	"github.com/mapprotocol/compass/pkg/metrics"
 	"github.com/pkg/errors"
 	"math/big"
 	"strconv"
@@ -61,7 +63,9 @@ func newSync(cs *chain.CommonSync, handler Handler, conn core.Connection) *sync
 
 func (m *sync) Sync() error {
 	m.Log.Info("Starting listener...")
+	health.Started(m.Cfg.Id)
 	if !m.Cfg.SyncToMap {
+		health.Idle(m.Cfg.Id)
 		time.Sleep(time.Hour * 2400)
 		return nil
 	}
@@ -72,12 +76,13 @@ func (m *sync) Sync() error {
 		return errors.New("polling terminated")
 	default:
 		for {
-			latestBlock, err := m.conn.LatestBlock()
+			latestBlock, err := m.conn.LatestBlock(m.Ctx)
 			if err != nil {
 				m.Log.Error("Unable to get latest block", "err", err)
 				time.Sleep(constant.RetryLongInterval)
 				continue
 			}
+			metrics.Head(m.Cfg.Id, latestBlock)
 
 			if big.NewInt(0).Sub(latestBlock, currentBlock).Cmp(m.BlockConfirmations) == -1 {
 				m.Log.Debug("Block not ready, will retry", "currentBlock", currentBlock, "latest", latestBlock)
@@ -89,7 +94,8 @@ func (m *sync) Sync() error {
 			if err != nil {
 				m.Log.Error("Failed to get events for block", "block", currentBlock, "err", err)
 				time.Sleep(constant.BlockRetryInterval)
-				util.Alarm(context.Background(), fmt.Sprintf("mos failed, chain=%s, err is %s", m.Cfg.Name, err.Error()))
+				alert.Fire(context.Background(), alert.Warning, m.Cfg.Name, alert.KeyListener,
+					fmt.Sprintf("mos failed on block %s, err is %s", currentBlock, err.Error()))
 				continue
 			}
 
@@ -99,6 +105,9 @@ func (m *sync) Sync() error {
 			if err != nil {
 				m.Log.Error("Failed to write latest block to blockstore", "block", currentBlock, "err", err)
 			}
+			metrics.Processed(m.Cfg.Id, currentBlock)
+			health.Processed(m.Cfg.Id)
+			alert.Resolve(context.Background(), m.Cfg.Name, alert.KeyListener)
 
 			currentBlock.Add(currentBlock, big.NewInt(1))
 			if latestBlock.Int64()-currentBlock.Int64() <= m.Cfg.BlockConfirmations.Int64() {
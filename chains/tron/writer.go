@@ -12,38 +12,46 @@ import (
 	"github.com/mapprotocol/compass/mapprotocol"
 	"github.com/pkg/errors"
 
+	"github.com/lbtsm/gotron-sdk/pkg/address"
+	"github.com/lbtsm/gotron-sdk/pkg/proto/api"
 	"github.com/lbtsm/gotron-sdk/pkg/proto/core"
 
 	"github.com/ChainSafe/log15"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/lbtsm/gotron-sdk/pkg/client/transaction"
 	"github.com/lbtsm/gotron-sdk/pkg/keystore"
 	"github.com/mapprotocol/compass/internal/constant"
 	"github.com/mapprotocol/compass/msg"
-	"github.com/mapprotocol/compass/pkg/util"
+	"github.com/mapprotocol/compass/pkg/alert"
+	"github.com/mapprotocol/compass/pkg/audit"
+	"github.com/mapprotocol/compass/pkg/cost"
+	"github.com/mapprotocol/compass/pkg/journal"
+	"github.com/mapprotocol/compass/pkg/metrics"
+	"github.com/mapprotocol/compass/pkg/signer"
 )
 
 var multiple = big.NewInt(420)
 
 type Writer struct {
-	cfg    *Config
-	log    log15.Logger
-	conn   *Connection
-	stop   <-chan int
-	sysErr chan<- error
-	pass   []byte
-	ks     *keystore.KeyStore
-	acc    *keystore.Account
+	cfg     *Config
+	log     log15.Logger
+	conn    *Connection
+	stop    <-chan int
+	sysErr  chan<- error
+	pass    []byte
+	ks      *keystore.KeyStore
+	acc     *keystore.Account
+	journal *journal.Journal
 }
 
-func newWriter(conn *Connection, cfg *Config, log log15.Logger, stop <-chan int, sysErr chan<- error, pass []byte) *Writer {
+func newWriter(conn *Connection, cfg *Config, jn *journal.Journal, log log15.Logger, stop <-chan int, sysErr chan<- error, pass []byte) *Writer {
 	return &Writer{
-		cfg:    cfg,
-		conn:   conn,
-		log:    log,
-		stop:   stop,
-		sysErr: sysErr,
-		pass:   pass,
+		cfg:     cfg,
+		conn:    conn,
+		log:     log,
+		stop:    stop,
+		sysErr:  sysErr,
+		pass:    pass,
+		journal: jn,
 	}
 }
 
@@ -70,13 +78,14 @@ func (w *Writer) syncMapToTron(m msg.Message) bool {
 			return false
 		default:
 			input := m.Payload[0].([]byte)
-			tx, err := w.sendTx(w.cfg.LightNode, input)
+			tx, err := w.sendTx(m, w.cfg.LightNode, input)
 			if err == nil {
 				w.log.Info("Sync Map Header to tron chain tx execution", "tx", tx, "src", m.Source, "dst", m.Destination)
-				err = w.txStatus(tx)
+				err = w.txStatus(m, tx)
 				if err != nil {
 					w.log.Warn("TxHash Status is not successful, will retry", "err", err)
 				} else {
+					alert.Resolve(context.Background(), w.cfg.Name, alert.KeyLightClient)
 					m.DoneCh <- struct{}{}
 					return true
 				}
@@ -94,8 +103,10 @@ func (w *Writer) syncMapToTron(m msg.Message) bool {
 				}
 			}
 			errorCount++
+			metrics.Retry(w.cfg.Id, m.Type)
 			if errorCount >= 10 {
-				util.Alarm(context.Background(), fmt.Sprintf("map2tron updateHeader failed, err is %s", err.Error()))
+				alert.Fire(context.Background(), alert.Warning, w.cfg.Name, alert.KeyLightClient,
+					fmt.Sprintf("map2tron updateHeader failed, err is %s", err.Error()))
 				errorCount = 0
 			}
 			time.Sleep(constant.BalanceRetryInterval)
@@ -117,7 +128,8 @@ func (w *Writer) exeMcs(m msg.Message) bool {
 				w.log.Error("check orderId exist failed ", "err", err, "orderId", common.Bytes2Hex(orderId))
 				checkIdCount++
 				if checkIdCount == 10 {
-					util.Alarm(context.Background(), fmt.Sprintf("writer mos checkOrderId failed, err is %s", err.Error()))
+					alert.Fire(context.Background(), alert.Warning, w.cfg.Name, alert.KeyOrderId,
+						fmt.Sprintf("writer mos checkOrderId failed, err is %s", err.Error()))
 					checkIdCount = 0
 				}
 			}
@@ -132,13 +144,14 @@ func (w *Writer) exeMcs(m msg.Message) bool {
 				inputHash = m.Payload[3]
 			}
 			w.log.Info("Send transaction", "addr", addr, "srcHash", inputHash)
-			mcsTx, err := w.sendTx(addr, m.Payload[0].([]byte))
+			mcsTx, err := w.sendTx(m, addr, m.Payload[0].([]byte))
 			if err == nil {
 				w.log.Info("Submitted cross tx execution", "src", m.Source, "dst", m.Destination, "srcHash", inputHash, "mcsTx", mcsTx)
-				err = w.txStatus(mcsTx)
+				err = w.txStatus(m, mcsTx)
 				if err != nil {
 					w.log.Warn("TxHash Status is not successful, will retry", "err", err)
 				} else {
+					w.mosResolve(inputHash)
 					m.DoneCh <- struct{}{}
 					return true
 				}
@@ -157,6 +170,7 @@ func (w *Writer) exeMcs(m msg.Message) bool {
 				w.log.Warn("Execution failed, will retry", "srcHash", inputHash, "err", err)
 			}
 			errorCount++
+			metrics.Retry(w.cfg.Id, m.Type)
 			if errorCount >= 10 {
 				w.mosAlarm(inputHash, err)
 				errorCount = 0
@@ -166,7 +180,11 @@ func (w *Writer) exeMcs(m msg.Message) bool {
 	}
 }
 
-func (w *Writer) sendTx(addr string, input []byte) (string, error) {
+func (w *Writer) sendTx(m msg.Message, addr string, input []byte) (string, error) {
+	identity := journal.Identity(methodOfTriggerContract, addr, input)
+	if txHash, ok := w.resumeTx(identity); ok {
+		return txHash, nil
+	}
 	// online estimateEnergy
 	contract, err := w.conn.cli.TriggerConstantContractByEstimate(w.cfg.From, addr, input)
 	if err != nil {
@@ -188,23 +206,92 @@ func (w *Writer) sendTx(addr string, input []byte) (string, error) {
 		return "", err
 	}
 
+	if err = w.checkPolicy(tx.Transaction); err != nil {
+		w.log.Error("Tx refused by the signing policy", "err", err)
+		return "", err
+	}
+
 	ks, acc, err := store.UnlockedKeystore(w.cfg.From, string(w.pass))
 	if err != nil {
 		w.log.Error("Failed to UnlockedKeystore", "err", err)
 		return "", err
 	}
-	controller := transaction.NewController(w.conn.cli, ks, acc, tx.Transaction)
-	if err = controller.ExecuteTransaction(); err != nil {
-		w.log.Error("Failed to ExecuteTransaction", "err", err)
+	if w.cfg.DryRun {
+		return w.simulateTx(m, ks, acc, addr, contract, tx)
+	}
+	signed, err := ks.SignTx(*acc, tx.Transaction)
+	if err != nil {
+		w.log.Error("Failed to SignTx", "err", err)
+		return "", err
+	}
+	txHash := common.Bytes2Hex(tx.GetTxid())
+	if err = w.auditTx(m, txHash, tx.Transaction); err != nil {
+		w.log.Error("Failed to audit tx", "tx", txHash, "err", err)
+		return "", err
+	}
+	if err = w.journalTx(identity, txHash, signed); err != nil {
+		w.log.Error("Failed to journal tx", "tx", txHash, "err", err)
+		return "", err
+	}
+	if _, err = w.conn.cli.Broadcast(signed); err != nil {
+		w.log.Error("Failed to Broadcast", "err", err)
+		w.forgetTx(txHash)
+		return "", err
+	}
+	return txHash, nil
+}
+
+// checkPolicy refuses txs other than contract calls allowed by the signing policy, the contract, data and call value
+// are read from the tx built by the node as it is what gets signed
+func (w *Writer) checkPolicy(tx *core.Transaction) error {
+	ctx := context.Background()
+	contracts := tx.GetRawData().GetContract()
+	if len(contracts) != 1 || contracts[0].GetType() != core.Transaction_Contract_TriggerSmartContract {
+		return w.cfg.Policy.Refuse(ctx, "", "", "only one contract call is allowed")
+	}
+	var call core.TriggerSmartContract
+	if err := contracts[0].GetParameter().UnmarshalTo(&call); err != nil {
+		return err
+	}
+	contract := address.Address(call.ContractAddress).String()
+	if call.CallTokenValue != 0 {
+		return w.cfg.Policy.Refuse(ctx, contract, signer.Selector(call.Data), "token value is not allowed")
+	}
+	return w.cfg.Policy.Check(ctx, contract, signer.Selector(call.Data), big.NewInt(call.CallValue), nil)
+}
+
+// simulateTx signs the tx and logs the TriggerConstantContract result instead of broadcasting it, only used in dry run mode
+func (w *Writer) simulateTx(m msg.Message, ks *keystore.KeyStore, acc *keystore.Account, addr string, contract, tx *api.TransactionExtention) (string, error) {
+	signed, err := ks.SignTx(*acc, tx.Transaction)
+	if err != nil {
+		w.log.Error("Dry run failed to SignTx", "err", err)
+		return "", err
+	}
+	if err = w.auditTx(m, common.Bytes2Hex(tx.GetTxid()), tx.Transaction); err != nil {
+		w.log.Error("Dry run failed to audit tx", "err", err)
 		return "", err
 	}
-	if controller.GetResultError() != nil {
-		return "", fmt.Errorf("contro resultError is %v", controller.GetResultError())
+	var result string
+	if len(contract.ConstantResult) > 0 {
+		result = common.Bytes2Hex(contract.ConstantResult[0])
 	}
+	w.log.Info("Dry run, tx simulated and not broadcast", "tx", common.Bytes2Hex(tx.GetTxid()), "to", addr,
+		"energy", contract.EnergyUsed, "signatures", len(signed.Signature), "result", result, "message", string(contract.GetResult().GetMessage()))
 	return common.Bytes2Hex(tx.GetTxid()), nil
 }
 
-func (w Writer) txStatus(txHash string) error {
+func (w Writer) txStatus(m msg.Message, txHash string) (err error) {
+	defer func() {
+		// a tx the node doesn't know yet may still be included, it is kept so the retry waits for it
+		if err != constant.ErrTxPending {
+			w.forgetTx(txHash)
+		}
+	}()
+	if w.cfg.DryRun {
+		w.log.Info("Dry run, skip waiting for tx receipt", "tx", txHash)
+		return nil
+	}
+	start := time.Now()
 	var count int64
 	time.Sleep(time.Second * 2)
 	for {
@@ -214,20 +301,50 @@ func (w Writer) txStatus(txHash string) error {
 			time.Sleep(constant.QueryRetryInterval)
 			count++
 			if count == 60 {
-				return err
+				if w.txExpired(txHash) {
+					return fmt.Errorf("txHash(%s) expired before it was included", txHash)
+				}
+				return constant.ErrTxPending
 			}
 			continue
 		}
 		if id.Ret[0].ContractRet == core.Transaction_Result_SUCCESS {
 			w.log.Info("Tx receipt status is success", "hash", txHash)
+			w.recordCost(m, txHash)
+			metrics.Confirmed(w.cfg.Id, time.Since(start))
+			w.auditReceipt(txHash, audit.StatusSuccess, "")
 			return nil
 		}
+		// a failed tx still burns its energy
+		w.recordCost(m, txHash)
+		w.auditReceipt(txHash, audit.StatusFailed, id.Ret[0].ContractRet.String())
 		return fmt.Errorf("txHash(%s), status not success, current status is (%s)", txHash, id.Ret[0].ContractRet.String())
 	}
 }
 
+// recordCost records the energy and fee burnt by the tx of the message, failed or not
+func (w Writer) recordCost(m msg.Message, txHash string) {
+	info, err := w.conn.cli.GetTransactionInfoByID(txHash)
+	if err != nil {
+		w.log.Warn("Failed to GetTransactionInfoByID, tx cost is not recorded", "tx", txHash, "err", err)
+		return
+	}
+	energy := info.GetReceipt().GetEnergyUsageTotal()
+	price := big.NewInt(0)
+	if energy != 0 {
+		price.SetInt64(info.GetReceipt().GetEnergyFee() / energy)
+	}
+	cost.Add(m, w.cfg.Id, txHash, uint64(energy), price, big.NewInt(info.GetFee()), cost.TronDecimals)
+}
+
 func (w *Writer) mosAlarm(tx interface{}, err error) {
-	util.Alarm(context.Background(), fmt.Sprintf("mos map2tron failed, srcHash=%v err is %s", tx, err.Error()))
+	alert.Fire(context.Background(), alert.Critical, w.cfg.Name, fmt.Sprintf("%s/%v", alert.KeyMos, tx),
+		fmt.Sprintf("mos map2tron failed, srcHash=%v err is %s", tx, err.Error()))
+}
+
+// mosResolve clears the alarm of the message of the source tx once it is executed
+func (w *Writer) mosResolve(tx interface{}) {
+	alert.Resolve(context.Background(), w.cfg.Name, fmt.Sprintf("%s/%v", alert.KeyMos, tx))
 }
 
 func (w *Writer) checkOrderId(toAddress string, input []byte) (bool, error) {
@@ -4,16 +4,29 @@
 package main
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"github.com/pkg/errors"
+	"io"
+	"os"
 	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
 
 	"github.com/lbtsm/gotron-sdk/pkg/account"
+	"github.com/lbtsm/gotron-sdk/pkg/store"
 
 	log "github.com/ChainSafe/log15"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/mapprotocol/compass/config"
 	"github.com/mapprotocol/compass/keystore"
+	"github.com/mapprotocol/compass/pkg/secret"
+	"github.com/mapprotocol/near-api-go/pkg/types/key"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
 )
 
 // dataHandler is a struct which wraps any extra data our CMD functions need that cannot be passed through parameters
@@ -40,36 +53,215 @@ func wrapHandler(hdl func(*cli.Context, *dataHandler) error) cli.ActionFunc {
 	}
 }
 
+// handleGenerateCmd creates a new key, encrypted with the password of --password or the prompt
+func handleGenerateCmd(ctx *cli.Context, dHandler *dataHandler) error {
+	log.Info("Generating key...")
+
+	switch {
+	case ctx.Bool(config.TronFlag.Name):
+		pk, err := crypto.GenerateKey()
+		if err != nil {
+			return err
+		}
+		return importTronKey(ctx, hex.EncodeToString(crypto.FromECDSA(pk)))
+	case ctx.Bool(config.NearFlag.Name):
+		kp, err := key.GenerateKeyPair(key.KeyTypeED25519, rand.Reader)
+		if err != nil {
+			return err
+		}
+		return writeNearKey(ctx, kp)
+	}
+
+	pk, err := crypto.GenerateKey()
+	if err != nil {
+		return err
+	}
+	path, err := keystore.WriteEthKey(dHandler.datadir, pk, string(encryptPassword(ctx)))
+	if err != nil {
+		return fmt.Errorf("write keystore failed, err is %v", err)
+	}
+	printKey(keystore.TypeEthereum, crypto.PubkeyToAddress(pk.PublicKey).Hex(), path)
+	return nil
+}
+
 // handleImportCmd imports external keystores into the bridge
 func handleImportCmd(ctx *cli.Context, dHandler *dataHandler) error {
 	log.Info("Importing key...")
 
-	if !ctx.Bool(config.TronFlag.Name) {
-		return errors.New("only support tron")
+	privkeyflag := ctx.String(config.PrivateKeyFlag.Name)
+	switch {
+	case ctx.Bool(config.EthereumImportFlag.Name):
+		src := ctx.Args().First()
+		if src == "" {
+			return errors.New("path of the ethereum keystore to import is missing")
+		}
+		password := ctx.String(config.PasswordFlag.Name)
+		if password == "" {
+			password = string(keystore.GetPassword(fmt.Sprintf("Enter password of key %s:", src)))
+		}
+		path, err := keystore.ImportEthKey(dHandler.datadir, src, password)
+		if err != nil {
+			return fmt.Errorf("import keystore failed, err is %v", err)
+		}
+		acc, err := keystore.AddressOf(path)
+		if err != nil {
+			return err
+		}
+		printKey(keystore.TypeEthereum, acc.Address, path)
+		return nil
+	case privkeyflag == "":
+		return fmt.Errorf("privateKey is nil")
+	case ctx.Bool(config.TronFlag.Name):
+		return importTronKey(ctx, privkeyflag)
+	case ctx.Bool(config.NearFlag.Name):
+		kp, err := key.NewBase58KeyPair(privkeyflag)
+		if err != nil {
+			return fmt.Errorf("invalid near private key, err is %v", err)
+		}
+		return writeNearKey(ctx, kp)
 	}
-	var err error
 
-	var password []byte = nil
-	if pwdflag := ctx.String(config.PasswordFlag.Name); pwdflag != "" {
-		password = []byte(pwdflag)
+	pk, err := crypto.HexToECDSA(strings.TrimPrefix(privkeyflag, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid private key, err is %v", err)
 	}
-	privkeyflag := ctx.String(config.PrivateKeyFlag.Name)
-	if privkeyflag == "" {
-		return fmt.Errorf("privateKey is nil")
+	path, err := keystore.WriteEthKey(dHandler.datadir, pk, string(encryptPassword(ctx)))
+	if err != nil {
+		return fmt.Errorf("write keystore failed, err is %v", err)
+	}
+	printKey(keystore.TypeEthereum, crypto.PubkeyToAddress(pk.PublicKey).Hex(), path)
+	return nil
+}
+
+// handleListCmd prints the keys of every chain type with their address
+func handleListCmd(ctx *cli.Context, dHandler *dataHandler) error {
+	home, err := nearHome(ctx)
+	if err != nil {
+		return err
+	}
+	accounts, err := keystore.ListAccounts(dHandler.datadir, home)
+	if err != nil {
+		return err
+	}
+	for _, name := range store.LocalAccounts() {
+		addr, err := store.AddressFromAccountName(name)
+		if err != nil {
+			continue
+		}
+		accounts = append(accounts, keystore.Account{Type: keystore.TypeTron, Address: addr,
+			Path: filepath.Join(store.DefaultLocation(), name)})
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tADDRESS\tNETWORK\tPATH")
+	for _, acc := range accounts {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", acc.Type, acc.Address, acc.Network, acc.Path)
+	}
+	return w.Flush()
+}
+
+// handleExportAddressCmd prints the address of a key, to be used as the from of a chain
+func handleExportAddressCmd(ctx *cli.Context, dHandler *dataHandler) error {
+	name := ctx.Args().First()
+	if name == "" {
+		return errors.New("key file or tron key name is missing")
+	}
+	if ctx.Bool(config.TronFlag.Name) {
+		addr, err := store.AddressFromAccountName(name)
+		if err != nil {
+			return fmt.Errorf("tron key %s: %v", name, err)
+		}
+		fmt.Println(addr)
+		return nil
+	}
+	path := name
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		path = filepath.Join(dHandler.datadir, name)
 	}
-	if password == nil {
-		password = keystore.GetPassword("Enter password to encrypt keystore file:")
+	acc, err := keystore.AddressOf(path)
+	if err != nil {
+		return err
 	}
+	fmt.Println(acc.Address)
+	return nil
+}
 
+// handleEncryptSecretCmd prints the ${secret:enc:...} reference of a config value encrypted with the password of
+// --password or the prompt. The value is read from the prompt, or from stdin if it isn't a terminal.
+func handleEncryptSecretCmd(ctx *cli.Context) error {
+	var value []byte
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		value = keystore.GetPassword("Enter the config value to encrypt:")
+	} else {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		value = bytes.TrimRight(data, "\r\n")
+	}
+	if len(value) == 0 {
+		return errors.New("value to encrypt is empty")
+	}
+	enc, err := secret.Encrypt(value, encryptPassword(ctx))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("${secret:enc:%s}\nThe password is read from %s or %s when the config is loaded\n", enc,
+		keystore.KeyEnv(secret.PasswordKey), keystore.EnvPassword)
+	return nil
+}
+
+func importTronKey(ctx *cli.Context, privateKey string) error {
 	name := ctx.String(config.TronKeyNameFlag.Name)
-	keyName, err := account.ImportFromPrivateKey(privkeyflag, name, string(password))
+	keyName, err := account.ImportFromPrivateKey(privateKey, name, string(encryptPassword(ctx)))
 	if err != nil {
 		return fmt.Errorf("tron import private key failed, err is %v", err)
 	}
-	fmt.Println("tron keystore save, key is", keyName, " please save you config file")
+	addr, err := store.AddressFromAccountName(keyName)
+	if err != nil {
+		return err
+	}
+	printKey(keystore.TypeTron, addr, filepath.Join(store.DefaultLocation(), keyName))
 	return nil
 }
 
+// writeNearKey saves the near credentials, encrypted only if --password is set, as near-cli doesn't encrypt them
+func writeNearKey(ctx *cli.Context, kp key.KeyPair) error {
+	home, err := nearHome(ctx)
+	if err != nil {
+		return err
+	}
+	id := ctx.String(config.NearAccountFlag.Name)
+	if id == "" {
+		id = keystore.NearImplicitAccount(kp)
+	}
+	path, err := keystore.WriteNearKey(home, ctx.String(config.NearNetworkFlag.Name), id, kp, ctx.String(config.PasswordFlag.Name))
+	if err != nil {
+		return fmt.Errorf("write near credentials failed, err is %v", err)
+	}
+	printKey(keystore.TypeNear, id, path)
+	return nil
+}
+
+// nearHome returns the directory holding .near-credentials, the --keystore directory if set or else the home
+func nearHome(ctx *cli.Context) (string, error) {
+	if ctx.IsSet(config.KeystorePathFlag.Name) {
+		return filepath.Abs(ctx.String(config.KeystorePathFlag.Name))
+	}
+	return os.UserHomeDir()
+}
+
+func encryptPassword(ctx *cli.Context) []byte {
+	if pwdflag := ctx.String(config.PasswordFlag.Name); pwdflag != "" {
+		return []byte(pwdflag)
+	}
+	return keystore.GetPassword("Enter password to encrypt keystore file:")
+}
+
+func printKey(typ, address, path string) {
+	fmt.Printf("%s key saved\n  address: %s\n  path:    %s\nUse the address as the from of the chain in your config file\n", typ, address, path)
+}
+
 // getDataDir obtains the path to the keystore and returns it as a string
 func getDataDir(ctx *cli.Context) (string, error) {
 	// key directory is datadir/keystore/
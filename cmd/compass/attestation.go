@@ -0,0 +1,45 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package main
+
+import (
+	"strings"
+
+	log "github.com/ChainSafe/log15"
+	"github.com/mapprotocol/compass/config"
+	"github.com/mapprotocol/compass/keystore"
+	"github.com/mapprotocol/compass/remoteattestation"
+	"github.com/urfave/cli/v2"
+)
+
+// startAttestation serves the attestation of the enclave, only the passwords of the relayer keys of cfg may be
+// provisioned over it, and keys without password wait for theirs instead of prompting
+func startAttestation(ctx *cli.Context, cfg *config.Config) (*remoteattestation.Server, error) {
+	var accounts []string
+	for _, c := range append([]config.RawChainConfig{cfg.MapChain}, cfg.Chains...) {
+		for _, from := range strings.Split(c.From, ",") {
+			accounts = append(accounts, strings.TrimSpace(from))
+		}
+	}
+
+	var provider remoteattestation.Provider = remoteattestation.Enclave{}
+	if ctx.Bool(config.AttestationSimulateFlag.Name) {
+		log.Warn("Attestation reports are simulated, they attest nothing")
+		provider = remoteattestation.Simulated{}
+	}
+	ra, err := remoteattestation.NewServer(remoteattestation.Config{
+		Addr:         ctx.String(config.AttestationAddrFlag.Name),
+		CertLifetime: ctx.Duration(config.AttestationCertLifetimeFlag.Name),
+		CertRotation: ctx.Duration(config.AttestationCertRotationFlag.Name),
+		Accounts:     accounts,
+	}, provider, log.New("system", "attestation"))
+	if err != nil {
+		return nil, err
+	}
+	if err = ra.Start(); err != nil {
+		return nil, err
+	}
+	keystore.AwaitProvisioning(ctx.Duration(config.AttestationSecretWaitFlag.Name))
+	return ra, nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/mapprotocol/compass/config"
+	"github.com/mapprotocol/compass/pkg/audit"
+	"github.com/urfave/cli/v2"
+)
+
+// handleAuditVerifyCmd checks the chain of hashes of the audit logs given as arguments, or else of all the audit
+// logs in the blockstore directory
+func handleAuditVerifyCmd(ctx *cli.Context) error {
+	files := ctx.Args().Slice()
+	if len(files) == 0 {
+		dir, err := audit.Dir(ctx.String(config.BlockstorePathFlag.Name))
+		if err != nil {
+			return err
+		}
+		if files, err = audit.Files(dir); err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("no audit log in %s", dir)
+		}
+	}
+	var broken int
+	for _, f := range files {
+		n, err := audit.Verify(f)
+		if err != nil {
+			fmt.Printf("%s: %v, %d entries verified before\n", f, err, n)
+			broken++
+			continue
+		}
+		fmt.Printf("%s: ok, %d entries\n", f, n)
+	}
+	if broken != 0 {
+		return cli.Exit(fmt.Sprintf("%d of %d audit logs failed verification", broken, len(files)), 1)
+	}
+	return nil
+}
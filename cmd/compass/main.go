@@ -2,6 +2,7 @@ package main
 
 import (
 	"errors"
+	"net/http"
 	"os"
 	"strconv"
 
@@ -9,7 +10,12 @@ import (
 
 	"github.com/mapprotocol/compass/chains/bttc"
 
-	"github.com/mapprotocol/compass/pkg/util"
+	"github.com/mapprotocol/compass/pkg/alert"
+	"github.com/mapprotocol/compass/pkg/audit"
+	"github.com/mapprotocol/compass/pkg/cost"
+	"github.com/mapprotocol/compass/pkg/health"
+	"github.com/mapprotocol/compass/pkg/instrument"
+	"github.com/mapprotocol/compass/pkg/metrics"
 
 	"github.com/mapprotocol/compass/chains/conflux"
 	"github.com/mapprotocol/compass/chains/platon"
@@ -30,8 +36,6 @@ import (
 	"github.com/mapprotocol/compass/mapprotocol"
 	"github.com/mapprotocol/compass/msg"
 	"github.com/urfave/cli/v2"
-
-	"github.com/mapprotocol/compass/remoteattestation"
 )
 
 var app = cli.NewApp()
@@ -45,6 +49,19 @@ var cliFlags = []cli.Flag{
 	config.FreshStartFlag,
 	config.LatestBlockFlag,
 	config.SkipErrorFlag,
+	config.DryRunFlag,
+	config.SlowRpcFlag,
+	config.AttestationFlag,
+	config.AttestationAddrFlag,
+	config.AttestationCertLifetimeFlag,
+	config.AttestationCertRotationFlag,
+	config.AttestationSecretWaitFlag,
+	config.AttestationSimulateFlag,
+	config.MetricsFlag,
+	config.MetricsAddrFlag,
+	config.HealthFlag,
+	config.HealthMaxBlockAgeFlag,
+	config.HealthMaxMessageAgeFlag,
 }
 
 var devFlags = []cli.Flag{
@@ -58,31 +75,145 @@ var importFlags = []cli.Flag{
 	config.KeystorePathFlag,
 	config.TronFlag,
 	config.TronKeyNameFlag,
+	config.NearFlag,
+	config.NearAccountFlag,
+	config.NearNetworkFlag,
+}
+
+var generateFlags = []cli.Flag{
+	config.PasswordFlag,
+	config.KeystorePathFlag,
+	config.TronFlag,
+	config.TronKeyNameFlag,
+	config.NearFlag,
+	config.NearAccountFlag,
+	config.NearNetworkFlag,
+}
+
+var listFlags = []cli.Flag{
+	config.KeystorePathFlag,
+}
+
+var exportAddressFlags = []cli.Flag{
+	config.KeystorePathFlag,
+	config.TronFlag,
+}
+
+var encryptSecretFlags = []cli.Flag{
+	config.PasswordFlag,
 }
 
 var accountCommand = cli.Command{
 	Name:  "accounts",
 	Usage: "manage bridge keystore",
 	Description: "The accounts command is used to manage the bridge keystore.\n" +
-		"\tTo import a tron private key file: compass accounts import --privateKey private_key",
+		"\tEthereum keystores are kept in the --keystore directory, tron keystores in ~/.tronctl and near credentials\n" +
+		"\tin .near-credentials below the home, or below the --keystore directory if it is set.\n" +
+		"\tTo import a tron private key file: compass accounts import --tron --privateKey private_key",
 	Subcommands: []*cli.Command{
+		{
+			Action: wrapHandler(handleGenerateCmd),
+			Name:   "generate",
+			Usage:  "generate a new bridge keystore",
+			Flags:  generateFlags,
+			Description: "The generate subcommand is used to generate a new key for the bridge.\n" +
+				"\tAn ethereum keystore is generated by default, use --tron or --near for the other chain types.\n" +
+				"\tNear keys get the implicit account of their public key unless --account is set.",
+		},
 		{
 			Action: wrapHandler(handleImportCmd),
 			Name:   "import",
 			Usage:  "import bridge keystore",
 			Flags:  importFlags,
 			Description: "The import subcommand is used to import a keystore for the bridge.\n" +
-				"\tA path to the keystore must be provided\n" +
-				"\tUse --privateKey to create a keystore from a provided private key.",
+				"\tUse --ethereum /path/to/key to copy an existing ethereum keystore, such as from geth.\n" +
+				"\tUse --privateKey to create a keystore from a provided private key, with --tron or --near for\n" +
+				"\tthose chain types. Near private keys are given as ed25519:<base58>.",
+		},
+		{
+			Action: wrapHandler(handleListCmd),
+			Name:   "list",
+			Usage:  "list bridge keystores",
+			Flags:  listFlags,
+			Description: "The list subcommand prints the ethereum, tron and near keys with their address,\n" +
+				"\tthe accounts to fund on each chain.",
+		},
+		{
+			Action:    wrapHandler(handleExportAddressCmd),
+			Name:      "export-address",
+			Usage:     "print the address of a keystore",
+			ArgsUsage: "<keystore file | tron key name>",
+			Flags:     exportAddressFlags,
+			Description: "The export-address subcommand prints the address of an ethereum keystore or near credentials file,\n" +
+				"\tor of a tron key name with --tron. No password is needed.",
+		},
+		{
+			Action: handleEncryptSecretCmd,
+			Name:   "encrypt-secret",
+			Usage:  "encrypt a config value",
+			Flags:  encryptSecretFlags,
+			Description: "The encrypt-secret subcommand encrypts a value entered at the prompt, such as an endpoint with an\n" +
+				"\tapi key, and prints the ${secret:enc:...} reference to put in the config file instead.",
+		},
+	},
+}
+
+var reportFlags = []cli.Flag{
+	config.BlockstorePathFlag,
+	config.SinceFlag,
+}
+
+var reportCommand = cli.Command{
+	Name:        "report",
+	Usage:       "report relayer statistics",
+	Description: "The report command is used to summarize what the relayer recorded locally",
+	Subcommands: []*cli.Command{
+		{
+			Action: handleReportCostsCmd,
+			Name:   "costs",
+			Usage:  "report gas spent by writer transactions",
+			Flags:  reportFlags,
+			Description: "The costs subcommand aggregates the confirmed writer transactions by chain, route and message type.\n" +
+				"\tRecords are read from the blockstore directory, or ~/" + cost.PathPostfix + " if it is not set.\n" +
+				"\tTo report the last week: compass report costs --since 7d",
+		},
+	},
+}
+
+var auditCommand = cli.Command{
+	Name:        "audit",
+	Usage:       "inspect the audit log of signed transactions",
+	Description: "The audit command is used to check the audit log the relayer keeps of every transaction it signs",
+	Subcommands: []*cli.Command{
+		{
+			Action:    handleAuditVerifyCmd,
+			Name:      "verify",
+			Usage:     "verify the chain of hashes of audit logs",
+			ArgsUsage: "[file...]",
+			Flags:     []cli.Flag{config.BlockstorePathFlag},
+			Description: "The verify subcommand checks that no entry of the audit logs was changed, removed or inserted.\n" +
+				"\tThe logs given as arguments are checked, or else all logs of the blockstore directory, or ~/" + audit.PathPostfix + "\n" +
+				"\tif it is not set. It exits with a non-zero status if a log is broken.",
 		},
 	},
 }
 
+// passwordHelp tells how the commands running a relayer unlock its keys
+const passwordHelp = "\n\tThe keystore password of a key is looked up in this order:\n" +
+	"\t  1. the env var set in the chain opts.keystorePasswordEnv, or else KEYSTORE_PASSWORD_<ADDRESS>,\n" +
+	"\t     the address of the key upper-cased without 0x, with other characters than letters and digits as _\n" +
+	"\t  2. the file set in the chain opts.keystorePasswordFile\n" +
+	"\t  3. the KEYSTORE_PASSWORD env var\n" +
+	"\t  4. a prompt, only when stdin is a terminal\n" +
+	"\tThis applies to EVM and Tron keystores, and to NEAR credential files encrypted like an ethereum keystore.\n" +
+	"\tWith --attestation, a password provisioned over the attestation server is used before KEYSTORE_PASSWORD,\n" +
+	"\tand the prompt is replaced by waiting --attestation-secret-wait for it to be provisioned."
+
 var maintainerCommand = cli.Command{
 	Name:  "maintainer",
 	Usage: "manage maintainer operations",
 	Description: "The maintainer command is used to manage maintainer on Map chain.\n" +
-		"\tTo register an account : compass relayers register --account '0x0...'",
+		"\tTo register an account : compass relayers register --account '0x0...'" + passwordHelp,
 	Action:      maintainer,
 	Subcommands: []*cli.Command{},
 	Flags:       append(app.Flags, cliFlags...),
@@ -91,7 +222,7 @@ var maintainerCommand = cli.Command{
 var messengerCommand = cli.Command{
 	Name:        "messenger",
 	Usage:       "manage messenger operations",
-	Description: "The messenger command is used to sync the log information of transactions in the block",
+	Description: "The messenger command is used to sync the log information of transactions in the block" + passwordHelp,
 	Action:      messenger,
 	Flags:       append(app.Flags, cliFlags...),
 }
@@ -99,7 +230,7 @@ var messengerCommand = cli.Command{
 var oracleCommand = cli.Command{
 	Name:        "oracle",
 	Usage:       "manage oracle operations",
-	Description: "The oracle command is used to sync the log information of transactions in the block",
+	Description: "The oracle command is used to sync the log information of transactions in the block" + passwordHelp,
 	Action:      oracle,
 	Flags:       append(app.Flags, cliFlags...),
 }
@@ -122,6 +253,8 @@ func init() {
 		&maintainerCommand,
 		&messengerCommand,
 		&oracleCommand,
+		&reportCommand,
+		&auditCommand,
 	}
 
 	app.Flags = append(app.Flags, cliFlags...)
@@ -175,11 +308,47 @@ func run(ctx *cli.Context, role mapprotocol.Role) error {
 		return err
 	}
 
-	log.Debug("Config on initialization...", "config", *cfg)
+	log.Debug("Config on initialization...", "config", cfg.Redacted())
 
-	remoteattestation.Ra_server()
+	if ctx.Bool(config.AttestationFlag.Name) {
+		ra, err := startAttestation(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		defer ra.Stop()
+	}
 
-	util.Init(cfg.Other.Env, cfg.Other.MonitorUrl)
+	health.Init(role, health.Config{
+		MaxBlockAge:   ctx.Duration(config.HealthMaxBlockAgeFlag.Name),
+		MaxMessageAge: ctx.Duration(config.HealthMaxMessageAgeFlag.Name),
+	})
+	if ctx.Bool(config.MetricsFlag.Name) || ctx.Bool(config.HealthFlag.Name) {
+		srv := metrics.NewServer(ctx.String(config.MetricsAddrFlag.Name), log.New("system", "metrics"))
+		if ctx.Bool(config.HealthFlag.Name) {
+			srv.Handle("/healthz", http.HandlerFunc(health.HealthHandler))
+			srv.Handle("/readyz", http.HandlerFunc(health.ReadyHandler))
+		}
+		if err = srv.Start(); err != nil {
+			return err
+		}
+		defer srv.Stop()
+	}
+
+	if err = alert.Init(cfg.Other.Env, cfg.Other.AlertConfig()); err != nil {
+		return err
+	}
+	instrument.SetSlowThreshold(ctx.Duration(config.SlowRpcFlag.Name))
+	err = cost.Init(ctx.String(config.BlockstorePathFlag.Name), role)
+	if err != nil {
+		return err
+	}
+	auditDir, err := audit.Dir(ctx.String(config.BlockstorePathFlag.Name))
+	if err != nil {
+		return err
+	}
+	if err = audit.Init(auditDir, role); err != nil {
+		return err
+	}
 	// Used to signal core shutdown due to fatal error
 	sysErr := make(chan error)
 	mapcid, err := strconv.Atoi(cfg.MapChain.Id)
@@ -191,6 +360,7 @@ func run(ctx *cli.Context, role mapprotocol.Role) error {
 	allChains := make([]config.RawChainConfig, 0, len(cfg.Chains)+1)
 	allChains = append(allChains, cfg.MapChain)
 	allChains = append(allChains, cfg.Chains...)
+	chainIds := make([]msg.ChainId, 0, len(allChains))
 
 	for idx, chain := range allChains {
 		ks := chain.KeystorePath
@@ -217,6 +387,7 @@ func run(ctx *cli.Context, role mapprotocol.Role) error {
 			LatestBlock:      ctx.Bool(config.LatestBlockFlag.Name),
 			Opts:             chain.Opts,
 			SkipError:        ctx.Bool(config.SkipErrorFlag.Name),
+			DryRun:           ctx.Bool(config.DryRunFlag.Name),
 		}
 		var (
 			newChain core.Chain
@@ -224,6 +395,9 @@ func run(ctx *cli.Context, role mapprotocol.Role) error {
 
 		logger := log.Root().New("chain", chainConfig.Name)
 		logger.Info("This task set skip error", "skip", ctx.Bool(config.SkipErrorFlag.Name))
+		if chainConfig.DryRun {
+			logger.Warn("Dry run mode enabled, transactions will be simulated but never broadcast")
+		}
 
 		switch chain.Type {
 		case chains.Ethereum:
@@ -264,6 +438,12 @@ func run(ctx *cli.Context, role mapprotocol.Role) error {
 
 		mapprotocol.OnlineChaId[chainConfig.Id] = chainConfig.Name
 		c.AddChain(newChain)
+		chainIds = append(chainIds, chainConfig.Id)
+	}
+	if ctx.Bool(config.MetricsFlag.Name) {
+		stop := make(chan struct{})
+		defer close(stop)
+		go metrics.WatchLightClients(msg.ChainId(mapcid), chainIds, metrics.LightClientInterval, stop)
 	}
 	c.Start()
 
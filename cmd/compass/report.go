@@ -0,0 +1,56 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mapprotocol/compass/config"
+	"github.com/mapprotocol/compass/pkg/cost"
+	"github.com/urfave/cli/v2"
+)
+
+// handleReportCostsCmd prints the gas spent by writer transactions aggregated by chain, route and message type
+func handleReportCostsCmd(ctx *cli.Context) error {
+	since, err := parseSince(ctx.String(config.SinceFlag.Name))
+	if err != nil {
+		return err
+	}
+	dir, err := cost.Dir(ctx.String(config.BlockstorePathFlag.Name))
+	if err != nil {
+		return err
+	}
+	records, err := cost.Load(dir, time.Now().Add(-since))
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHAIN\tROUTE\tTYPE\tTXS\tGAS USED\tFEE")
+	for _, s := range cost.Aggregate(records) {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\n", s.Chain, s.Route, s.Type, s.Count, s.GasUsed, s.FeeString())
+	}
+	return w.Flush()
+}
+
+// parseSince parses a duration which additionally accepts days, e.g. 7d
+func parseSince(v string) (time.Duration, error) {
+	if strings.HasSuffix(v, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(v, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid --%s %q", config.SinceFlag.Name, v)
+		}
+		return time.Duration(days) * time.Hour * 24, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --%s %q", config.SinceFlag.Name, v)
+	}
+	return d, nil
+}
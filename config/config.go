@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/mapprotocol/compass/pkg/alert"
+	"github.com/mapprotocol/compass/pkg/secret"
 	"github.com/urfave/cli/v2"
 )
 
@@ -18,6 +20,7 @@ type Config struct {
 	MapChain RawChainConfig   `json:"mapchain"`
 	Chains   []RawChainConfig `json:"chains"`
 	Other    Construction     `json:"other,omitempty"`
+	secrets  []string         // values of the ${secret:...} references, redacted in logs
 }
 
 // RawChainConfig is parsed directly from the config file and should be using to construct the core.ChainConfig
@@ -33,8 +36,21 @@ type RawChainConfig struct {
 }
 
 type Construction struct {
-	MonitorUrl string `json:"monitor_url,omitempty"`
-	Env        string `json:"env,omitempty"`
+	MonitorUrl string       `json:"monitor_url,omitempty"`
+	Env        string       `json:"env,omitempty"`
+	Alert      alert.Config `json:"alert,omitempty"`
+}
+
+// MonitorSink is the name of the slack sink of the legacy monitor_url
+const MonitorSink = "monitor_url"
+
+// AlertConfig returns the alert config with the monitor_url, if set, as a slack sink named MonitorSink
+func (c Construction) AlertConfig() alert.Config {
+	ret := c.Alert
+	if c.MonitorUrl != "" {
+		ret.Sinks = append([]alert.SinkConfig{{Name: MonitorSink, Type: alert.SinkSlack, Url: c.MonitorUrl}}, ret.Sinks...)
+	}
+	return ret
 }
 
 func (c *Config) ToJSON(file string) *os.File {
@@ -108,6 +124,9 @@ func GetConfig(ctx *cli.Context) (*Config, error) {
 		return &fig, err
 	}
 	log.Debug("Loaded config", "path", path)
+	if err = fig.resolveSecrets(); err != nil {
+		return nil, err
+	}
 	err = fig.validate()
 	// fill map chain config
 	fig.MapChain.Type = "ethereum"
@@ -119,6 +138,80 @@ func GetConfig(ctx *cli.Context) (*Config, error) {
 	return &fig, nil
 }
 
+// resolveSecrets replaces the ${secret:<provider>:<argument>} references of the chain values, of the monitor url and
+// of the alert sinks
+func (c *Config) resolveSecrets() error {
+	resolve := func(where string, v *string) error {
+		ret, secrets, err := secret.Interpolate(*v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", where, err)
+		}
+		*v = ret
+		c.secrets = append(c.secrets, secrets...)
+		return nil
+	}
+	chains := []*RawChainConfig{&c.MapChain}
+	for i := range c.Chains {
+		chains = append(chains, &c.Chains[i])
+	}
+	for _, chain := range chains {
+		for name, v := range map[string]*string{"endpoint": &chain.Endpoint, "from": &chain.From,
+			"network": &chain.Network, "keystorePath": &chain.KeystorePath} {
+			if err := resolve(fmt.Sprintf("chain %s %s", chain.Id, name), v); err != nil {
+				return err
+			}
+		}
+		for k, v := range chain.Opts {
+			if err := resolve(fmt.Sprintf("chain %s opts.%s", chain.Id, k), &v); err != nil {
+				return err
+			}
+			chain.Opts[k] = v
+		}
+	}
+	for i := range c.Other.Alert.Sinks {
+		sink := &c.Other.Alert.Sinks[i]
+		for name, v := range map[string]*string{"url": &sink.Url, "token": &sink.Token, "chatId": &sink.ChatId,
+			"smtp": &sink.Smtp, "username": &sink.Username, "password": &sink.Password} {
+			if err := resolve(fmt.Sprintf("alert sink %s %s", sink.Name, name), v); err != nil {
+				return err
+			}
+		}
+	}
+	return resolve("other.monitor_url", &c.Other.MonitorUrl)
+}
+
+// Redacted returns a copy of the config with the secrets replaced, to be logged
+func (c *Config) Redacted() Config {
+	redactChain := func(chain RawChainConfig) RawChainConfig {
+		chain.Endpoint = secret.Redact(chain.Endpoint, c.secrets)
+		chain.From = secret.Redact(chain.From, c.secrets)
+		chain.Network = secret.Redact(chain.Network, c.secrets)
+		chain.KeystorePath = secret.Redact(chain.KeystorePath, c.secrets)
+		opts := make(map[string]string, len(chain.Opts))
+		for k, v := range chain.Opts {
+			opts[k] = secret.Redact(v, c.secrets)
+		}
+		chain.Opts = opts
+		return chain
+	}
+	ret := Config{MapChain: redactChain(c.MapChain), Other: c.Other}
+	for _, chain := range c.Chains {
+		ret.Chains = append(ret.Chains, redactChain(chain))
+	}
+	ret.Other.MonitorUrl = secret.Redact(ret.Other.MonitorUrl, c.secrets)
+	ret.Other.Alert.Sinks = nil
+	for _, sink := range c.Other.Alert.Sinks {
+		sink.Url = secret.Redact(sink.Url, c.secrets)
+		sink.Token = secret.Redact(sink.Token, c.secrets)
+		sink.ChatId = secret.Redact(sink.ChatId, c.secrets)
+		sink.Smtp = secret.Redact(sink.Smtp, c.secrets)
+		sink.Username = secret.Redact(sink.Username, c.secrets)
+		sink.Password = secret.Redact(sink.Password, c.secrets)
+		ret.Other.Alert.Sinks = append(ret.Other.Alert.Sinks, sink)
+	}
+	return ret
+}
+
 func loadConfig(file string, config *Config) error {
 	ext := filepath.Ext(file)
 	fp, err := filepath.Abs(file)
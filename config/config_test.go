@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mapprotocol/compass/pkg/alert"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	t.Setenv("TEST_RPC_KEY", "rpc-key")
+	t.Setenv("TEST_REDIS_PASSWORD", "redis-pass")
+	cfg := Config{
+		MapChain: RawChainConfig{Id: "22776", Endpoint: "https://map.example/${secret:env:TEST_RPC_KEY}",
+			Opts: map[string]string{"redis": "redis://:${secret:env:TEST_REDIS_PASSWORD}@localhost:6379", "mcs": "0x1234"}},
+		Chains: []RawChainConfig{{Id: "1", Endpoint: "https://eth.example/v3/${secret:env:TEST_RPC_KEY}"}},
+		Other: Construction{MonitorUrl: "https://hooks.example/${secret:env:TEST_RPC_KEY}", Alert: alert.Config{
+			Sinks: []alert.SinkConfig{{Name: "tg", Type: alert.SinkTelegram, Token: "${secret:env:TEST_RPC_KEY}", ChatId: "42"}}}},
+	}
+	if err := cfg.resolveSecrets(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Chains[0].Endpoint != "https://eth.example/v3/rpc-key" || cfg.MapChain.Opts["redis"] != "redis://:redis-pass@localhost:6379" ||
+		cfg.Other.MonitorUrl != "https://hooks.example/rpc-key" || cfg.Other.Alert.Sinks[0].Token != "rpc-key" {
+		t.Errorf("Unexpected resolved config %+v", cfg)
+	}
+
+	logged := fmt.Sprintf("%+v", cfg.Redacted())
+	if strings.Contains(logged, "rpc-key") || strings.Contains(logged, "redis-pass") || !strings.Contains(logged, "0x1234") {
+		t.Errorf("Unexpected redacted config %s", logged)
+	}
+	if cfg.MapChain.Opts["redis"] != "redis://:redis-pass@localhost:6379" || cfg.Other.Alert.Sinks[0].Token != "rpc-key" {
+		t.Error("Redacted changed the config")
+	}
+
+	sinks := cfg.Other.AlertConfig().Sinks
+	if len(sinks) != 2 || sinks[0].Name != MonitorSink || sinks[0].Url != "https://hooks.example/rpc-key" {
+		t.Errorf("Unexpected alert sinks %+v", sinks)
+	}
+
+	cfg.Chains[0].Opts = map[string]string{"redis": "${secret:env:TEST_UNSET_SECRET}"}
+	if err := cfg.resolveSecrets(); err == nil || !strings.Contains(err.Error(), "chain 1 opts.redis") {
+		t.Errorf("Expected an unset secret to fail with its option, got %v", err)
+	}
+}
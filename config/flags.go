@@ -4,6 +4,8 @@
 package config
 
 import (
+	"time"
+
 	log "github.com/ChainSafe/log15"
 	"github.com/urfave/cli/v2"
 )
@@ -42,6 +44,20 @@ var (
 		Value: "",
 	}
 
+	NearFlag = &cli.BoolFlag{
+		Name:  "near",
+		Usage: "Use near credentials instead of ethereum keystores",
+	}
+	NearAccountFlag = &cli.StringFlag{
+		Name:  "account",
+		Usage: "Near account id of the key, the implicit account of the key if empty",
+	}
+	NearNetworkFlag = &cli.StringFlag{
+		Name:  "network",
+		Usage: "Near network of the credentials, as the network field of the chain config",
+		Value: "mainnet",
+	}
+
 	BlockstorePathFlag = &cli.StringFlag{
 		Name:  "blockstore",
 		Usage: "Specify path for blockstore",
@@ -62,6 +78,82 @@ var (
 		Name:  "skipError",
 		Usage: "Skip Error",
 	}
+
+	DryRunFlag = &cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "Build, sign and simulate transactions without broadcasting them",
+	}
+
+	SlowRpcFlag = &cli.DurationFlag{
+		Name:  "slow-rpc",
+		Usage: "Log rpc calls taking longer than this with their parameters, 0 disables the log",
+		Value: 5 * time.Second,
+	}
+)
+
+var (
+	AttestationFlag = &cli.BoolFlag{
+		Name:  "attestation",
+		Usage: "Serve the remote attestation of the enclave, and accept keystore passwords over it",
+	}
+	AttestationAddrFlag = &cli.StringFlag{
+		Name:  "attestation-addr",
+		Usage: "Listen address of the attestation server",
+		Value: "127.0.0.1:8999",
+	}
+	AttestationCertLifetimeFlag = &cli.DurationFlag{
+		Name:  "attestation-cert-lifetime",
+		Usage: "Validity of the attestation server certificate",
+		Value: 24 * time.Hour,
+	}
+	AttestationCertRotationFlag = &cli.DurationFlag{
+		Name:  "attestation-cert-rotation",
+		Usage: "How often the attestation server certificate and report are renewed, shorter than its lifetime",
+		Value: 12 * time.Hour,
+	}
+	AttestationSecretWaitFlag = &cli.DurationFlag{
+		Name:  "attestation-secret-wait",
+		Usage: "How long to wait for a keystore password to be provisioned over the attestation server, 0 doesn't wait",
+		Value: 10 * time.Minute,
+	}
+	AttestationSimulateFlag = &cli.BoolFlag{
+		Name:  "attestation-simulate",
+		Usage: "Serve simulated attestation reports, for tests outside an enclave",
+	}
+)
+
+var (
+	MetricsFlag = &cli.BoolFlag{
+		Name:  "metrics",
+		Usage: "Serve prometheus metrics on /metrics",
+	}
+	MetricsAddrFlag = &cli.StringFlag{
+		Name:  "metrics-addr",
+		Usage: "Listen address of the metrics server",
+		Value: "127.0.0.1:9100",
+	}
+	HealthFlag = &cli.BoolFlag{
+		Name:  "health",
+		Usage: "Serve /healthz and /readyz on the metrics server, which is started too",
+	}
+	HealthMaxBlockAgeFlag = &cli.DurationFlag{
+		Name:  "health-max-block-age",
+		Usage: "Report a chain unhealthy when its listener processed no block for this long, 0 disables the check",
+		Value: 30 * time.Minute,
+	}
+	HealthMaxMessageAgeFlag = &cli.DurationFlag{
+		Name:  "health-max-message-age",
+		Usage: "Report a chain unhealthy when its writer is on the same message for this long, 0 disables the check",
+		Value: time.Hour,
+	}
+)
+
+var (
+	SinceFlag = &cli.StringFlag{
+		Name:  "since",
+		Usage: "Only include records newer than this, e.g. 7d, 12h or 30m",
+		Value: "7d",
+	}
 )
 
 var (
@@ -1,7 +1,11 @@
 package eth2
 
 import (
+	"context"
+	eth "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
 	"math/big"
 
 	"github.com/mapprotocol/compass/core"
@@ -9,36 +13,62 @@ import (
 	"github.com/ChainSafe/log15"
 	"github.com/mapprotocol/compass/connections/ethereum"
 	"github.com/mapprotocol/compass/internal/eth2"
+	"github.com/mapprotocol/compass/pkg/quorum"
 )
 
 type Connection struct {
 	endpoint, eth2Endpoint string
+	chain                  string
 	core.Connection
 	eth2Conn *eth2.Client
 }
 
 // NewConnection returns an uninitialized connection, must call Connection.Connect() before using.
 func NewConnection(endpoint, eth2Endpoint string, http bool, kp *keystore.Key, log log15.Logger, gasLimit, gasPrice *big.Int,
-	gasMultiplier float64) core.Eth2Connection {
-	conn := ethereum.NewConnection(endpoint, http, kp, log, gasLimit, gasPrice, gasMultiplier)
+	gasMultiplier float64, connOpts core.ConnOpts) core.Eth2Connection {
+	conn := ethereum.NewConnection(endpoint, http, kp, log, gasLimit, gasPrice, gasMultiplier, connOpts)
 	return &Connection{
 		Connection:   conn,
 		endpoint:     endpoint,
 		eth2Endpoint: eth2Endpoint,
+		chain:        connOpts.Name,
 	}
 }
 
+// QuorumEndpoints returns the endpoints of the execution layer connection
+func (c *Connection) QuorumEndpoints() []quorum.Endpoint {
+	if qc, ok := c.Connection.(core.QuorumConnection); ok {
+		return qc.QuorumEndpoints()
+	}
+	return nil
+}
+
+// SubscribeState subscribes to the transport state changes of the execution layer endpoints
+func (c *Connection) SubscribeState(ch chan<- core.ConnEvent) event.Subscription {
+	return c.Connection.(core.ReconnectingConnection).SubscribeState(ch)
+}
+
+// SubscribeFilterLogs subscribes to the logs of the execution layer, made again after the endpoint drops
+func (c *Connection) SubscribeFilterLogs(q eth.FilterQuery, ch chan<- types.Log) event.Subscription {
+	return c.Connection.(core.ReconnectingConnection).SubscribeFilterLogs(q, ch)
+}
+
+// SubscribeNewHead subscribes to the heads of the execution layer, made again after the endpoint drops
+func (c *Connection) SubscribeNewHead(ch chan<- *types.Header) event.Subscription {
+	return c.Connection.(core.ReconnectingConnection).SubscribeNewHead(ch)
+}
+
 func (c *Connection) Eth2Client() *eth2.Client {
 	return c.eth2Conn
 }
 
 // Connect starts the ethereum WS connection
-func (c *Connection) Connect() error {
-	if err := c.Connection.Connect(); err != nil {
+func (c *Connection) Connect(ctx context.Context) error {
+	if err := c.Connection.Connect(ctx); err != nil {
 		return err
 	}
 
-	client, err := eth2.DialHttp(c.eth2Endpoint)
+	client, err := eth2.DialHttp(c.chain, c.eth2Endpoint)
 	if err != nil {
 		return err
 	}
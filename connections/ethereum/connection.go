@@ -6,7 +6,9 @@ package ethereum
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/event"
 	"math/big"
 	"sync"
 	"time"
@@ -16,10 +18,9 @@ import (
 	"github.com/ChainSafe/log15"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethcommon "github.com/ethereum/go-ethereum/common"
-	ethcrypto "github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/mapprotocol/compass/internal/constant"
 	"github.com/mapprotocol/compass/pkg/ethclient"
+	"github.com/mapprotocol/compass/pkg/quorum"
 )
 
 type Connection struct {
@@ -29,19 +30,21 @@ type Connection struct {
 	gasLimit                  *big.Int
 	maxGasPrice               *big.Int
 	gasMultiplier             *big.Float
-	conn                      *ethclient.Client
-	opts                      *bind.TransactOpts
-	callOpts                  *bind.CallOpts
-	nonce                     uint64
-	optsLock                  sync.Mutex
+	connOpts                  core.ConnOpts
+	endpoints                 []*endpoint
+	active                    int // index of the endpoint reads are routed to
+	endpointsLock             sync.RWMutex
+	stateFeed                 event.Feed // endpoint transport state changes
 	log                       log15.Logger
 	stop                      chan int // All routines should exit when this channel is closed
-	reqTime, cacheBlockNumber int64
+	blockLock                 sync.Mutex
+	reqTime, cacheBlockNumber int64 // latest block cached for a second, guarded by blockLock
 }
 
 // NewConnection returns an uninitialized connection, must call Connection.Connect() before using.
+// endpoint may be a comma-separated list of urls, reads are then routed to the healthiest one.
 func NewConnection(endpoint string, http bool, kp *keystore.Key, log log15.Logger, gasLimit, gasPrice *big.Int,
-	gasMultiplier float64) core.Connection {
+	gasMultiplier float64, connOpts core.ConnOpts) core.Connection {
 	bigFloat := new(big.Float).SetFloat64(gasMultiplier)
 	return &Connection{
 		endpoint:      endpoint,
@@ -50,89 +53,76 @@ func NewConnection(endpoint string, http bool, kp *keystore.Key, log log15.Logge
 		gasLimit:      gasLimit,
 		maxGasPrice:   gasPrice,
 		gasMultiplier: bigFloat,
+		connOpts:      connOpts,
 		log:           log,
 		stop:          make(chan int),
 	}
 }
 
 // Connect starts the ethereum WS connection
-func (c *Connection) Connect() error {
-	c.log.Info("Connecting to ethereum chain...", "url", c.endpoint)
-	var rpcClient *rpc.Client
+func (c *Connection) Connect(ctx context.Context) error {
+	c.endpoints = splitEndpoints(c.endpoint)
+	if len(c.endpoints) == 0 {
+		return fmt.Errorf("no endpoint configured")
+	}
 	var err error
-	// Start http or ws client
-	if c.http {
-		rpcClient, err = rpc.DialHTTP(c.endpoint)
-	} else {
-		rpcClient, err = rpc.DialContext(context.Background(), c.endpoint)
+	// Start http or ws clients, the connection works as long as one endpoint can be dialed
+	connected := 0
+	for _, e := range c.endpoints {
+		c.log.Info("Connecting to ethereum chain...", "url", e.label)
+		if e.client, err = c.dial(ctx, e); err != nil {
+			c.log.Warn("Dial endpoint failed", "url", e.label, "err", err)
+			continue
+		}
+		connected++
 	}
-	if err != nil {
+	if connected == 0 {
 		return err
 	}
-	c.conn = ethclient.NewClient(rpcClient, c.endpoint)
-
-	// Construct tx opts, call opts, and nonce mechanism
-	opts, _, err := c.newTransactOpts(big.NewInt(0), c.gasLimit, c.maxGasPrice)
-	if err != nil {
-		return err
+	c.linkPeers()
+	// websocket transports are watched even with a single endpoint, so they are redialed when they drop
+	if len(c.endpoints) > 1 || !c.http {
+		c.checkHealth()
+		go c.monitorHealth()
 	}
-	c.opts = opts
-	c.nonce = 0
 	return nil
 }
 
-// newTransactOpts builds the TransactOpts for the connection's keypair.
-func (c *Connection) newTransactOpts(value, gasLimit, gasPrice *big.Int) (*bind.TransactOpts, uint64, error) {
-	if c.kp == nil {
-		return nil, 0, nil
-	}
-	privateKey := c.kp.PrivateKey
-	address := ethcrypto.PubkeyToAddress(privateKey.PublicKey)
-
-	nonce, err := c.conn.PendingNonceAt(context.Background(), address)
-	if err != nil {
-		return nil, 0, err
-	}
-
-	id, err := c.conn.ChainID(context.Background())
-	if err != nil {
-		return nil, 0, err
-	}
-
-	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, id)
-	if err != nil {
-		return nil, 0, err
-	}
-
-	auth.Nonce = big.NewInt(int64(nonce))
-	auth.Value = value
-	auth.GasLimit = uint64(gasLimit.Int64())
-	auth.GasPrice = gasPrice
-	auth.Context = context.Background()
-
-	return auth, nonce, nil
-}
-
 func (c *Connection) Keypair() *keystore.Key {
 	return c.kp
 }
 
+// Client returns the client of the healthiest endpoint
 func (c *Connection) Client() *ethclient.Client {
-	return c.conn
+	c.endpointsLock.RLock()
+	defer c.endpointsLock.RUnlock()
+	return c.endpoints[c.active].client
 }
 
-func (c *Connection) Opts() *bind.TransactOpts {
-	return c.opts
+// QuorumEndpoints returns every endpoint of the connection, for reads which must be confirmed by several providers
+func (c *Connection) QuorumEndpoints() []quorum.Endpoint {
+	c.endpointsLock.RLock()
+	defer c.endpointsLock.RUnlock()
+	ret := make([]quorum.Endpoint, 0, len(c.endpoints))
+	for _, e := range c.endpoints {
+		ret = append(ret, quorum.Endpoint{Name: e.label, Client: e.client})
+	}
+	return ret
 }
 
-func (c *Connection) CallOpts() *bind.CallOpts {
-	return c.callOpts
+// CallOpts returns the options of a contract call from the keypair, canceled with ctx
+func (c *Connection) CallOpts(ctx context.Context) *bind.CallOpts {
+	opts := &bind.CallOpts{Context: ctx}
+	if c.kp != nil {
+		opts.From = c.kp.Address
+	}
+	return opts
 }
 
 func (c *Connection) SafeEstimateGas(ctx context.Context) (*big.Int, error) {
 	var suggestedGasPrice *big.Int
 	c.log.Debug("Fetching gasPrice from node")
-	nodePriceEstimate, err := c.conn.SuggestGasPrice(context.TODO())
+	nodePriceEstimate, err := c.Client().SuggestGasPrice(ctx)
 	if err != nil {
 		return nil, err
 	} else {
@@ -143,7 +133,7 @@ func (c *Connection) SafeEstimateGas(ctx context.Context) (*big.Int, error) {
 
 	// Check we aren't exceeding our limit
 	if gasPrice.Cmp(c.maxGasPrice) == 1 {
-		return c.maxGasPrice, nil
+		return new(big.Int).Set(c.maxGasPrice), nil
 	} else {
 		return gasPrice, nil
 	}
@@ -157,7 +147,7 @@ func (c *Connection) EstimateGasLondon(ctx context.Context, baseFee *big.Int) (*
 		return maxPriorityFeePerGas, maxFeePerGas, nil
 	}
 
-	maxPriorityFeePerGas, err := c.conn.SuggestGasTipCap(context.TODO())
+	maxPriorityFeePerGas, err := c.Client().SuggestGasTipCap(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -172,7 +162,7 @@ func (c *Connection) EstimateGasLondon(ctx context.Context, baseFee *big.Int) (*
 	if maxFeePerGas.Cmp(c.maxGasPrice) == 1 {
 		c.log.Info("EstimateGasLondon maxFeePerGas more than set", "maxFeePerGas", maxFeePerGas, "baseFee", baseFee)
 		maxPriorityFeePerGas.Sub(c.maxGasPrice, baseFee)
-		maxFeePerGas = c.maxGasPrice
+		maxFeePerGas = new(big.Int).Set(c.maxGasPrice)
 	}
 	return maxPriorityFeePerGas, maxFeePerGas, nil
 }
@@ -185,76 +175,65 @@ func multiplyGasPrice(gasEstimate *big.Int, gasMultiplier *big.Float) *big.Int {
 	return gasPrice
 }
 
-// LockAndUpdateOpts acquires a lock on the opts before updating the nonce
-// and gas price.
-func (c *Connection) LockAndUpdateOpts(needNewNonce bool) error {
-	//c.optsLock.Lock()
-	head, err := c.conn.HeaderByNumber(context.TODO(), nil)
+// PrepareTx returns the gas settings of a new tx from the current fees of the chain
+func (c *Connection) PrepareTx(ctx context.Context) (*core.TxOpts, error) {
+	opts := &core.TxOpts{GasLimit: c.gasLimit.Uint64()}
+	if c.kp != nil {
+		opts.From = c.kp.Address
+	}
+	head, err := c.Client().HeaderByNumber(ctx, nil)
 	// cos map chain dont have this section in return,this err will be raised
 	if err != nil && err.Error() != "missing required field 'sha3Uncles' for Header" {
-		c.UnlockOpts()
-		c.log.Error("LockAndUpdateOpts HeaderByNumber", "err", err)
-		return err
+		c.log.Error("PrepareTx HeaderByNumber", "err", err)
+		return nil, err
 	}
 
-	if head.BaseFee != nil {
-		c.opts.GasTipCap, c.opts.GasFeeCap, err = c.EstimateGasLondon(context.TODO(), head.BaseFee)
+	if head != nil && head.BaseFee != nil {
+		opts.GasTipCap, opts.GasFeeCap, err = c.EstimateGasLondon(ctx, head.BaseFee)
 		// Both gasPrice and (maxFeePerGas or maxPriorityFeePerGas) cannot be specified: https://github.com/ethereum/go-ethereum/blob/95bbd46eabc5d95d9fb2108ec232dd62df2f44ab/accounts/abi/bind/base.go#L254
-		c.opts.GasPrice = nil
 		if err != nil {
 			// if EstimateGasLondon failed, fall back to suggestGasPrice
-			c.opts.GasPrice, err = c.conn.SuggestGasPrice(context.TODO())
+			opts.GasTipCap, opts.GasFeeCap = nil, nil
+			opts.GasPrice, err = c.Client().SuggestGasPrice(ctx)
 			if err != nil {
-				//c.UnlockOpts()
-				return err
+				return nil, err
 			}
 		}
-		c.log.Info("LockAndUpdateOpts ", "head.BaseFee", head.BaseFee, "maxGasPrice", c.maxGasPrice,
-			"gasTipCap", c.opts.GasTipCap, "gasFeeCap", c.opts.GasFeeCap)
+		c.log.Info("PrepareTx ", "head.BaseFee", head.BaseFee, "maxGasPrice", c.maxGasPrice,
+			"gasTipCap", opts.GasTipCap, "gasFeeCap", opts.GasFeeCap)
 	} else {
-		var gasPrice *big.Int
-		gasPrice, err = c.SafeEstimateGas(context.TODO())
+		opts.GasPrice, err = c.SafeEstimateGas(ctx)
 		if err != nil {
-			//c.UnlockOpts()
-			return err
+			return nil, err
 		}
-		c.opts.GasPrice = gasPrice
-	}
-
-	if !needNewNonce {
-		return nil
 	}
-	nonce, err := c.conn.PendingNonceAt(context.Background(), c.opts.From)
-	if err != nil {
-		//c.optsLock.Unlock()
-		return err
-	}
-	c.opts.Nonce.SetUint64(nonce)
-	return nil
-}
-
-func (c *Connection) UnlockOpts() {
-	//c.optsLock.Unlock()
+	return opts, nil
 }
 
 // LatestBlock returns the latest block from the current chain
-func (c *Connection) LatestBlock() (*big.Int, error) {
+func (c *Connection) LatestBlock(ctx context.Context) (*big.Int, error) {
 	// 1s req
+	c.blockLock.Lock()
 	if time.Now().Unix()-c.reqTime < 1 {
+		defer c.blockLock.Unlock()
 		return big.NewInt(0).SetInt64(c.cacheBlockNumber), nil
 	}
-	bnum, err := c.conn.BlockNumber(context.Background())
+	c.blockLock.Unlock()
+
+	bnum, err := c.Client().BlockNumber(ctx)
 	if err != nil {
 		return nil, err
 	}
+	c.blockLock.Lock()
 	c.cacheBlockNumber = int64(bnum)
 	c.reqTime = time.Now().Unix()
+	c.blockLock.Unlock()
 	return big.NewInt(0).SetUint64(bnum), nil
 }
 
 // EnsureHasBytecode asserts if contract code exists at the specified address
-func (c *Connection) EnsureHasBytecode(addr ethcommon.Address) error {
-	//code, err := c.conn.CodeAt(context.Background(), addr, nil)
+func (c *Connection) EnsureHasBytecode(ctx context.Context, addr ethcommon.Address) error {
+	//code, err := c.Client().CodeAt(ctx, addr, nil)
 	//if err != nil {
 	//	return err
 	//}
@@ -267,13 +246,15 @@ func (c *Connection) EnsureHasBytecode(addr ethcommon.Address) error {
 
 // WaitForBlock will poll for the block number until the current block is equal or greater.
 // If delay is provided it will wait until currBlock - delay = targetBlock
-func (c *Connection) WaitForBlock(targetBlock *big.Int, delay *big.Int) error {
+func (c *Connection) WaitForBlock(ctx context.Context, targetBlock *big.Int, delay *big.Int) error {
 	for {
 		select {
 		case <-c.stop:
 			return errors.New("connection terminated")
+		case <-ctx.Done():
+			return ctx.Err()
 		default:
-			currBlock, err := c.LatestBlock()
+			currBlock, err := c.LatestBlock(ctx)
 			if err != nil {
 				return err
 			}
@@ -287,16 +268,145 @@ func (c *Connection) WaitForBlock(targetBlock *big.Int, delay *big.Int) error {
 				return nil
 			}
 			c.log.Trace("Block not ready, waiting", "target", targetBlock, "current", currBlock, "delay", delay)
-			time.Sleep(constant.BlockRetryInterval)
-			continue
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(constant.BlockRetryInterval):
+			}
 		}
 	}
 }
 
 // Close terminates the client connection and stops any running routines
 func (c *Connection) Close() {
-	if c.conn != nil {
-		c.conn.Close()
-	}
 	close(c.stop)
+	c.endpointsLock.RLock()
+	defer c.endpointsLock.RUnlock()
+	for _, e := range c.endpoints {
+		if e.client != nil {
+			e.client.Close()
+		}
+	}
+}
+
+// monitorHealth probes the endpoints periodically, routes reads to the healthiest one and redials dropped
+// websocket transports
+func (c *Connection) monitorHealth() {
+	timer := time.NewTimer(healthCheckInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-timer.C:
+			timer.Reset(c.checkHealth())
+		}
+	}
+}
+
+// checkHealth returns the delay before the next check, which is shorter while a dropped endpoint awaits a redial.
+// It is the only writer of the endpoints once connected, its writes are made under the endpoints lock and the
+// network calls outside of it.
+func (c *Connection) checkHealth() time.Duration {
+	next := healthCheckInterval
+	redialed := false
+	wasHealthy := make([]bool, len(c.endpoints))
+	for i, e := range c.endpoints {
+		wasHealthy[i] = e.healthy() || !e.probed
+		if e.client == nil {
+			if err := c.redial(e); err != nil {
+				c.log.Debug("Redial endpoint failed", "url", e.label, "err", err)
+			} else {
+				redialed = true
+			}
+		} else if e.dropped && !time.Now().Before(e.retryAt) && c.reconnect(e) {
+			redialed = true
+		}
+		r := probe(e.client)
+		c.endpointsLock.Lock()
+		e.record(r)
+		c.endpointsLock.Unlock()
+		if !c.http && e.client != nil && !e.dropped && core.TransportClosed(r.err) {
+			c.drop(e, r.err)
+		}
+		if e.dropped {
+			if wait := time.Until(e.retryAt); wait < next {
+				next = wait
+			}
+		}
+	}
+	if redialed {
+		c.linkPeers()
+	}
+
+	c.endpointsLock.Lock()
+	prev := c.active
+	c.active = pick(c.endpoints, c.active)
+	c.endpointsLock.Unlock()
+	for i, e := range c.endpoints {
+		if wasHealthy[i] != e.healthy() {
+			c.log.Info("Endpoint health changed", "url", e.label, "healthy", e.healthy(), "latency", e.latency,
+				"errRate", e.errRate, "head", e.head, "lag", e.lag)
+		}
+	}
+	if prev != c.active {
+		from, to := c.endpoints[prev], c.endpoints[c.active]
+		c.log.Warn("Switch rpc endpoint", "from", from.label, "fromScore", from.score(), "to", to.label, "toScore", to.score())
+		endpointFailover.WithLabelValues(c.connOpts.Name).Inc()
+	}
+
+	for i, e := range c.endpoints {
+		endpointLatency.WithLabelValues(c.connOpts.Name, e.label).Set(e.latency.Seconds())
+		endpointErrRate.WithLabelValues(c.connOpts.Name, e.label).Set(e.errRate)
+		endpointHeadLag.WithLabelValues(c.connOpts.Name, e.label).Set(float64(e.lag))
+		active := 0.0
+		if i == c.active {
+			active = 1
+		}
+		endpointActive.WithLabelValues(c.connOpts.Name, e.label).Set(active)
+	}
+	return next
+}
+
+// redial dials the endpoint without holding the endpoints lock, so reads aren't stalled by a slow endpoint, and
+// swaps the new client in under the lock
+func (c *Connection) redial(e *endpoint) error {
+	client, err := c.dial(context.Background(), e)
+	if err != nil {
+		return err
+	}
+	c.endpointsLock.Lock()
+	e.client = client
+	c.endpointsLock.Unlock()
+	return nil
+}
+
+func (c *Connection) dial(ctx context.Context, e *endpoint) (*ethclient.Client, error) {
+	client, err := e.dial(ctx, c.connOpts.Name)
+	if err != nil {
+		return nil, err
+	}
+	client.SetReceiptBatchSize(c.connOpts.ReceiptBatchSize)
+	return client, nil
+}
+
+// linkPeers makes every client broadcast signed txs to the other endpoints if BroadcastAll is set
+func (c *Connection) linkPeers() {
+	if !c.connOpts.BroadcastAll {
+		return
+	}
+	c.endpointsLock.RLock()
+	defer c.endpointsLock.RUnlock()
+	for _, e := range c.endpoints {
+		if e.client == nil {
+			continue
+		}
+		peers := make([]*ethclient.Client, 0, len(c.endpoints)-1)
+		for _, p := range c.endpoints {
+			if p != e && p.client != nil {
+				peers = append(peers, p.client)
+			}
+		}
+		e.client.SetPeers(peers)
+	}
 }
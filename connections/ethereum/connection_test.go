@@ -0,0 +1,64 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mapprotocol/compass/core"
+)
+
+func TestPrepareTxConcurrent(t *testing.T) {
+	node := newStubNode(100)
+	defer node.Close()
+	conn := newTestConnection(t, core.ConnOpts{Name: "test"}, node)
+	defer conn.Close()
+
+	want := big.NewInt(stubBaseFee + stubTipCap)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			opts, err := conn.PrepareTx(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if opts.GasPrice != nil || opts.GasFeeCap.Cmp(want) != 0 || opts.GasTipCap.Int64() != stubTipCap {
+				t.Errorf("Unexpected gas settings, gasPrice %v gasFeeCap %v gasTipCap %v", opts.GasPrice, opts.GasFeeCap, opts.GasTipCap)
+			}
+			// the options belong to the caller, changing them must not affect other sends
+			opts.GasFeeCap.Mul(opts.GasFeeCap, big.NewInt(2))
+			if _, err = conn.LatestBlock(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestContextCancel(t *testing.T) {
+	node := newStubNode(100)
+	defer node.Close()
+	conn := newTestConnection(t, core.ConnOpts{Name: "test"}, node)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := conn.PrepareTx(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected canceled PrepareTx, got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := conn.WaitForBlock(ctx, big.NewInt(200), nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected WaitForBlock to stop at the deadline, got %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mapprotocol/compass/pkg/ethclient"
+	"github.com/mapprotocol/compass/pkg/instrument"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	healthCheckInterval = 10 * time.Second
+	healthCheckTimeout  = 5 * time.Second
+	healthWeight        = 0.5  // weight of the latest probe in the moving averages
+	maxErrRate          = 0.5  // endpoints failing more calls than this are unhealthy
+	maxHeadLag          = 5    // endpoints lagging more blocks than this behind the best head are unhealthy
+	errRatePenalty      = 10e3 // score penalty of an endpoint failing every call, in milliseconds
+	headLagPenalty      = 1e3  // score penalty per block of head lag, in milliseconds
+	switchMargin        = 200  // a healthy active endpoint is only replaced by one scoring this much better
+)
+
+var (
+	endpointLatency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "compass",
+		Name:      "rpc_endpoint_latency_seconds",
+		Help:      "Moving average of the rpc endpoint probe latency",
+	}, []string{"chain", "endpoint"})
+	endpointErrRate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "compass",
+		Name:      "rpc_endpoint_error_rate",
+		Help:      "Moving average of failed rpc endpoint calls, between 0 and 1",
+	}, []string{"chain", "endpoint"})
+	endpointHeadLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "compass",
+		Name:      "rpc_endpoint_head_lag",
+		Help:      "Number of blocks the rpc endpoint is behind the best endpoint of the chain",
+	}, []string{"chain", "endpoint"})
+	endpointActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "compass",
+		Name:      "rpc_endpoint_active",
+		Help:      "1 if reads of the chain are routed to the rpc endpoint",
+	}, []string{"chain", "endpoint"})
+	endpointFailover = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "compass",
+		Name:      "rpc_endpoint_failover_total",
+		Help:      "Number of times reads of the chain switched to another rpc endpoint",
+	}, []string{"chain"})
+)
+
+func init() {
+	prometheus.MustRegister(endpointLatency, endpointErrRate, endpointHeadLag, endpointActive, endpointFailover)
+}
+
+// endpoint is one rpc url of a connection and its health
+type endpoint struct {
+	url     string
+	label   string // url without path and credentials, used in logs and metrics
+	client  *ethclient.Client
+	probed  bool
+	latency time.Duration // moving average of the probe latency
+	errRate float64       // moving average of the failed calls of each probe interval, between 0 and 1
+	head    uint64
+	lag     uint64
+	dropped bool      // the websocket transport was closed, the client is redialed with backoff
+	retries int       // failed redials since the transport was closed
+	retryAt time.Time // time of the next redial
+}
+
+// splitEndpoints parses a comma-separated list of urls
+func splitEndpoints(urls string) []*endpoint {
+	ret := make([]*endpoint, 0)
+	for _, u := range strings.Split(urls, ",") {
+		u = strings.TrimSpace(u)
+		if u == "" {
+			continue
+		}
+		ret = append(ret, &endpoint{url: u, label: instrument.Endpoint(u)})
+	}
+	return ret
+}
+
+// dial connects a new client to the endpoint, rate limited by the limit of chain
+func (e *endpoint) dial(ctx context.Context, chain string) (*ethclient.Client, error) {
+	return ethclient.DialClient(ctx, chain, e.url)
+}
+
+// probeResult is the outcome of a health probe of an endpoint
+type probeResult struct {
+	head    uint64
+	latency time.Duration
+	calls   int64 // calls made through the client since the previous probe, the probe included
+	failed  int64 // calls of them the endpoint failed to answer
+	err     error // error of the probe call
+}
+
+// probe measures the latency and head of the endpoint through client, and collects the outcome of the calls made
+// through it since the previous probe. It makes network calls, so it is done without holding the endpoints lock
+// and the result is folded into the endpoint by record.
+func probe(client *ethclient.Client) probeResult {
+	if client == nil {
+		return probeResult{calls: 1, failed: 1}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	start := time.Now()
+	head, err := client.BlockNumber(ctx)
+	cancel()
+	ret := probeResult{head: head, latency: time.Since(start), err: err}
+	ret.calls, ret.failed = client.CallStats()
+	if err != nil && ret.failed == 0 {
+		ret.failed = 1
+	}
+	if ret.calls < ret.failed {
+		ret.calls = ret.failed
+	}
+	return ret
+}
+
+// record folds the probe result into the moving averages, the error rate covers every call made through the
+// client, not only the probes
+func (e *endpoint) record(r probeResult) {
+	failed := 1.0
+	if r.calls > 0 {
+		failed = float64(r.failed) / float64(r.calls)
+	}
+	if r.err == nil {
+		e.head = r.head
+		e.latency = ewmaDuration(e.latency, r.latency, e.probed)
+	}
+	if !e.probed {
+		e.errRate = failed
+	} else {
+		e.errRate = healthWeight*failed + (1-healthWeight)*e.errRate
+	}
+	e.probed = true
+}
+
+func ewmaDuration(avg, sample time.Duration, init bool) time.Duration {
+	if !init || avg == 0 {
+		return sample
+	}
+	return time.Duration(healthWeight*float64(sample) + (1-healthWeight)*float64(avg))
+}
+
+func (e *endpoint) healthy() bool {
+	return e.client != nil && !e.dropped && e.errRate <= maxErrRate && e.lag <= maxHeadLag
+}
+
+// score is lower for healthier endpoints, in milliseconds of latency
+func (e *endpoint) score() float64 {
+	if e.client == nil || e.dropped {
+		return math.Inf(1)
+	}
+	return float64(e.latency)/float64(time.Millisecond) + e.errRate*errRatePenalty + float64(e.lag)*headLagPenalty
+}
+
+// pick returns the index of the endpoint reads should be routed to. The active endpoint is kept while
+// it is healthy, unless another one scores better by switchMargin, so reads don't flap between endpoints.
+func pick(endpoints []*endpoint, active int) int {
+	var best uint64
+	for _, e := range endpoints {
+		if e.head > best {
+			best = e.head
+		}
+	}
+	for _, e := range endpoints {
+		e.lag = 0
+		if e.head < best {
+			e.lag = best - e.head
+		}
+	}
+
+	next := active
+	for i, e := range endpoints {
+		if e.score() < endpoints[next].score() {
+			next = i
+		}
+	}
+	cur := endpoints[active]
+	if next != active && cur.healthy() && endpoints[next].score()+switchMargin > cur.score() {
+		return active
+	}
+	return next
+}
@@ -0,0 +1,225 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/log15"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mapprotocol/compass/core"
+)
+
+const (
+	stubBaseFee = 7000000000
+	stubTipCap  = 1000000000
+)
+
+// stubNode is a json-rpc server answering eth_blockNumber, eth_getBlockByNumber, eth_maxPriorityFeePerGas and
+// eth_sendRawTransaction
+type stubNode struct {
+	*httptest.Server
+	head   uint64
+	down   int32
+	flaky  int32 // answers eth_blockNumber but fails every other call
+	delay  time.Duration
+	raw    int32 // number of eth_sendRawTransaction calls
+	reject bool  // reject sent txs
+}
+
+func newStubNode(head uint64) *stubNode {
+	n := &stubNode{head: head}
+	n.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Id     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if atomic.LoadInt32(&n.down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if atomic.LoadInt32(&n.flaky) == 1 && req.Method != "eth_blockNumber" {
+			fmt.Fprint(w, "<html>bad gateway</html>")
+			return
+		}
+		time.Sleep(n.delay)
+		w.Header().Set("Content-Type", "application/json")
+		switch req.Method {
+		case "eth_blockNumber":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x%x"}`, req.Id, atomic.LoadUint64(&n.head))
+		case "eth_getBlockByNumber":
+			header, _ := json.Marshal(&types.Header{Number: new(big.Int).SetUint64(atomic.LoadUint64(&n.head)),
+				Difficulty: big.NewInt(0), BaseFee: big.NewInt(stubBaseFee)})
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.Id, header)
+		case "eth_maxPriorityFeePerGas":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x%x"}`, req.Id, stubTipCap)
+		case "eth_sendRawTransaction":
+			atomic.AddInt32(&n.raw, 1)
+			if n.reject {
+				fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"error":{"code":-32000,"message":"nonce too low"}}`, req.Id)
+				return
+			}
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x%s"}`, req.Id, common.Hash{}.Hex()[2:])
+		default:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"error":{"code":-32601,"message":"method not found"}}`, req.Id)
+		}
+	}))
+	return n
+}
+
+func newTestConnection(t *testing.T, opts core.ConnOpts, nodes ...*stubNode) *Connection {
+	endpoints := ""
+	for i, n := range nodes {
+		if i > 0 {
+			endpoints += ","
+		}
+		endpoints += n.URL
+	}
+	conn := NewConnection(endpoints, true, nil, log15.Root(), big.NewInt(6721975), big.NewInt(20000000000), 1,
+		opts).(*Connection)
+	if err := conn.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestFailoverAndFailback(t *testing.T) {
+	slow, fast := newStubNode(100), newStubNode(100)
+	defer slow.Close()
+	defer fast.Close()
+	slow.delay = 300 * time.Millisecond
+
+	conn := newTestConnection(t, core.ConnOpts{Name: "test"}, slow, fast)
+	defer conn.Close()
+	if conn.Client() != conn.endpoints[1].client {
+		t.Fatal("Expected reads to be routed to the faster endpoint")
+	}
+
+	// The active endpoint fails, reads move to the other one
+	atomic.StoreInt32(&fast.down, 1)
+	conn.checkHealth()
+	if conn.Client() != conn.endpoints[0].client {
+		t.Fatal("Expected failover to the healthy endpoint")
+	}
+	if _, err := conn.Client().BlockNumber(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once it recovers reads move back as it is faster
+	atomic.StoreInt32(&fast.down, 0)
+	for i := 0; i < 10; i++ {
+		conn.checkHealth()
+	}
+	if conn.Client() != conn.endpoints[1].client {
+		t.Fatal("Expected failback to the faster endpoint")
+	}
+}
+
+func TestCallErrorsCount(t *testing.T) {
+	flaky, other := newStubNode(100), newStubNode(100)
+	defer flaky.Close()
+	defer other.Close()
+	other.delay = 300 * time.Millisecond
+
+	conn := newTestConnection(t, core.ConnOpts{Name: "test"}, flaky, other)
+	defer conn.Close()
+	if conn.Client() != conn.endpoints[0].client {
+		t.Fatal("Expected reads to be routed to the faster endpoint")
+	}
+
+	// The probes still succeed, the calls failing in between make the endpoint unhealthy
+	atomic.StoreInt32(&flaky.flaky, 1)
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 5; i++ {
+			if _, err := conn.endpoints[0].client.HeaderByNumber(context.Background(), nil); err == nil {
+				t.Fatal("Expected the call to fail")
+			}
+		}
+		conn.checkHealth()
+	}
+	if conn.endpoints[0].healthy() {
+		t.Fatal("Expected the endpoint failing calls to be unhealthy")
+	}
+	if conn.Client() != conn.endpoints[1].client {
+		t.Fatal("Expected failover to the endpoint answering calls")
+	}
+}
+
+func TestHeadLag(t *testing.T) {
+	behind, ahead := newStubNode(100), newStubNode(100+maxHeadLag+1)
+	defer behind.Close()
+	defer ahead.Close()
+
+	conn := newTestConnection(t, core.ConnOpts{Name: "test"}, behind, ahead)
+	defer conn.Close()
+	if conn.Client() != conn.endpoints[1].client {
+		t.Fatal("Expected reads to be routed to the endpoint with the best head")
+	}
+	if conn.endpoints[0].healthy() {
+		t.Fatal("Expected lagging endpoint to be unhealthy")
+	}
+}
+
+func TestBroadcastAll(t *testing.T) {
+	a, b, c := newStubNode(100), newStubNode(100), newStubNode(100)
+	defer a.Close()
+	defer b.Close()
+	defer c.Close()
+
+	key, _ := crypto.GenerateKey()
+	tx, err := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil),
+		types.HomesteadSigner{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	one := newTestConnection(t, core.ConnOpts{Name: "test"}, a, b)
+	defer one.Close()
+	if err = one.Client().SendTransaction(context.Background(), tx); err != nil {
+		t.Fatal(err)
+	}
+	if sent := atomic.LoadInt32(&a.raw) + atomic.LoadInt32(&b.raw); sent != 1 {
+		t.Fatalf("Expected tx sent to one endpoint, got %d", sent)
+	}
+
+	// The tx is propagated to every endpoint once the active one takes it, whatever the peers answer
+	c.reject = true
+	all := newTestConnection(t, core.ConnOpts{Name: "test", BroadcastAll: true}, c, a, b)
+	defer all.Close()
+	all.endpointsLock.Lock()
+	all.active = 1
+	all.endpointsLock.Unlock()
+	if err = all.Client().SendTransaction(context.Background(), tx); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&c.raw) != 1 || atomic.LoadInt32(&a.raw)+atomic.LoadInt32(&b.raw) != 3 {
+		t.Fatal("Expected tx broadcast to every endpoint")
+	}
+
+	// The error of the active endpoint is returned even if a peer would take the tx, so the writer handles it
+	all.endpointsLock.Lock()
+	all.active = 0
+	all.endpointsLock.Unlock()
+	if err = all.Client().SendTransaction(context.Background(), tx); err == nil || !strings.Contains(err.Error(), "nonce too low") {
+		t.Fatalf("Expected the nonce too low of the active endpoint, got %v", err)
+	}
+	if atomic.LoadInt32(&c.raw) != 2 || atomic.LoadInt32(&a.raw)+atomic.LoadInt32(&b.raw) != 3 {
+		t.Fatal("Expected the rejected tx not to be propagated")
+	}
+}
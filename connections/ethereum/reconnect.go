@@ -0,0 +1,76 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"time"
+
+	eth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/mapprotocol/compass/core"
+)
+
+// SubscribeState subscribes to the transport state changes of the endpoints. The connection waits for every
+// subscriber to receive an event, so ch should be buffered and drained.
+func (c *Connection) SubscribeState(ch chan<- core.ConnEvent) event.Subscription {
+	return c.stateFeed.Subscribe(ch)
+}
+
+// SubscribeFilterLogs subscribes to the logs matching q on the active endpoint, the subscription is made again
+// when the endpoint drops or reads move to another endpoint. Only websocket endpoints support subscriptions.
+func (c *Connection) SubscribeFilterLogs(q eth.FilterQuery, ch chan<- types.Log) event.Subscription {
+	return core.Resubscribe(c.log, "logs", func(ctx context.Context) (eth.Subscription, error) {
+		return c.Client().SubscribeFilterLogs(ctx, q, ch)
+	})
+}
+
+// SubscribeNewHead subscribes to the new heads of the active endpoint, made again like SubscribeFilterLogs
+func (c *Connection) SubscribeNewHead(ch chan<- *types.Header) event.Subscription {
+	return core.Resubscribe(c.log, "newHeads", func(ctx context.Context) (eth.Subscription, error) {
+		return c.Client().SubscribeNewHead(ctx, ch)
+	})
+}
+
+// drop marks the transport of e closed, it is redialed by the next health checks
+func (c *Connection) drop(e *endpoint, err error) {
+	c.endpointsLock.Lock()
+	e.dropped, e.retries, e.retryAt = true, 0, time.Now()
+	c.endpointsLock.Unlock()
+	c.log.Warn("Endpoint transport closed, reconnecting", "url", e.label, "err", err)
+	c.stateFeed.Send(core.ConnEvent{Endpoint: e.label, State: core.ConnDisconnected, Err: err, Connected: c.connected()})
+}
+
+// reconnect redials the dropped endpoint e. On success the old client is closed, which ends its subscriptions
+// so they are made again. On failure the next redial is delayed with backoff.
+func (c *Connection) reconnect(e *endpoint) bool {
+	old := e.client
+	if err := c.redial(e); err != nil {
+		c.endpointsLock.Lock()
+		e.retries++
+		e.retryAt = time.Now().Add(core.ReconnectBackoff(e.retries))
+		c.endpointsLock.Unlock()
+		c.log.Debug("Redial endpoint failed", "url", e.label, "retries", e.retries, "next", e.retryAt, "err", err)
+		return false
+	}
+	old.Close()
+	c.endpointsLock.Lock()
+	e.dropped, e.retries = false, 0
+	c.endpointsLock.Unlock()
+	c.log.Info("Endpoint reconnected", "url", e.label)
+	c.stateFeed.Send(core.ConnEvent{Endpoint: e.label, State: core.ConnReconnected, Connected: c.connected()})
+	return true
+}
+
+// connected returns the number of endpoints whose transport is up
+func (c *Connection) connected() int {
+	n := 0
+	for _, e := range c.endpoints {
+		if e.client != nil && !e.dropped {
+			n++
+		}
+	}
+	return n
+}
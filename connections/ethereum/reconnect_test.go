@@ -0,0 +1,105 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package ethereum
+
+import (
+	"context"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/log15"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/mapprotocol/compass/core"
+)
+
+// wsService answers eth_blockNumber and streams a head every 20ms to newHeads subscribers
+type wsService struct{}
+
+func (s *wsService) BlockNumber() hexutil.Uint64 {
+	return 100
+}
+
+func (s *wsService) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, ok := rpc.NotifierFromContext(ctx)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sub.Err():
+				return
+			case <-ticker.C:
+				_ = notifier.Notify(sub.ID, &types.Header{Number: big.NewInt(100), Difficulty: big.NewInt(0)})
+			}
+		}
+	}()
+	return sub, nil
+}
+
+func newWSNode(t *testing.T) *httptest.Server {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("eth", new(wsService)); err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(srv.WebsocketHandler([]string{"*"}))
+}
+
+func waitHead(t *testing.T, heads chan *types.Header) {
+	select {
+	case <-heads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a head from the subscription")
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	node := newWSNode(t)
+	defer node.Close()
+	conn := NewConnection("ws://"+node.Listener.Addr().String(), false, nil, log15.Root(), big.NewInt(6721975),
+		big.NewInt(20000000000), 1, core.ConnOpts{Name: "test"}).(*Connection)
+	if err := conn.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	states := make(chan core.ConnEvent, 4)
+	stateSub := conn.SubscribeState(states)
+	defer stateSub.Unsubscribe()
+	heads := make(chan *types.Header, 64)
+	headSub := conn.SubscribeNewHead(heads)
+	defer headSub.Unsubscribe()
+	waitHead(t, heads)
+
+	// The transport drops, the next health checks notice and redial it
+	old := conn.Client()
+	old.Close()
+	conn.checkHealth()
+	if ev := <-states; ev.State != core.ConnDisconnected || ev.Connected != 0 {
+		t.Fatalf("Expected disconnected event got %+v", ev)
+	}
+	conn.checkHealth()
+	if ev := <-states; ev.State != core.ConnReconnected || ev.Connected != 1 {
+		t.Fatalf("Expected reconnected event got %+v", ev)
+	}
+	if conn.Client() == old {
+		t.Fatal("Expected a new client")
+	}
+	if _, err := conn.Client().BlockNumber(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The subscription is made again on the new client
+	for len(heads) > 0 {
+		<-heads
+	}
+	waitHead(t, heads)
+}
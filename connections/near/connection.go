@@ -4,11 +4,16 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"net/http"
+	"reflect"
 	"sync"
 	"time"
+	"unsafe"
 
 	"github.com/ChainSafe/log15"
 	"github.com/mapprotocol/atlas/accounts/abi/bind"
+	"github.com/mapprotocol/compass/pkg/ethclient"
+	"github.com/mapprotocol/compass/pkg/instrument"
 	nearclient "github.com/mapprotocol/near-api-go/pkg/client"
 	"github.com/mapprotocol/near-api-go/pkg/client/block"
 	"github.com/mapprotocol/near-api-go/pkg/types/key"
@@ -16,6 +21,22 @@ import (
 
 var BlockRetryInterval = time.Second * 5
 
+// newHTTPClient returns the http client of the near rpc requests, it sends the credentials of the endpoint and
+// records the request metrics
+func newHTTPClient() *http.Client {
+	return &http.Client{Transport: instrument.Transport(ethclient.AuthTransport(http.DefaultTransport))}
+}
+
+// setHTTPClient replaces the http client of the near rpc client, near-api-go creates its own and does not export it
+func setHTTPClient(client *nearclient.Client, hc *http.Client) error {
+	f := reflect.ValueOf(&client.RPCClient).Elem().FieldByName("client")
+	if !f.IsValid() || f.Type() != reflect.TypeOf(hc) {
+		return errors.New("near rpc client has no http client to replace")
+	}
+	reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem().Set(reflect.ValueOf(hc))
+	return nil
+}
+
 type Connection struct {
 	endpoint      string
 	http          bool
@@ -54,10 +75,14 @@ func NewConnection(endpoint string, http bool, kp *key.KeyPair, log log15.Logger
 // Connect starts the ethereum WS connection
 func (c *Connection) Connect() error {
 	c.log.Info("Connecting to near chain...", "url", c.endpoint)
+	instrument.Watch("near", c.endpoint)
 	client, err := nearclient.NewClient(c.endpoint)
 	if err != nil {
 		return err
 	}
+	if err = setHTTPClient(&client, newHTTPClient()); err != nil {
+		return err
+	}
 
 	resp, err := client.NetworkStatusValidators(context.Background())
 	if err != nil {
@@ -0,0 +1,41 @@
+package near
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mapprotocol/compass/pkg/ethclient"
+	nearclient "github.com/mapprotocol/near-api-go/pkg/client"
+)
+
+func TestSetHTTPClient(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-Api-Key")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":"1","result":{}}`)
+	}))
+	defer srv.Close()
+	ethclient.SetAuth(srv.URL, &ethclient.Auth{Header: http.Header{"X-Api-Key": []string{"near-key"}}})
+	defer ethclient.SetAuth(srv.URL, nil)
+
+	client, err := nearclient.NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = setHTTPClient(&client, newHTTPClient()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.RPCClient.CallRPC(context.Background(), "status", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got != "near-key" {
+		t.Errorf("Expected the endpoint credentials to be sent, got %q", got)
+	}
+	if _, ok := http.DefaultTransport.(*http.Transport); !ok {
+		t.Error("Expected http.DefaultTransport to be left alone")
+	}
+}
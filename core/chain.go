@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"math/big"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/mapprotocol/compass/internal/klaytn"
 	"github.com/mapprotocol/compass/msg"
 	"github.com/mapprotocol/compass/pkg/ethclient"
+	"github.com/mapprotocol/compass/pkg/quorum"
 )
 
 type Chain interface {
@@ -36,22 +38,39 @@ type ChainConfig struct {
 	LatestBlock      bool              // If true, overrides blockstore or latest block in config and starts from current block
 	Opts             map[string]string // Per chain options
 	SkipError        bool              // Flag of Skip Error
+	DryRun           bool              // If true, writers simulate transactions instead of broadcasting them
 }
 
+// Connection is the connection of a chain. Calls to the node take a context which cancels them, and tx options
+// are prepared for each send, so a connection may be used by several goroutines.
 type Connection interface {
-	Connect() error
+	Connect(ctx context.Context) error
 	Keypair() *keystore.Key
-	Opts() *bind.TransactOpts
-	CallOpts() *bind.CallOpts
-	LockAndUpdateOpts(bool) error
-	UnlockOpts()
+	// PrepareTx returns the gas settings of a new tx, the nonce is taken by the writer from its key pool
+	PrepareTx(ctx context.Context) (*TxOpts, error)
+	CallOpts(ctx context.Context) *bind.CallOpts
 	Client() *ethclient.Client
-	EnsureHasBytecode(address common.Address) error
-	LatestBlock() (*big.Int, error)
-	WaitForBlock(block *big.Int, delay *big.Int) error
+	EnsureHasBytecode(ctx context.Context, address common.Address) error
+	LatestBlock(ctx context.Context) (*big.Int, error)
+	WaitForBlock(ctx context.Context, block *big.Int, delay *big.Int) error
 	Close()
 }
 
+// TxOpts are the settings of a single tx. They are made anew by PrepareTx for every send and never shared, so
+// callers may change them.
+type TxOpts struct {
+	From      common.Address
+	GasLimit  uint64
+	GasPrice  *big.Int // gas price of legacy txs, nil if the chain supports dynamic fee txs
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+}
+
+// QuorumConnection is implemented by connections with several independent endpoints
+type QuorumConnection interface {
+	QuorumEndpoints() []quorum.Endpoint
+}
+
 type KConnection interface {
 	Connection
 	KClient() *klaytn.Client
@@ -62,4 +81,11 @@ type Eth2Connection interface {
 	Eth2Client() *eth2.Client
 }
 
-type CreateConn func(string, bool, *keystore.Key, log15.Logger, *big.Int, *big.Int, float64) Connection
+// ConnOpts are the optional settings of a connection
+type ConnOpts struct {
+	Name             string // Human-readable chain name, used to label the endpoint metrics
+	BroadcastAll     bool   // Broadcast signed txs to every endpoint instead of only the active one
+	ReceiptBatchSize int    // Receipts requested in one batch call, 0 uses the default
+}
+
+type CreateConn func(string, bool, *keystore.Key, log15.Logger, *big.Int, *big.Int, float64, ConnOpts) Connection
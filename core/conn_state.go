@@ -0,0 +1,104 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ChainSafe/log15"
+	eth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	ReconnectBackoffMin = time.Second
+	ReconnectBackoffMax = time.Minute
+	ResubscribeBackoff  = 10 * time.Second // maximum wait before a dropped subscription is made again
+)
+
+// ConnState is the state of the transport of an endpoint
+type ConnState int
+
+const (
+	ConnDisconnected ConnState = iota // the transport was closed, calls fail until it is redialed
+	ConnReconnected                   // the endpoint was redialed, subscriptions are made again
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case ConnDisconnected:
+		return "disconnected"
+	case ConnReconnected:
+		return "reconnected"
+	}
+	return "unknown"
+}
+
+// ConnEvent reports a state change of an endpoint of a connection
+type ConnEvent struct {
+	Endpoint  string // endpoint host, without path and credentials
+	State     ConnState
+	Err       error // the error which closed the transport
+	Connected int   // endpoints of the connection still connected after the change
+}
+
+// ReconnectingConnection is implemented by connections which redial dropped websocket endpoints. Subscriptions
+// made through it are made again once the endpoint is back, they end only when unsubscribed.
+type ReconnectingConnection interface {
+	SubscribeState(ch chan<- ConnEvent) event.Subscription
+	SubscribeFilterLogs(q eth.FilterQuery, ch chan<- types.Log) event.Subscription
+	SubscribeNewHead(ch chan<- *types.Header) event.Subscription
+}
+
+var errSubscriptionEnded = errors.New("subscription ended")
+
+// Resubscribe keeps a subscription made by subscribe alive until it is unsubscribed: it is made again with backoff
+// whenever it fails or ends, including when the client it was made on is closed after a reconnection.
+func Resubscribe(log log15.Logger, name string, subscribe func(ctx context.Context) (eth.Subscription, error)) event.Subscription {
+	return event.ResubscribeErr(ResubscribeBackoff, func(ctx context.Context, err error) (event.Subscription, error) {
+		if err != nil {
+			log.Warn("Subscription dropped, resubscribing", "subscription", name, "err", err)
+		}
+		sub, err := subscribe(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// a subscription of a closed client ends without error, which would stop ResubscribeErr
+		return event.NewSubscription(func(quit <-chan struct{}) error {
+			defer sub.Unsubscribe()
+			select {
+			case err := <-sub.Err():
+				if err == nil {
+					err = errSubscriptionEnded
+				}
+				return err
+			case <-quit:
+				return nil
+			}
+		}), nil
+	})
+}
+
+// ReconnectBackoff returns the delay before the attempt-th redial of a dropped endpoint
+func ReconnectBackoff(attempt int) time.Duration {
+	if attempt > 6 {
+		return ReconnectBackoffMax
+	}
+	d := ReconnectBackoffMin << uint(attempt)
+	if d > ReconnectBackoffMax {
+		return ReconnectBackoffMax
+	}
+	return d
+}
+
+// TransportClosed reports whether err is the transport to the endpoint failing, rather than the node answering
+// with an error
+func TransportClosed(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var rpcErr rpc.Error
+	return !errors.As(err, &rpcErr)
+}
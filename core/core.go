@@ -14,6 +14,7 @@ import (
 	utilcore "github.com/ChainSafe/chainbridge-utils/core"
 	"github.com/ChainSafe/log15"
 	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/health"
 )
 
 type Core struct {
@@ -50,6 +51,7 @@ func (c *Core) Start() {
 		}
 		c.log.Info(fmt.Sprintf("Started %s chain", chain.Name()))
 	}
+	health.SetReady()
 
 	sigc := make(chan os.Signal, 1)
 	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
@@ -9,6 +9,8 @@ import (
 
 	log "github.com/ChainSafe/log15"
 	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/health"
+	"github.com/mapprotocol/compass/pkg/metrics"
 )
 
 // Writer consumes a message and makes the requried on-chain interactions.
@@ -46,7 +48,12 @@ func (r *Router) Send(msg msg.Message) error {
 		return fmt.Errorf("unknown destination chainId: %d", msg.Destination)
 	}
 
-	go w.ResolveMessage(msg)
+	metrics.Routed(msg)
+	go func() {
+		done := health.Handling(dest)
+		defer done()
+		metrics.Handled(msg, w.ResolveMessage(msg))
+	}()
 	return nil
 }
 
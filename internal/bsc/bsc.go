@@ -1,6 +1,7 @@
 package bsc
 
 import (
+	"fmt"
 	"github.com/mapprotocol/compass/internal/mapo"
 	"math/big"
 
@@ -93,6 +94,9 @@ func AssembleProof(header []Header, log *types.Log, receipts []*types.Receipt, m
 	if err != nil {
 		return nil, err
 	}
+	if err = iproof.Verify(receipts, types.Receipts(receipts), common.BytesToHash(header[0].ReceiptsRoot), txIndex, proof); err != nil {
+		return nil, fmt.Errorf("verify receipt proof of block %d: %w", log.BlockNumber, err)
+	}
 
 	var key []byte
 	key = rlp.AppendUint64(key[:0], uint64(txIndex))
@@ -0,0 +1,42 @@
+package chain
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/audit"
+)
+
+// auditTx records the signed tx of the message in the audit log, a tx which can not be recorded is not sent
+func (w *Writer) auditTx(m msg.Message, from common.Address, signedTx *types.Transaction) error {
+	e := audit.Entry{
+		ChainId:  w.cfg.Id,
+		Tx:       signedTx.Hash().Hex(),
+		From:     from.Hex(),
+		Nonce:    signedTx.Nonce(),
+		DataHash: audit.DataHash(signedTx.Data()),
+		Gas:      signedTx.Gas(),
+		DryRun:   w.cfg.DryRun,
+	}
+	if signedTx.To() != nil {
+		e.To = signedTx.To().Hex()
+	}
+	if len(signedTx.Data()) >= 4 {
+		e.Selector = hexutil.Encode(signedTx.Data()[:4])
+	}
+	if signedTx.Type() == types.LegacyTxType {
+		e.GasPrice = signedTx.GasPrice().String()
+	} else {
+		e.GasTipCap = signedTx.GasTipCap().String()
+		e.GasFeeCap = signedTx.GasFeeCap().String()
+	}
+	return audit.Signed(e, m)
+}
+
+// auditReceipt records the final status of a tx in the audit log
+func (w *Writer) auditReceipt(txHash common.Hash, status, reason string) {
+	if err := audit.Receipt(w.cfg.Id, txHash.Hex(), status, reason); err != nil {
+		w.log.Warn("Audit tx receipt failed", "tx", txHash, "err", err)
+	}
+}
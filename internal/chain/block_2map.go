@@ -7,20 +7,18 @@ import (
 	"strings"
 	"time"
 
-	"github.com/mapprotocol/compass/pkg/util"
+	"github.com/mapprotocol/compass/pkg/alert"
 
 	"github.com/mapprotocol/compass/internal/constant"
 	"github.com/mapprotocol/compass/mapprotocol"
 	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/metrics"
 )
 
 // execToMapMsg executes sync msg, and send tx to the destination blockchain
 // the current function is only responsible for sending messages and is not responsible for processing data formats，
 func (w *Writer) execToMapMsg(m msg.Message) bool {
-	var (
-		errorCount int64
-		needNonce  = true
-	)
+	var errorCount int64
 	for {
 		select {
 		case <-w.stop:
@@ -38,44 +36,43 @@ func (w *Writer) execToMapMsg(m msg.Message) bool {
 				method = mapprotocol.MethodUpdateLightClient
 			}
 
-			err := w.toMap(m, id, marshal, method, needNonce)
+			err := w.toMap(m, id, marshal, method)
 			if err != nil {
-				needNonce = w.needNonce(err)
 				time.Sleep(constant.TxRetryInterval)
 				errorCount++
+				metrics.Retry(w.cfg.Id, m.Type)
 				if errorCount >= 10 {
-					util.Alarm(context.Background(), fmt.Sprintf("%s2map updateHeader failed, err is %s",
-						mapprotocol.OnlineChaId[m.Source], err.Error()))
+					alert.Fire(context.Background(), alert.Warning, mapprotocol.OnlineChaId[m.Source], alert.KeyLightClient,
+						fmt.Sprintf("%s2map updateHeader failed, err is %s", mapprotocol.OnlineChaId[m.Source], err.Error()))
 					errorCount = 0
 				}
 				continue
 			}
+			alert.Resolve(context.Background(), mapprotocol.OnlineChaId[m.Source], alert.KeyLightClient)
 			m.DoneCh <- struct{}{}
 			return true
 		}
 	}
 }
 
-func (w *Writer) toMap(m msg.Message, id *big.Int, marshal []byte, method string, needNonce bool) error {
-	err := w.conn.LockAndUpdateOpts(needNonce)
+func (w *Writer) toMap(m msg.Message, id *big.Int, marshal []byte, method string) error {
+	opts, err := w.conn.PrepareTx(w.ctx)
 	if err != nil {
-		w.log.Error("BlockToMap Failed to update nonce", "err", err)
+		w.log.Error("BlockToMap Failed to prepare tx", "err", err)
 		return err
 	}
 
 	data, err := mapprotocol.PackInput(mapprotocol.LightManger, method, id, marshal)
 	if err != nil {
 		w.log.Error("block2Map Failed to pack abi data", "err", err)
-		w.conn.UnlockOpts()
 		return err
 	}
-	tx, err := w.sendTx(&w.cfg.LightNode, nil, data)
-	w.conn.UnlockOpts()
+	tx, err := w.sendTx(opts, m, &w.cfg.LightNode, nil, data)
 	if err == nil {
 		// message successfully handled
 		w.log.Info("Sync Header to map tx execution", "tx", tx.Hash(), "src", m.Source, "dst", m.Destination,
-			"method", method, "needNonce", needNonce, "nonce", w.conn.Opts().Nonce)
-		err = w.txStatus(tx.Hash())
+			"method", method, "nonce", tx.Nonce())
+		err = w.txStatus(m, tx)
 		if err != nil {
 			w.log.Warn("TxHash Status is not successful, will retry", "err", err)
 		} else {
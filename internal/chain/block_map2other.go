@@ -9,36 +9,34 @@ import (
 	"github.com/mapprotocol/compass/internal/constant"
 	"github.com/mapprotocol/compass/mapprotocol"
 	"github.com/mapprotocol/compass/msg"
-	"github.com/mapprotocol/compass/pkg/util"
+	"github.com/mapprotocol/compass/pkg/alert"
+	"github.com/mapprotocol/compass/pkg/metrics"
 )
 
 // execMap2OtherMsg executes sync msg, and send tx to the destination blockchain
 func (w *Writer) execMap2OtherMsg(m msg.Message) bool {
-	var (
-		errorCount int64
-		needNonce  = true
-	)
+	var errorCount int64
 	for {
 		select {
 		case <-w.stop:
 			return false
 		default:
-			err := w.conn.LockAndUpdateOpts(needNonce)
+			opts, err := w.conn.PrepareTx(w.ctx)
 			if err != nil {
-				w.log.Error("Failed to update nonce", "err", err)
+				w.log.Error("Failed to prepare tx", "err", err)
 				time.Sleep(constant.TxRetryInterval)
 				continue
 			}
 
-			tx, err := w.sendTx(&w.cfg.LightNode, nil, m.Payload[0].([]byte))
-			w.conn.UnlockOpts()
+			tx, err := w.sendTx(opts, m, &w.cfg.LightNode, nil, m.Payload[0].([]byte))
 			if err == nil {
 				// message successfully handled
-				w.log.Info("Sync Map Header to other chain tx execution", "tx", tx.Hash(), "src", m.Source, "dst", m.Destination, "needNonce", needNonce, "nonce", w.conn.Opts().Nonce)
-				err = w.txStatus(tx.Hash())
+				w.log.Info("Sync Map Header to other chain tx execution", "tx", tx.Hash(), "src", m.Source, "dst", m.Destination, "nonce", tx.Nonce())
+				err = w.txStatus(m, tx)
 				if err != nil {
 					w.log.Warn("TxHash Status is not successful, will retry", "err", err)
 				} else {
+					alert.Resolve(context.Background(), w.cfg.Name, alert.KeyLightClient)
 					m.DoneCh <- struct{}{}
 					return true
 				}
@@ -56,10 +54,11 @@ func (w *Writer) execMap2OtherMsg(m msg.Message) bool {
 				}
 				w.log.Warn("Sync Map Header to other chain Execution failed, header may already been synced", "id", m.Destination, "err", err)
 			}
-			needNonce = w.needNonce(err)
 			errorCount++
+			metrics.Retry(w.cfg.Id, m.Type)
 			if errorCount >= 10 {
-				util.Alarm(context.Background(), fmt.Sprintf("map2%s updateHeader failed, err is %s", mapprotocol.OnlineChaId[m.Destination], err.Error()))
+				alert.Fire(context.Background(), alert.Warning, w.cfg.Name, alert.KeyLightClient,
+					fmt.Sprintf("map2%s updateHeader failed, err is %s", mapprotocol.OnlineChaId[m.Destination], err.Error()))
 				errorCount = 0
 			}
 			time.Sleep(constant.TxRetryInterval)
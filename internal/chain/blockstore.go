@@ -5,6 +5,7 @@ import (
 
 	"github.com/mapprotocol/compass/mapprotocol"
 	"github.com/mapprotocol/compass/pkg/blockstore"
+	"github.com/mapprotocol/compass/pkg/journal"
 )
 
 func SetupBlockStore(cfg *Config, role mapprotocol.Role) (*blockstore.Blockstore, error) {
@@ -29,3 +30,8 @@ func SetupBlockStore(cfg *Config, role mapprotocol.Role) (*blockstore.Blockstore
 
 	return bs, nil
 }
+
+// SetupJournal opens the pending tx journal of the writer, it lives next to the blockstore
+func SetupJournal(cfg *Config, role mapprotocol.Role) (*journal.Journal, error) {
+	return journal.New(cfg.BlockstorePath, cfg.Id, cfg.From, role)
+}
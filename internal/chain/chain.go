@@ -1,7 +1,13 @@
 package chain
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
 	"github.com/ChainSafe/log15"
+	ethkeystore "github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/mapprotocol/compass/chains"
 	"github.com/mapprotocol/compass/core"
@@ -11,6 +17,7 @@ import (
 	"github.com/mapprotocol/compass/pkg/abi"
 	"github.com/mapprotocol/compass/pkg/contract"
 	"github.com/mapprotocol/compass/pkg/ethclient"
+	"github.com/mapprotocol/compass/pkg/signer"
 	"github.com/pkg/errors"
 )
 
@@ -29,25 +36,34 @@ func New(chainCfg *core.ChainConfig, logger log15.Logger, sysErr chan<- error, r
 		return nil, err
 	}
 
-	kpI, err := keystore.KeypairFromEth(cfg.KeystorePath)
+	SetupEndpoints(cfg)
+	keys, err := LoadSigners(context.Background(), cfg)
 	if err != nil {
 		return nil, err
 	}
+	kpI := ConnKey(keys[0])
 
 	bs, err := SetupBlockStore(cfg, role)
 	if err != nil {
 		return nil, err
 	}
+	jn, err := SetupJournal(cfg, role)
+	if err != nil {
+		return nil, err
+	}
 
 	stop := make(chan int)
-	conn := createConn(cfg.Endpoint, cfg.Http, kpI, logger, cfg.GasLimit, cfg.MaxGasPrice, cfg.GasMultiplier)
-	err = conn.Connect()
+	conn := createConn(cfg.Endpoint, cfg.Http, kpI, logger, cfg.GasLimit, cfg.MaxGasPrice, cfg.GasMultiplier, cfg.ConnOpts())
+	if _, ok := conn.(core.QuorumConnection); cfg.Quorum > 0 && !ok {
+		return nil, fmt.Errorf("the connection of %s does not support opts.%s", cfg.Name, QuorumOpt)
+	}
+	err = conn.Connect(context.Background())
 	if err != nil {
 		return nil, err
 	}
 
 	if chainCfg.LatestBlock {
-		curr, err := conn.LatestBlock()
+		curr, err := conn.LatestBlock(context.Background())
 		if err != nil {
 			return nil, err
 		}
@@ -62,6 +78,7 @@ func New(chainCfg *core.ChainConfig, logger log15.Logger, sysErr chan<- error, r
 			fn := mapprotocol.Map2EthHeight(cfg.From, cfg.LightNode, conn.Client())
 			height, err := fn()
 			if err != nil {
+				cs.Close()
 				return nil, errors.Wrap(err, "Map2Other get init headerHeight failed")
 			}
 			logger.Info("Map2other Current situation", "id", cfg.Id, "height", height, "lightNode", cfg.LightNode)
@@ -77,7 +94,7 @@ func New(chainCfg *core.ChainConfig, logger log15.Logger, sysErr chan<- error, r
 	case mapprotocol.RoleOfOracle:
 		listen = NewOracle(cs)
 	}
-	wri := NewWriter(conn, cfg, logger, stop, sysErr)
+	wri := NewWriter(conn, cfg, keys, jn, logger, stop, sysErr)
 
 	return &Chain{
 		cfg:    chainCfg,
@@ -88,13 +105,92 @@ func New(chainCfg *core.ChainConfig, logger log15.Logger, sysErr chan<- error, r
 	}, nil
 }
 
+// LoadSigners returns the signers of all relayer keys configured for the chain, backed by the remote signer if
+// one is configured, or else by the keystores
+func LoadSigners(ctx context.Context, cfg *Config) ([]signer.Signer, error) {
+	if cfg.Signer == "" {
+		keys, err := LoadKeys(cfg)
+		if err != nil {
+			return nil, err
+		}
+		ret := make([]signer.Signer, 0, len(keys))
+		for _, k := range keys {
+			ret = append(ret, signer.NewLocal(k))
+		}
+		return ret, nil
+	}
+
+	if cfg.SignerAuth != nil {
+		ethclient.SetAuth(cfg.Signer, cfg.SignerAuth)
+	}
+	ret := make([]signer.Signer, 0, len(cfg.Froms))
+	for _, from := range cfg.Froms {
+		remote, err := signer.DialRemote(ctx, cfg.Signer, common.HexToAddress(from))
+		if err != nil {
+			return nil, err
+		}
+		// signers without eth_accounts are only found out at the first signature
+		if accounts, err := remote.Accounts(ctx); err == nil && !containsAddress(accounts, remote.Address()) {
+			return nil, fmt.Errorf("signer %s holds no key of %s", cfg.Signer, from)
+		}
+		ret = append(ret, remote)
+	}
+	return ret, nil
+}
+
+// ConnKey returns the key given to the connection of the chain, which only uses its address when signing remotely
+func ConnKey(s signer.Signer) *ethkeystore.Key {
+	if l, ok := s.(*signer.Local); ok {
+		return l.Key()
+	}
+	return &ethkeystore.Key{Address: s.Address()}
+}
+
+func containsAddress(addrs []common.Address, addr common.Address) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadKeys decrypts the keystores of all relayer keys configured for the chain
+func LoadKeys(cfg *Config) ([]*ethkeystore.Key, error) {
+	paths := cfg.KeystorePaths
+	if len(paths) == 0 {
+		paths = []string{cfg.KeystorePath}
+	}
+	keys := make([]*ethkeystore.Key, 0, len(paths))
+	for idx, path := range paths {
+		from := cfg.From
+		if idx < len(cfg.Froms) {
+			from = cfg.Froms[idx]
+		}
+		kp, err := keystore.KeypairFromEth(path, from, cfg.Password)
+		if err != nil {
+			return nil, err
+		}
+		if idx < len(cfg.Froms) && len(cfg.Froms) > 1 && !strings.EqualFold(kp.Address.Hex(), cfg.Froms[idx]) {
+			return nil, fmt.Errorf("keystore %s belongs to %s, not %s", path, kp.Address, cfg.Froms[idx])
+		}
+		keys = append(keys, kp)
+	}
+	return keys, nil
+}
+
 func (c *Chain) SetRouter(r *core.Router) {
 	r.Listen(c.cfg.Id, c.writer)
 	c.listen.SetRouter(r)
 }
 
 func (c *Chain) Start() error {
-	err := c.listen.Sync()
+	err := c.writer.Start()
+	if err != nil {
+		return err
+	}
+
+	err = c.listen.Sync()
 	if err != nil {
 		return err
 	}
@@ -1,6 +1,7 @@
 package chain
 
 import (
+	"context"
 	"fmt"
 	"github.com/mapprotocol/compass/mapprotocol"
 	"github.com/mapprotocol/compass/msg"
@@ -8,6 +9,19 @@ import (
 	"math/big"
 )
 
+// stopContext returns a context canceled when stop is closed, cancel releases it when stop is never closed
+func stopContext(stop <-chan int) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
 var (
 	OrderExist    = errors.New("order exist")
 	NotVerifyAble = errors.New("not verify able")
@@ -1,6 +1,7 @@
 package chain
 
 import (
+	"context"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/mapprotocol/compass/internal/constant"
 	"github.com/mapprotocol/compass/msg"
@@ -62,6 +63,8 @@ type CommonSync struct {
 	Log                log15.Logger
 	Router             chains.Router
 	Stop               <-chan int
+	Ctx                context.Context // canceled when Stop is closed
+	cancel             context.CancelFunc
 	MsgCh              chan struct{}
 	SysErr             chan<- error // Reports fatal error to core
 	LatestBlock        metrics.LatestBlock
@@ -72,16 +75,21 @@ type CommonSync struct {
 	mosHandler         Mos
 	oracleHandler      OracleHandler
 	assembleProof      AssembleProof
+	disconnected       int32        // 1 while every endpoint of Conn is down
+	agreed             *agreedBlock // set by QuorumPaused for the block being processed
 }
 
 // NewCommonSync creates and returns a listener
 func NewCommonSync(conn core.Connection, cfg *Config, log log15.Logger, stop <-chan int, sysErr chan<- error,
 	bs blockstore.Blockstorer, opts ...SyncOpt) *CommonSync {
+	ctx, cancel := stopContext(stop)
 	cs := &CommonSync{
 		Cfg:                *cfg,
 		Conn:               conn,
 		Log:                log,
 		Stop:               stop,
+		Ctx:                ctx,
+		cancel:             cancel,
 		SysErr:             sysErr,
 		LatestBlock:        metrics.LatestBlock{LastUpdated: time.Now()},
 		BlockConfirmations: cfg.BlockConfirmations,
@@ -93,10 +101,18 @@ func NewCommonSync(conn core.Connection, cfg *Config, log log15.Logger, stop <-c
 	for _, op := range opts {
 		op(cs)
 	}
+	if rc, ok := conn.(core.ReconnectingConnection); ok {
+		go cs.watchConn(rc)
+	}
 
 	return cs
 }
 
+// Close cancels Ctx, for a listener dropped before Stop is closed
+func (c *CommonSync) Close() {
+	c.cancel()
+}
+
 func (c *CommonSync) SetRouter(r chains.Router) {
 	c.Router = r
 }
@@ -5,14 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"github.com/mapprotocol/compass/internal/constant"
+	"math"
 	"math/big"
+	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 	gconfig "github.com/mapprotocol/compass/config"
 	"github.com/mapprotocol/compass/core"
+	"github.com/mapprotocol/compass/keystore"
 	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/ethclient"
+	"github.com/mapprotocol/compass/pkg/signer"
 )
 
 const (
@@ -43,17 +48,65 @@ var (
 	RedisOpt              = "redis"
 	ApiUrl                = "apiUrl"
 	OracleNode            = "oracleNode"
+	KeyStrategyOpt        = "keyStrategy"
+	DedicatedKeysOpt      = "dedicatedKeys"
+	MinBalanceOpt         = "minBalance"
+	TxConfirmationsOpt    = "txConfirmations"
+	BroadcastOpt          = "broadcast"
+	QuorumOpt             = "quorum"
+	RateLimitOpt          = "rateLimit"
+	RateBurstOpt          = "rateBurst"
+	ReceiptBatchSizeOpt   = "receiptBatchSize"
+	RpcHeadersOpt         = "rpcHeaders"
+	RpcBasicAuthOpt       = "rpcBasicAuth"
+	RpcJwtSecretOpt       = "rpcJwtSecret"
+	RpcTlsCertOpt         = "rpcTlsCert"
+	RpcTlsKeyOpt          = "rpcTlsKey"
+	RpcTlsCaOpt           = "rpcTlsCa"
+	PasswordEnvOpt        = "keystorePasswordEnv"
+	PasswordFileOpt       = "keystorePasswordFile"
+	SignerOpt             = "signer"
+	SignerHeadersOpt      = "signerHeaders"
+	SignerTlsCertOpt      = "signerTlsCert"
+	SignerTlsKeyOpt       = "signerTlsKey"
+	SignerTlsCaOpt        = "signerTlsCa"
+	SignPolicyOpt         = "signPolicy"
+	SignMaxGasPriceOpt    = "signMaxGasPrice"
+)
+
+// TxFinalized is the txConfirmations value which waits for the tx block to be finalized
+const TxFinalized = "finalized"
+
+// Values of the broadcast option
+const (
+	BroadcastOne = "one" // send txs to the active endpoint only
+	BroadcastAll = "all" // send txs to every endpoint
 )
 
 // Config encapsulates all necessary parameters in ethereum compatible forms
 type Config struct {
-	Name               string      // Human-readable chain name
-	Id                 msg.ChainId // ChainID
-	Endpoint           string      // url for rpc endpoint
-	From               string      // address of key to use
-	KeystorePath       string      // Location of keyfiles
+	Name               string          // Human-readable chain name
+	Id                 msg.ChainId     // ChainID
+	Endpoint           string          // url for rpc endpoint, or a comma-separated list of urls
+	BroadcastAll       bool            // Send txs to every endpoint instead of only the active one
+	Quorum             int             // Number of endpoints which must agree on proof-critical reads, 0 disables quorum reads
+	RateLimit          float64         // Requests per second sent to each endpoint, 0 is unlimited
+	RateBurst          int             // Requests which may be sent at once before RateLimit applies
+	ReceiptBatchSize   int             // Receipts requested in one batch call, 1 disables batching
+	Auth               *ethclient.Auth // Credentials sent to every endpoint, nil if none is configured
+	From               string          // address of key to use
+	KeystorePath       string          // Location of keyfiles
+	Froms              []string        // addresses of all relayer keys, From is the first one
+	KeystorePaths      []string        // Location of keyfiles of all relayer keys, in the same order as Froms
+	KeyStrategy        string          // How the writer picks a relayer key: roundRobin, leastPending or dedicated
+	DedicatedKeys      map[msg.TransferType]common.Address
+	MinBalance         *big.Int // relayer keys with a lower balance are taken out of rotation
 	BlockstorePath     string
-	FreshStart         bool // Disables loading from blockstore at start
+	Password           keystore.PasswordSource
+	Signer             string          // url of the remote signer holding the relayer keys, keystores are used if empty
+	SignerAuth         *ethclient.Auth // Credentials sent to the signer, nil if none is configured
+	Policy             *signer.Policy  // What the relayer keys will sign
+	FreshStart         bool            // Disables loading from blockstore at start
 	McsContract        []common.Address
 	GasLimit           *big.Int
 	MaxGasPrice        *big.Int
@@ -62,6 +115,8 @@ type Config struct {
 	Http               bool // Config for type of connection
 	StartBlock         *big.Int
 	BlockConfirmations *big.Int
+	TxConfirmations    uint64 // Number of blocks a writer tx must be deep before it is acknowledged
+	TxFinalized        bool   // Acknowledge a writer tx only once its block is finalized
 	EgsApiKey          string // API key for ethgasstation to query gas prices
 	EgsSpeed           string // The speed which a transaction should be processed: average, fast, fastest. Default: fast
 	SyncToMap          bool   // Whether sync blockchain headers to Map
@@ -71,6 +126,7 @@ type Config struct {
 	SyncMap            map[msg.ChainId]*big.Int
 	Events             []constant.EventSig
 	SkipError          bool
+	DryRun             bool // Simulate transactions instead of broadcasting them
 	Eth2Endpoint       string
 	ApiUrl             string
 	OracleNode         common.Address
@@ -85,6 +141,8 @@ func ParseConfig(chainCfg *core.ChainConfig) (*Config, error) {
 		Endpoint:           chainCfg.Endpoint,
 		From:               chainCfg.From,
 		KeystorePath:       chainCfg.KeystorePath,
+		KeyStrategy:        KeyStrategyRoundRobin,
+		DedicatedKeys:      make(map[msg.TransferType]common.Address),
 		BlockstorePath:     chainCfg.BlockstorePath,
 		FreshStart:         chainCfg.FreshStart,
 		McsContract:        []common.Address{},
@@ -100,6 +158,7 @@ func ParseConfig(chainCfg *core.ChainConfig) (*Config, error) {
 		EgsSpeed:           "",
 		Events:             make([]constant.EventSig, 0),
 		SkipError:          chainCfg.SkipError,
+		DryRun:             chainCfg.DryRun,
 		Eth2Endpoint:       "",
 		ApiUrl:             "",
 	}
@@ -112,6 +171,49 @@ func ParseConfig(chainCfg *core.ChainConfig) (*Config, error) {
 		return nil, fmt.Errorf("must provide opts.mcs field for ethereum config")
 	}
 
+	for _, from := range strings.Split(chainCfg.From, ",") {
+		config.Froms = append(config.Froms, strings.TrimSpace(from))
+	}
+	config.From = config.Froms[0]
+	if chainCfg.KeystorePath != "" {
+		for _, path := range strings.Split(chainCfg.KeystorePath, ",") {
+			config.KeystorePaths = append(config.KeystorePaths, strings.TrimSpace(path))
+		}
+		config.KeystorePath = config.KeystorePaths[0]
+	}
+	config.Signer = chainCfg.Opts[SignerOpt]
+	if len(config.Froms) > 1 && config.Signer == "" && len(config.KeystorePaths) != len(config.Froms) {
+		return nil, fmt.Errorf("%d relayer addresses configured but %d keystore paths", len(config.Froms), len(config.KeystorePaths))
+	}
+
+	if v, ok := chainCfg.Opts[KeyStrategyOpt]; ok && v != "" {
+		switch v {
+		case KeyStrategyRoundRobin, KeyStrategyLeastPending, KeyStrategyDedicated:
+			config.KeyStrategy = v
+		default:
+			return nil, fmt.Errorf("unknown %s %s", KeyStrategyOpt, v)
+		}
+	}
+
+	if v, ok := chainCfg.Opts[DedicatedKeysOpt]; ok && v != "" {
+		dedicated := make(map[msg.TransferType]string)
+		err := json.Unmarshal([]byte(v), &dedicated)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", DedicatedKeysOpt, err)
+		}
+		for t, addr := range dedicated {
+			config.DedicatedKeys[t] = common.HexToAddress(addr)
+		}
+	}
+
+	if v, ok := chainCfg.Opts[MinBalanceOpt]; ok && v != "" {
+		val, pass := big.NewInt(0).SetString(v, 10)
+		if !pass {
+			return nil, fmt.Errorf("unable to parse %s", MinBalanceOpt)
+		}
+		config.MinBalance = val
+	}
+
 	if gasPrice, ok := chainCfg.Opts[MaxGasPriceOpt]; ok {
 		price := big.NewInt(0)
 		_, pass := price.SetString(gasPrice, 10)
@@ -178,6 +280,86 @@ func ParseConfig(chainCfg *core.ChainConfig) (*Config, error) {
 		config.BlockConfirmations = big.NewInt(DefaultBlockConfirmations)
 	}
 
+	if v, ok := chainCfg.Opts[TxConfirmationsOpt]; ok && v != "" {
+		if v == TxFinalized {
+			config.TxFinalized = true
+		} else {
+			val, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse %s", TxConfirmationsOpt)
+			}
+			config.TxConfirmations = val
+		}
+	}
+
+	if v, ok := chainCfg.Opts[BroadcastOpt]; ok && v != "" {
+		switch v {
+		case BroadcastOne:
+		case BroadcastAll:
+			config.BroadcastAll = true
+		default:
+			return nil, fmt.Errorf("unknown %s %s, must be %s or %s", BroadcastOpt, v, BroadcastOne, BroadcastAll)
+		}
+	}
+
+	if v, ok := chainCfg.Opts[QuorumOpt]; ok && v != "" {
+		val, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s", QuorumOpt)
+		}
+		endpoints := len(strings.Split(config.Endpoint, ","))
+		if val < 2 || val > endpoints {
+			return nil, fmt.Errorf("%s must be between 2 and the number of endpoints (%d), got %d", QuorumOpt, endpoints, val)
+		}
+		config.Quorum = val
+	}
+
+	if v, ok := chainCfg.Opts[RateLimitOpt]; ok && v != "" {
+		val, err := strconv.ParseFloat(v, 64)
+		if err != nil || val < 0 {
+			return nil, fmt.Errorf("unable to parse %s", RateLimitOpt)
+		}
+		config.RateLimit = val
+		config.RateBurst = int(math.Ceil(val))
+	}
+
+	if v, ok := chainCfg.Opts[RateBurstOpt]; ok && v != "" {
+		val, err := strconv.Atoi(v)
+		if err != nil || val < 1 {
+			return nil, fmt.Errorf("unable to parse %s", RateBurstOpt)
+		}
+		config.RateBurst = val
+	}
+
+	if v, ok := chainCfg.Opts[ReceiptBatchSizeOpt]; ok && v != "" {
+		val, err := strconv.Atoi(v)
+		if err != nil || val < 1 {
+			return nil, fmt.Errorf("unable to parse %s", ReceiptBatchSizeOpt)
+		}
+		config.ReceiptBatchSize = val
+	}
+
+	auth, err := ParseAuth(chainCfg.Opts)
+	if err != nil {
+		return nil, err
+	}
+	config.Auth = auth
+	config.Password = ParsePassword(chainCfg.Opts)
+	if config.SignerAuth, err = ParseAuth(map[string]string{
+		RpcHeadersOpt: chainCfg.Opts[SignerHeadersOpt],
+		RpcTlsCertOpt: chainCfg.Opts[SignerTlsCertOpt],
+		RpcTlsKeyOpt:  chainCfg.Opts[SignerTlsKeyOpt],
+		RpcTlsCaOpt:   chainCfg.Opts[SignerTlsCaOpt],
+	}); err != nil {
+		return nil, fmt.Errorf("unable to parse signer credentials: %v", err)
+	}
+
+	DeleteCredentialOpts(chainCfg.Opts)
+
+	if config.Policy, err = ParsePolicy(config.Name, chainCfg.Opts, PolicyContracts(chainCfg.Opts)); err != nil {
+		return nil, err
+	}
+
 	if gsnApiKey, ok := chainCfg.Opts[EGSApiKey]; ok && gsnApiKey != "" {
 		config.EgsApiKey = gsnApiKey
 	}
@@ -233,3 +415,138 @@ func ParseConfig(chainCfg *core.ChainConfig) (*Config, error) {
 
 	return config, nil
 }
+
+// ConnOpts returns the connection options of the chain
+func (c *Config) ConnOpts() core.ConnOpts {
+	return core.ConnOpts{Name: c.Name, BroadcastAll: c.BroadcastAll, ReceiptBatchSize: c.ReceiptBatchSize}
+}
+
+// SetupEndpoints applies the rate limit and the credentials of the chain to every endpoint of it, including the
+// eth2Url endpoint
+func SetupEndpoints(cfg *Config) {
+	endpoints := strings.Split(cfg.Endpoint, ",")
+	if cfg.Eth2Endpoint != "" {
+		endpoints = append(endpoints, cfg.Eth2Endpoint)
+	}
+	for _, e := range endpoints {
+		e = strings.TrimSpace(e)
+		if cfg.RateLimit != 0 {
+			ethclient.SetRateLimit(cfg.Name, e, cfg.RateLimit, cfg.RateBurst)
+		}
+		if cfg.Auth != nil {
+			ethclient.SetAuth(e, cfg.Auth)
+		}
+	}
+}
+
+// DeleteCredentialOpts removes the credentials options once they are parsed, so they are only held by the config
+func DeleteCredentialOpts(opts map[string]string) {
+	for _, opt := range []string{RpcHeadersOpt, RpcBasicAuthOpt, RpcJwtSecretOpt, RpcTlsCertOpt, RpcTlsKeyOpt, RpcTlsCaOpt,
+		PasswordEnvOpt, PasswordFileOpt, SignerHeadersOpt, SignerTlsCertOpt, SignerTlsKeyOpt, SignerTlsCaOpt} {
+		delete(opts, opt)
+	}
+}
+
+// ParseAuth reads the credentials options of a chain, it returns nil if none is set. Header values, the basic auth
+// and the jwt secret may be read from the environment with "env:NAME" or from a file with "file:PATH".
+func ParseAuth(opts map[string]string) (*ethclient.Auth, error) {
+	var (
+		auth = &ethclient.Auth{}
+		set  bool
+	)
+	if v, ok := opts[RpcHeadersOpt]; ok && v != "" {
+		auth.Header = make(http.Header)
+		for _, kv := range strings.Split(v, ",") {
+			i := strings.Index(kv, ":")
+			if i <= 0 {
+				return nil, fmt.Errorf("unable to parse %s, expected Name: value", RpcHeadersOpt)
+			}
+			val, err := ethclient.ReadSecret(strings.TrimSpace(kv[i+1:]))
+			if err != nil {
+				return nil, fmt.Errorf("unable to read %s: %v", RpcHeadersOpt, err)
+			}
+			auth.Header.Add(strings.TrimSpace(kv[:i]), val)
+		}
+		set = true
+	}
+
+	if v, ok := opts[RpcBasicAuthOpt]; ok && v != "" {
+		val, err := ethclient.ReadSecret(v)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %v", RpcBasicAuthOpt, err)
+		}
+		i := strings.Index(val, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("unable to parse %s, expected user:password", RpcBasicAuthOpt)
+		}
+		auth.Username, auth.Password = val[:i], val[i+1:]
+		set = true
+	}
+
+	if v, ok := opts[RpcJwtSecretOpt]; ok && v != "" {
+		val, err := ethclient.ReadSecret(v)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %v", RpcJwtSecretOpt, err)
+		}
+		if auth.JWTSecret, err = ethclient.ParseJWTSecret(val); err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %v", RpcJwtSecretOpt, err)
+		}
+		set = true
+	}
+
+	cert, key, ca := opts[RpcTlsCertOpt], opts[RpcTlsKeyOpt], opts[RpcTlsCaOpt]
+	if (cert == "") != (key == "") {
+		return nil, fmt.Errorf("%s and %s must be set together", RpcTlsCertOpt, RpcTlsKeyOpt)
+	}
+	if cert != "" || ca != "" {
+		var err error
+		if auth.TLS, err = ethclient.NewTLSConfig(cert, key, ca); err != nil {
+			return nil, err
+		}
+		set = true
+	}
+
+	if !set {
+		return nil, nil
+	}
+	return auth, nil
+}
+
+// ParsePassword reads where the keystore passwords of a chain come from
+func ParsePassword(opts map[string]string) keystore.PasswordSource {
+	return keystore.PasswordSource{Env: opts[PasswordEnvOpt], File: opts[PasswordFileOpt]}
+}
+
+// AnySignPolicy is the signPolicy allowing the relayer keys to call any contract
+const AnySignPolicy = "any"
+
+// PolicyContracts returns the contracts the relayer keys of a chain may write without signPolicy, its mcs and
+// lightnode. The oracle node has to be listed in signPolicy.
+func PolicyContracts(opts map[string]string) []string {
+	return append(strings.Split(opts[McsOpt], ","), opts[LightNode])
+}
+
+// ParsePolicy reads what the relayer keys of a chain will sign. Without signPolicy any method of contracts may be
+// called, AnySignPolicy allows any call without value.
+func ParsePolicy(name string, opts map[string]string, contracts []string) (*signer.Policy, error) {
+	policy := &signer.Policy{Chain: name}
+	switch v := opts[SignPolicyOpt]; v {
+	case AnySignPolicy:
+	case "":
+		policy.Calls = signer.AllowContracts(contracts)
+	default:
+		calls, err := signer.ParseCalls(v)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", SignPolicyOpt, err)
+		}
+		policy.Calls = calls
+	}
+	if v, ok := opts[SignMaxGasPriceOpt]; ok && v != "" {
+		val, pass := big.NewInt(0).SetString(v, 10)
+		if !pass {
+			return nil, fmt.Errorf("unable to parse %s", SignMaxGasPriceOpt)
+		}
+		policy.MaxGasPrice = val
+	}
+	return policy, nil
+}
@@ -0,0 +1,48 @@
+package chain
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/mapprotocol/compass/core"
+	"github.com/mapprotocol/compass/internal/constant"
+	"github.com/mapprotocol/compass/pkg/health"
+)
+
+// watchConn follows the transport state of the connection, listeners pause while no endpoint is connected
+func (c *CommonSync) watchConn(rc core.ReconnectingConnection) {
+	ch := make(chan core.ConnEvent, 16)
+	sub := rc.SubscribeState(ch)
+	defer sub.Unsubscribe()
+	for {
+		select {
+		case <-c.Stop:
+			return
+		case err := <-sub.Err():
+			if err != nil {
+				c.Log.Warn("Connection state subscription failed", "err", err)
+			}
+			return
+		case ev := <-ch:
+			c.Log.Info("Connection state changed", "endpoint", ev.Endpoint, "state", ev.State, "connected", ev.Connected,
+				"err", ev.Err)
+			health.Connected(c.Cfg.Id, ev.Connected > 0)
+			if ev.Connected > 0 {
+				atomic.StoreInt32(&c.disconnected, 0)
+			} else {
+				atomic.StoreInt32(&c.disconnected, 1)
+			}
+		}
+	}
+}
+
+// Disconnected returns true if every endpoint of the connection dropped, after waiting constant.BlockRetryInterval
+// for them to be redialed. The caller retries the block.
+func (c *CommonSync) Disconnected() bool {
+	if atomic.LoadInt32(&c.disconnected) == 0 {
+		return false
+	}
+	c.Log.Debug("Connection is down, waiting for reconnection")
+	time.Sleep(constant.BlockRetryInterval)
+	return true
+}
@@ -0,0 +1,108 @@
+package chain
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/mapprotocol/compass/internal/constant"
+	"github.com/mapprotocol/compass/pkg/journal"
+)
+
+// journalTx records the signed tx of the message before it is broadcast
+func (w *Writer) journalTx(identity string, from common.Address, signedTx *types.Transaction) error {
+	if w.journal == nil {
+		return nil
+	}
+	raw, err := signedTx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return w.journal.Add(journal.Entry{
+		Hash:    signedTx.Hash().Hex(),
+		Nonce:   signedTx.Nonce(),
+		From:    from.Hex(),
+		Message: identity,
+		Raw:     hexutil.Encode(raw),
+	})
+}
+
+// forgetTx drops the tx from the journal once its outcome is known
+func (w *Writer) forgetTx(txHash common.Hash) {
+	if w.journal == nil {
+		return
+	}
+	if err := w.journal.Remove(txHash.Hex()); err != nil {
+		w.log.Warn("Remove tx from journal failed", "tx", txHash, "err", err)
+	}
+}
+
+// resumeTx returns the journaled tx of the message, so the caller waits for it instead of sending the message again
+func (w *Writer) resumeTx(identity string) (*types.Transaction, bool) {
+	if w.journal == nil {
+		return nil, false
+	}
+	e, ok := w.journal.Get(identity)
+	if !ok {
+		return nil, false
+	}
+	tx, err := decodeJournalTx(e)
+	if err != nil {
+		w.log.Warn("Journaled tx is invalid, drop it", "tx", e.Hash, "err", err)
+		w.forgetTx(common.HexToHash(e.Hash))
+		return nil, false
+	}
+	w.log.Info("Message has a journaled tx, resume waiting for it", "tx", e.Hash, "nonce", e.Nonce, "from", e.From)
+	return tx, true
+}
+
+// reconcile checks every journaled tx left by a previous run. Mined and pending txs are kept, so the message is
+// resumed when it is handled again, txs the node no longer knows are broadcast again with their original nonce.
+func (w *Writer) reconcile() error {
+	if w.journal == nil {
+		return nil
+	}
+	for _, e := range w.journal.Entries() {
+		txHash := common.HexToHash(e.Hash)
+		receipt, err := w.conn.Client().TransactionReceipt(context.Background(), txHash)
+		if err == nil {
+			if receipt.Status != types.ReceiptStatusSuccessful || e.Age() > constant.JournalEntryTTL {
+				w.log.Info("Journaled tx is finished, drop it", "tx", e.Hash, "status", receipt.Status, "block", receipt.BlockNumber)
+				w.forgetTx(txHash)
+				continue
+			}
+			w.log.Info("Journaled tx is mined, wait for its message", "tx", e.Hash, "block", receipt.BlockNumber)
+			continue
+		}
+		if _, _, err = w.conn.Client().TransactionByHash(context.Background(), txHash); err == nil {
+			w.log.Info("Journaled tx is pending, resume confirmation with its message", "tx", e.Hash, "nonce", e.Nonce)
+			continue
+		}
+
+		tx, err := decodeJournalTx(e)
+		if err == nil {
+			err = w.conn.Client().SendTransaction(context.Background(), tx)
+		}
+		if err != nil && !strings.Contains(err.Error(), "already known") {
+			w.log.Warn("Journaled tx is lost and can not be broadcast again, drop it", "tx", e.Hash, "nonce", e.Nonce, "err", err)
+			w.forgetTx(txHash)
+			continue
+		}
+		w.log.Info("Journaled tx broadcast again", "tx", e.Hash, "nonce", e.Nonce, "from", e.From)
+	}
+	return nil
+}
+
+func decodeJournalTx(e journal.Entry) (*types.Transaction, error) {
+	raw, err := hexutil.Decode(e.Raw)
+	if err != nil {
+		return nil, err
+	}
+	tx := new(types.Transaction)
+	if err = tx.UnmarshalBinary(raw); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
@@ -0,0 +1,267 @@
+package chain
+
+import (
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mapprotocol/compass/internal/constant"
+	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/signer"
+	"github.com/pkg/errors"
+)
+
+const (
+	KeyStrategyRoundRobin   = "roundRobin"
+	KeyStrategyLeastPending = "leastPending"
+	KeyStrategyDedicated    = "dedicated"
+)
+
+const (
+	maxKeyFailures       = 5
+	keyFailureCooldown   = time.Minute * 5
+	keyBalanceCheckDelay = time.Minute
+)
+
+var ErrNoAvailableKey = errors.New("no available relayer key")
+
+// relayerKey is a single signing key of the pool with its own nonce and health state
+type relayerKey struct {
+	signer        signer.Signer
+	nonce         uint64
+	nonceSynced   bool
+	pending       int
+	failures      int
+	disabledUntil time.Time
+	balance       *big.Int
+	checkedAt     time.Time
+}
+
+func (k *relayerKey) Address() common.Address {
+	return k.signer.Address()
+}
+
+// KeyPool hands out relayer keys to the writer according to the configured strategy.
+// Keys which run out of balance or keep failing are taken out of rotation for a while.
+type KeyPool struct {
+	lock      sync.Mutex
+	keys      []*relayerKey
+	strategy  string
+	dedicated map[msg.TransferType]common.Address
+	next      int
+	inflight  map[common.Hash]*relayerKey
+}
+
+func NewKeyPool(keys []signer.Signer, strategy string, dedicated map[msg.TransferType]common.Address) *KeyPool {
+	p := &KeyPool{
+		keys:      make([]*relayerKey, 0, len(keys)),
+		strategy:  strategy,
+		dedicated: dedicated,
+		inflight:  make(map[common.Hash]*relayerKey),
+	}
+	for _, k := range keys {
+		p.keys = append(p.keys, &relayerKey{signer: k})
+	}
+	if p.strategy == "" {
+		p.strategy = KeyStrategyRoundRobin
+	}
+	return p
+}
+
+func (p *KeyPool) Len() int {
+	return len(p.keys)
+}
+
+// Acquire picks a key for the given message type and marks it as pending
+func (p *KeyPool) Acquire(t msg.TransferType) (*relayerKey, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := time.Now()
+	var picked *relayerKey
+	switch p.strategy {
+	case KeyStrategyLeastPending:
+		for i := 0; i < len(p.keys); i++ {
+			k := p.keys[(p.next+i)%len(p.keys)]
+			if !k.available(now) {
+				continue
+			}
+			if picked == nil || k.pending < picked.pending {
+				picked = k
+			}
+		}
+	case KeyStrategyDedicated:
+		if addr, ok := p.dedicated[t]; ok {
+			for _, k := range p.keys {
+				if k.Address() == addr && k.available(now) {
+					picked = k
+					break
+				}
+			}
+		}
+		if picked == nil {
+			picked = p.roundRobin(now, true)
+		}
+	default:
+		picked = p.roundRobin(now, false)
+	}
+	if picked == nil {
+		return nil, ErrNoAvailableKey
+	}
+	p.next = (p.next + 1) % len(p.keys)
+	picked.pending++
+	return picked, nil
+}
+
+// roundRobin returns the next available key, skipping keys dedicated to a message type if possible
+func (p *KeyPool) roundRobin(now time.Time, skipDedicated bool) *relayerKey {
+	var fallback *relayerKey
+	for i := 0; i < len(p.keys); i++ {
+		k := p.keys[(p.next+i)%len(p.keys)]
+		if !k.available(now) {
+			continue
+		}
+		if skipDedicated && p.isDedicated(k.Address()) {
+			if fallback == nil {
+				fallback = k
+			}
+			continue
+		}
+		return k
+	}
+	return fallback
+}
+
+func (p *KeyPool) isDedicated(addr common.Address) bool {
+	for _, v := range p.dedicated {
+		if v == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// Nonce returns the nonce to use for the next tx of the key. When the key has nothing else in flight
+// the nonce is resynced from the node using the pending nonce returned by fetch.
+func (p *KeyPool) Nonce(k *relayerKey, fetch func(common.Address) (uint64, error)) (uint64, error) {
+	p.lock.Lock()
+	needSync := !k.nonceSynced || k.pending <= 1
+	p.lock.Unlock()
+	if needSync {
+		nonce, err := fetch(k.Address())
+		if err != nil {
+			return 0, err
+		}
+		p.lock.Lock()
+		// the node may not see the txs still in flight yet, so only move forward in that case
+		if k.pending <= 1 || nonce > k.nonce {
+			k.nonce = nonce
+		}
+		k.nonceSynced = true
+		p.lock.Unlock()
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	nonce := k.nonce
+	k.nonce++
+	return nonce, nil
+}
+
+// Sent records the tx sent by the key, the key stays pending until Done is called with its hash
+func (p *KeyPool) Sent(k *relayerKey, txHash common.Hash) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.inflight[txHash] = k
+}
+
+// Release gives the key back to the pool after a failed send, err is used to track key health.
+// A nil err means the failure was not caused by the key itself. The nonce is reused unless the node found it too low.
+func (p *KeyPool) Release(k *relayerKey, nonce uint64, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if k.nonce == nonce+1 && !nonceTooLow(err) {
+		k.nonce = nonce
+	} else {
+		k.nonceSynced = false
+	}
+	p.release(k, err)
+}
+
+func nonceTooLow(err error) bool {
+	return err != nil && (err.Error() == constant.ErrNonceTooLow.Error() || strings.Contains(err.Error(), "nonce too low"))
+}
+
+// Return gives the key back to the pool when the send failed before a nonce was taken, the nonce is left alone
+func (p *KeyPool) Return(k *relayerKey) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.release(k, nil)
+}
+
+// Done releases the key which sent txHash once its receipt has been checked
+func (p *KeyPool) Done(txHash common.Hash, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	k, ok := p.inflight[txHash]
+	if !ok {
+		return
+	}
+	delete(p.inflight, txHash)
+	if err != nil {
+		k.nonceSynced = false
+	}
+	p.release(k, err)
+}
+
+func (p *KeyPool) release(k *relayerKey, err error) {
+	if k.pending > 0 {
+		k.pending--
+	}
+	if err == nil {
+		k.failures = 0
+		return
+	}
+	k.failures++
+	if k.failures >= maxKeyFailures && p.enabled(time.Now()) > 1 {
+		k.disabledUntil = time.Now().Add(keyFailureCooldown)
+		k.failures = 0
+	}
+}
+
+// Disable takes the key out of rotation for d
+func (p *KeyPool) Disable(k *relayerKey, d time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	k.disabledUntil = time.Now().Add(d)
+}
+
+// NeedBalanceCheck reports whether the balance of the key should be queried again
+func (p *KeyPool) NeedBalanceCheck(k *relayerKey) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return time.Since(k.checkedAt) >= keyBalanceCheckDelay
+}
+
+func (p *KeyPool) SetBalance(k *relayerKey, balance *big.Int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	k.balance = balance
+	k.checkedAt = time.Now()
+}
+
+func (p *KeyPool) enabled(now time.Time) int {
+	count := 0
+	for _, k := range p.keys {
+		if k.available(now) {
+			count++
+		}
+	}
+	return count
+}
+
+func (k *relayerKey) available(now time.Time) bool {
+	return !now.Before(k.disabledUntil)
+}
@@ -0,0 +1,167 @@
+package chain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/signer"
+)
+
+func newTestKeys(t *testing.T, n int) []signer.Signer {
+	keys := make([]signer.Signer, 0, n)
+	for i := 0; i < n; i++ {
+		pk, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, signer.NewLocal(&keystore.Key{Address: crypto.PubkeyToAddress(pk.PublicKey), PrivateKey: pk}))
+	}
+	return keys
+}
+
+func TestKeyPoolRoundRobin(t *testing.T) {
+	keys := newTestKeys(t, 3)
+	p := NewKeyPool(keys, KeyStrategyRoundRobin, nil)
+
+	for i := 0; i < 6; i++ {
+		k, err := p.Acquire(msg.SyncToMap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k.Address() != keys[i%3].Address() {
+			t.Fatalf("round %d: expected %s got %s", i, keys[i%3].Address(), k.Address())
+		}
+	}
+
+	p.Disable(p.keys[1], time.Minute)
+	for i := 0; i < 4; i++ {
+		k, err := p.Acquire(msg.SyncToMap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k.Address() == keys[1].Address() {
+			t.Fatal("disabled key should be out of rotation")
+		}
+	}
+}
+
+func TestKeyPoolLeastPending(t *testing.T) {
+	keys := newTestKeys(t, 2)
+	p := NewKeyPool(keys, KeyStrategyLeastPending, nil)
+
+	first, _ := p.Acquire(msg.SwapWithProof)
+	second, _ := p.Acquire(msg.SwapWithProof)
+	if first == second {
+		t.Fatal("expected a different key while the first is pending")
+	}
+	p.Sent(second, common.HexToHash("0x01"))
+	p.Release(first, 0, nil)
+
+	third, _ := p.Acquire(msg.SwapWithProof)
+	if third != first {
+		t.Fatalf("expected least pending key %s got %s", first.Address(), third.Address())
+	}
+}
+
+func TestKeyPoolDedicated(t *testing.T) {
+	keys := newTestKeys(t, 3)
+	p := NewKeyPool(keys, KeyStrategyDedicated, map[msg.TransferType]common.Address{
+		msg.SyncToMap: keys[2].Address(),
+	})
+
+	for i := 0; i < 3; i++ {
+		k, _ := p.Acquire(msg.SyncToMap)
+		if k.Address() != keys[2].Address() {
+			t.Fatalf("expected dedicated key %s got %s", keys[2].Address(), k.Address())
+		}
+		k, _ = p.Acquire(msg.SwapWithProof)
+		if k.Address() == keys[2].Address() {
+			t.Fatal("dedicated key should not be used by other message types")
+		}
+	}
+}
+
+func TestKeyPoolFailingKeyOutOfRotation(t *testing.T) {
+	keys := newTestKeys(t, 2)
+	p := NewKeyPool(keys, KeyStrategyRoundRobin, nil)
+
+	bad := p.keys[0]
+	for i := 0; i < maxKeyFailures; i++ {
+		p.lock.Lock()
+		bad.pending++
+		p.lock.Unlock()
+		p.Release(bad, 0, errors.New("send failed"))
+	}
+	for i := 0; i < 4; i++ {
+		k, err := p.Acquire(msg.SyncFromMap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k == bad {
+			t.Fatal("failing key should be out of rotation")
+		}
+	}
+}
+
+func TestKeyPoolNonce(t *testing.T) {
+	keys := newTestKeys(t, 1)
+	p := NewKeyPool(keys, KeyStrategyRoundRobin, nil)
+	fetch := func(common.Address) (uint64, error) { return 7, nil }
+
+	k, _ := p.Acquire(msg.SyncToMap)
+	n, _ := p.Nonce(k, fetch)
+	if n != 7 {
+		t.Fatalf("expected nonce 7 got %d", n)
+	}
+	p.Sent(k, common.HexToHash("0x01"))
+
+	// a second message while the first is in flight uses the local nonce
+	k, _ = p.Acquire(msg.SyncToMap)
+	n, _ = p.Nonce(k, fetch)
+	if n != 8 {
+		t.Fatalf("expected nonce 8 got %d", n)
+	}
+	p.Release(k, n, errors.New("send failed"))
+
+	k, _ = p.Acquire(msg.SyncToMap)
+	n, _ = p.Nonce(k, fetch)
+	if n != 8 {
+		t.Fatalf("expected released nonce 8 to be reused, got %d", n)
+	}
+	// the node already has a tx with the nonce, it must not be handed out again
+	p.Release(k, n, errors.New("nonce too low"))
+
+	k, _ = p.Acquire(msg.SyncToMap)
+	n, _ = p.Nonce(k, fetch)
+	if n != 9 {
+		t.Fatalf("expected nonce 9 after nonce too low, got %d", n)
+	}
+}
+
+func TestKeyPoolReturn(t *testing.T) {
+	keys := newTestKeys(t, 1)
+	p := NewKeyPool(keys, KeyStrategyRoundRobin, nil)
+	fetch := func(common.Address) (uint64, error) { return 0, nil }
+
+	k, _ := p.Acquire(msg.SyncToMap)
+	n, _ := p.Nonce(k, fetch)
+	if n != 0 {
+		t.Fatalf("expected nonce 0 got %d", n)
+	}
+	p.Sent(k, common.HexToHash("0x01"))
+
+	// a send failing before its nonce is taken, like a failed gas estimation, must not roll back the nonce in flight
+	k, _ = p.Acquire(msg.SyncToMap)
+	p.Return(k)
+
+	k, _ = p.Acquire(msg.SyncToMap)
+	n, _ = p.Nonce(k, fetch)
+	if n != 1 {
+		t.Fatalf("expected nonce 1 got %d", n)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"github.com/mapprotocol/compass/internal/constant"
 	"github.com/mapprotocol/compass/mapprotocol"
-	"github.com/mapprotocol/compass/pkg/util"
+	"github.com/mapprotocol/compass/pkg/alert"
+	"github.com/mapprotocol/compass/pkg/health"
+	"github.com/mapprotocol/compass/pkg/metrics"
 	"github.com/pkg/errors"
 
 	"math/big"
@@ -27,7 +29,9 @@ func NewMaintainer(cs *CommonSync) *Maintainer {
 func (m *Maintainer) Sync() error {
 	m.Log.Debug("Starting listener...")
 	go func() {
+		health.Started(m.Cfg.Id)
 		err := m.sync()
+		health.Stopped(m.Cfg.Id, err)
 		if err != nil {
 			m.Log.Error("Polling blocks failed", "err", err)
 		}
@@ -41,6 +45,7 @@ func (m *Maintainer) Sync() error {
 // a block will be retried up to BlockRetryLimit times before continuing to the next block.
 func (m *Maintainer) sync() error {
 	if m.Cfg.Id != m.Cfg.MapChainID && !m.Cfg.SyncToMap {
+		health.Idle(m.Cfg.Id)
 		time.Sleep(time.Hour * 2400)
 		return nil
 	}
@@ -82,12 +87,16 @@ func (m *Maintainer) sync() error {
 		case <-m.Stop:
 			return errors.New("polling terminated")
 		default:
-			latestBlock, err := m.Conn.LatestBlock()
+			if m.Disconnected() {
+				continue
+			}
+			latestBlock, err := m.Conn.LatestBlock(m.Ctx)
 			if err != nil {
 				m.Log.Error("Unable to get latest block", "block", currentBlock, "err", err)
 				time.Sleep(constant.BlockRetryInterval)
 				continue
 			}
+			metrics.Head(m.Cfg.Id, latestBlock)
 
 			if big.NewInt(0).Sub(latestBlock, currentBlock).Cmp(m.BlockConfirmations) == -1 {
 				m.Log.Debug("Block not ready, will retry", "current", currentBlock, "latest", latestBlock)
@@ -101,12 +110,16 @@ func (m *Maintainer) sync() error {
 				time.Sleep(constant.BlockRetryInterval * time.Duration(difference.Int64()))
 			}
 
+			if m.QuorumPaused(currentBlock, nil) {
+				continue
+			}
 			if m.Cfg.Id == m.Cfg.MapChainID && len(m.Cfg.SyncChainIDList) > 0 {
 				err = m.syncHeaderToMap(m, currentBlock)
 				if err != nil {
 					m.Log.Error("Failed to listen header for block", "block", currentBlock, "err", err)
 					time.Sleep(constant.QueryRetryInterval)
-					util.Alarm(context.Background(), fmt.Sprintf("map sync header to other failed, err is %s", err.Error()))
+					alert.Fire(context.Background(), alert.Warning, m.Cfg.Name, alert.KeyHeaderSync,
+						fmt.Sprintf("map sync header to other failed, err is %s", err.Error()))
 					continue
 				}
 			} else if currentBlock.Cmp(m.syncedHeight) == 1 {
@@ -115,19 +128,23 @@ func (m *Maintainer) sync() error {
 					m.Log.Error("Failed to listen header for block", "block", currentBlock, "err", err)
 					time.Sleep(constant.QueryRetryInterval)
 					if err.Error() != "not found" {
-						util.Alarm(context.Background(), fmt.Sprintf("%s sync header failed, err is %s", m.Cfg.Name, err.Error()))
+						alert.Fire(context.Background(), alert.Warning, m.Cfg.Name, alert.KeyHeaderSync,
+							fmt.Sprintf("%s sync header failed, err is %s", m.Cfg.Name, err.Error()))
 					}
 					continue
 				}
 			} else {
 				time.Sleep(time.Hour)
 			}
+			alert.Resolve(context.Background(), m.Cfg.Name, alert.KeyHeaderSync)
 
 			// Write to block store. Not a critical operation, no need to retry
 			err = m.BlockStore.StoreBlock(currentBlock)
 			if err != nil {
 				m.Log.Error("Failed to write latest block to blockstore", "block", currentBlock, "err", err)
 			}
+			metrics.Processed(m.Cfg.Id, currentBlock)
+			health.Processed(m.Cfg.Id)
 
 			m.LatestBlock.Height = big.NewInt(0).Set(latestBlock)
 			m.LatestBlock.LastUpdated = time.Now()
@@ -3,11 +3,14 @@ package chain
 import (
 	"context"
 	"fmt"
+	"math/big"
 	"strings"
 	"time"
 
 	"github.com/mapprotocol/compass/internal/constant"
-	"github.com/mapprotocol/compass/pkg/util"
+	"github.com/mapprotocol/compass/pkg/alert"
+	"github.com/mapprotocol/compass/pkg/audit"
+	"github.com/mapprotocol/compass/pkg/cost"
 
 	"github.com/mapprotocol/compass/mapprotocol"
 
@@ -16,6 +19,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/metrics"
 	"github.com/pkg/errors"
 )
 
@@ -28,7 +32,6 @@ func (w *Writer) exeSwapMsg(m msg.Message) bool {
 func (w *Writer) callContractWithMsg(addr common.Address, m msg.Message) bool {
 	var (
 		errorCount, checkIdCount int64
-		needNonce                = true
 	)
 	for {
 		select {
@@ -41,7 +44,8 @@ func (w *Writer) callContractWithMsg(addr common.Address, m msg.Message) bool {
 				w.log.Error("check orderId exist failed ", "err", err, "orderId", common.Bytes2Hex(orderId))
 				checkIdCount++
 				if checkIdCount == 10 {
-					util.Alarm(context.Background(), fmt.Sprintf("writer mos checkOrderId failed, err is %s", err.Error()))
+					alert.Fire(context.Background(), alert.Warning, w.cfg.Name, alert.KeyOrderId,
+						fmt.Sprintf("writer mos checkOrderId failed, err is %s", err.Error()))
 					checkIdCount = 0
 				}
 			}
@@ -51,9 +55,9 @@ func (w *Writer) callContractWithMsg(addr common.Address, m msg.Message) bool {
 				return true
 			}
 
-			err = w.conn.LockAndUpdateOpts(needNonce)
+			opts, err := w.conn.PrepareTx(w.ctx)
 			if err != nil {
-				w.log.Error("Failed to update nonce", "err", err)
+				w.log.Error("Failed to prepare tx", "err", err)
 				time.Sleep(constant.TxRetryInterval)
 				continue
 			}
@@ -62,15 +66,16 @@ func (w *Writer) callContractWithMsg(addr common.Address, m msg.Message) bool {
 			if len(m.Payload) > 3 {
 				inputHash = m.Payload[3]
 			}
-			w.log.Info("Send transaction", "addr", addr, "srcHash", inputHash, "needNonce", needNonce, "nonce", w.conn.Opts().Nonce)
-			mcsTx, err := w.sendTx(&addr, nil, m.Payload[0].([]byte))
+			w.log.Info("Send transaction", "addr", addr, "srcHash", inputHash)
+			mcsTx, err := w.sendTx(opts, m, &addr, nil, m.Payload[0].([]byte))
 			//err = w.call(&addr, m.Payload[0].([]byte), mapprotocol.Other, mapprotocol.MethodVerifyProofData)
 			if err == nil {
-				w.log.Info("Submitted cross tx execution", "src", m.Source, "dst", m.Destination, "srcHash", inputHash, "mcsTx", mcsTx.Hash())
-				err = w.txStatus(mcsTx.Hash())
+				w.log.Info("Submitted cross tx execution", "src", m.Source, "dst", m.Destination, "srcHash", inputHash, "mcsTx", mcsTx.Hash(), "nonce", mcsTx.Nonce())
+				err = w.txStatus(m, mcsTx)
 				if err != nil {
 					w.log.Warn("TxHash Status is not successful, will retry", "err", err)
 				} else {
+					w.mosResolve(inputHash)
 					m.DoneCh <- struct{}{}
 					return true
 				}
@@ -88,8 +93,8 @@ func (w *Writer) callContractWithMsg(addr common.Address, m msg.Message) bool {
 				}
 				w.log.Warn("Execution failed, will retry", "srcHash", inputHash, "err", err)
 			}
-			needNonce = w.needNonce(err)
 			errorCount++
+			metrics.Retry(w.cfg.Id, m.Type)
 			if errorCount >= 10 {
 				w.mosAlarm(m, inputHash, err)
 				errorCount = 0
@@ -102,7 +107,6 @@ func (w *Writer) callContractWithMsg(addr common.Address, m msg.Message) bool {
 func (w *Writer) merlinWithMsg(m msg.Message) bool {
 	var (
 		errorCount int64
-		needNonce  = true
 		addr       = w.cfg.McsContract[m.Idx]
 	)
 	for {
@@ -110,21 +114,22 @@ func (w *Writer) merlinWithMsg(m msg.Message) bool {
 		case <-w.stop:
 			return false
 		default:
-			err := w.conn.LockAndUpdateOpts(needNonce)
+			opts, err := w.conn.PrepareTx(w.ctx)
 			if err != nil {
-				w.log.Error("Failed to update nonce", "err", err)
+				w.log.Error("Failed to prepare tx", "err", err)
 				time.Sleep(constant.TxRetryInterval)
 				continue
 			}
 			var inputHash = m.Payload[3]
-			w.log.Info("Send transaction", "method", m.Payload[4], "srcHash", inputHash, "needNonce", needNonce, "nonce", w.conn.Opts().Nonce)
-			mcsTx, err := w.sendTx(&addr, nil, m.Payload[0].([]byte))
+			w.log.Info("Send transaction", "method", m.Payload[4], "srcHash", inputHash)
+			mcsTx, err := w.sendTx(opts, m, &addr, nil, m.Payload[0].([]byte))
 			if err == nil {
-				w.log.Info("Submitted cross tx execution", "src", m.Source, "dst", m.Destination, "srcHash", inputHash, "mcsTx", mcsTx.Hash())
-				err = w.txStatus(mcsTx.Hash())
+				w.log.Info("Submitted cross tx execution", "src", m.Source, "dst", m.Destination, "srcHash", inputHash, "mcsTx", mcsTx.Hash(), "nonce", mcsTx.Nonce())
+				err = w.txStatus(m, mcsTx)
 				if err != nil {
 					w.log.Warn("Store TxHash Status is not successful, will retry", "err", err)
 				} else {
+					w.mosResolve(inputHash)
 					m.DoneCh <- struct{}{}
 					return true
 				}
@@ -143,8 +148,8 @@ func (w *Writer) merlinWithMsg(m msg.Message) bool {
 				w.log.Warn("Execution SwapInVerify failed, will retry", "srcHash", inputHash, "err", err)
 			}
 
-			needNonce = w.needNonce(err)
 			errorCount++
+			metrics.Retry(w.cfg.Id, m.Type)
 			if errorCount >= 10 {
 				w.mosAlarm(m, inputHash, err)
 				errorCount = 0
@@ -155,13 +160,19 @@ func (w *Writer) merlinWithMsg(m msg.Message) bool {
 }
 
 func (w *Writer) mosAlarm(m msg.Message, tx interface{}, err error) {
-	util.Alarm(context.Background(), fmt.Sprintf("mos %s2%s failed, srcHash=%v err is %s", mapprotocol.OnlineChaId[m.Source],
-		mapprotocol.OnlineChaId[m.Destination], tx, err.Error()))
+	alert.Fire(context.Background(), alert.Critical, w.cfg.Name, fmt.Sprintf("%s/%v", alert.KeyMos, tx),
+		fmt.Sprintf("mos %s2%s failed, srcHash=%v err is %s", mapprotocol.OnlineChaId[m.Source],
+			mapprotocol.OnlineChaId[m.Destination], tx, err.Error()))
+}
+
+// mosResolve clears the alarm of the message of the source tx once it is executed
+func (w *Writer) mosResolve(tx interface{}) {
+	alert.Resolve(context.Background(), w.cfg.Name, fmt.Sprintf("%s/%v", alert.KeyMos, tx))
 }
 
 func (w *Writer) call(toAddress *common.Address, input []byte, useAbi abi.ABI, method string, ret interface{}) error {
 	from := w.conn.Keypair().Address
-	outPut, err := w.conn.Client().CallContract(context.Background(),
+	outPut, err := w.conn.Client().CallContract(w.ctx,
 		ethereum.CallMsg{
 			From: from,
 			To:   toAddress,
@@ -198,7 +209,7 @@ func (w *Writer) checkOrderId(toAddress *common.Address, input []byte, useAbi ab
 		return false, err
 	}
 	from := w.conn.Keypair().Address
-	outPut, err := w.conn.Client().CallContract(context.Background(),
+	outPut, err := w.conn.Client().CallContract(w.ctx,
 		ethereum.CallMsg{
 			From: from,
 			To:   toAddress,
@@ -224,17 +235,29 @@ func (w *Writer) checkOrderId(toAddress *common.Address, input []byte, useAbi ab
 	return exist, nil
 }
 
-func (w *Writer) txStatus(txHash common.Hash) error {
+func (w *Writer) txStatus(m msg.Message, tx *types.Transaction) (err error) {
+	txHash := tx.Hash()
+	defer func() {
+		w.keys.Done(txHash, err)
+		if err != constant.ErrTxPending {
+			w.forgetTx(txHash)
+		}
+	}()
+	if w.cfg.DryRun {
+		w.log.Info("Dry run, skip waiting for tx receipt", "tx", txHash)
+		return nil
+	}
+	start := time.Now()
 	var count int64
 	//time.Sleep(time.Second * 2)
 	for {
-		_, pending, err := w.conn.Client().TransactionByHash(context.Background(), txHash) // Query whether it is on the chain
+		_, pending, err := w.conn.Client().TransactionByHash(w.ctx, txHash) // Query whether it is on the chain
 		if pending {
 			w.log.Info("Tx is Pending, please wait...", "tx", txHash)
 			time.Sleep(w.queryInterval()) // todo suo xiao
 			count++
 			if count == 60 {
-				return errors.New("The Tx pending state is too long")
+				return constant.ErrTxPending
 			}
 			continue
 		}
@@ -251,7 +274,7 @@ func (w *Writer) txStatus(txHash common.Hash) error {
 	}
 	count = 0
 	for {
-		receipt, err := w.conn.Client().TransactionReceipt(context.Background(), txHash) // Query receipt after chaining
+		receipt, err := w.conn.Client().TransactionReceipt(w.ctx, txHash) // Query receipt after chaining
 		if err != nil {
 			if strings.Index(err.Error(), "not found") != -1 {
 				w.log.Info("Tx is temporary not found, please wait...", "tx", txHash)
@@ -267,12 +290,161 @@ func (w *Writer) txStatus(txHash common.Hash) error {
 
 		if receipt.Status == types.ReceiptStatusSuccessful {
 			w.log.Info("Tx receipt status is success", "hash", txHash)
+			if err = w.waitConfirmations(txHash, receipt); err != nil {
+				return err
+			}
+			w.recordCost(m, tx, receipt)
+			metrics.Confirmed(w.cfg.Id, time.Since(start))
+			w.auditReceipt(txHash, audit.StatusSuccess, "")
 			return nil
 		}
+		// a reverted tx still burns its gas
+		w.recordCost(m, tx, receipt)
+		w.auditReceipt(txHash, audit.StatusFailed, fmt.Sprintf("receipt status %d", receipt.Status))
 		return fmt.Errorf("txHash(%s), status not success, current status is (%d)", txHash, receipt.Status)
 	}
 }
 
+// recordCost records the gas spent by the mined tx of the message, reverted or not
+func (w *Writer) recordCost(m msg.Message, tx *types.Transaction, receipt *types.Receipt) {
+	price, err := w.conn.Client().EffectiveGasPrice(w.ctx, tx.Hash())
+	if err != nil {
+		var baseFee *big.Int
+		if tx.Type() == types.DynamicFeeTxType {
+			header, err := w.conn.Client().HeaderByNumber(w.ctx, receipt.BlockNumber)
+			if err != nil {
+				w.log.Warn("Get tx block base fee failed, the fee cap is recorded as the gas price", "tx", tx.Hash(), "err", err)
+			} else {
+				baseFee = header.BaseFee
+			}
+		}
+		price = paidGasPrice(tx, baseFee)
+	}
+	fee := new(big.Int).Mul(price, new(big.Int).SetUint64(receipt.GasUsed))
+	cost.Add(m, w.cfg.Id, tx.Hash().Hex(), receipt.GasUsed, price, fee, cost.EvmDecimals)
+}
+
+// paidGasPrice returns the gas price paid by tx in a block of baseFee, for nodes whose receipts lack it. Dynamic
+// fee txs pay the base fee plus their tip, up to their fee cap, which is returned if the base fee isn't known.
+func paidGasPrice(tx *types.Transaction, baseFee *big.Int) *big.Int {
+	if tx.Type() != types.DynamicFeeTxType {
+		return tx.GasPrice()
+	}
+	if baseFee == nil {
+		return tx.GasFeeCap()
+	}
+	price := new(big.Int).Add(baseFee, tx.GasTipCap())
+	if price.Cmp(tx.GasFeeCap()) > 0 {
+		return new(big.Int).Set(tx.GasFeeCap())
+	}
+	return price
+}
+
+// waitConfirmations waits until the block of the receipt is txConfirmations deep or finalized and checks
+// the receipt again. constant.ErrTxVanished is returned if a reorg dropped the tx, so the message is resubmitted.
+func (w *Writer) waitConfirmations(txHash common.Hash, receipt *types.Receipt) error {
+	if w.cfg.TxConfirmations == 0 && !w.cfg.TxFinalized {
+		return nil
+	}
+	for {
+		select {
+		case <-w.stop:
+			return errors.New("writer stopped before the tx was confirmed")
+		default:
+		}
+		if receipt == nil {
+			current, err := w.currentReceipt(txHash)
+			if err != nil {
+				return err
+			}
+			if current == nil {
+				w.log.Info("Tx was reorged back into the pool, please wait...", "tx", txHash)
+				time.Sleep(w.queryInterval())
+				continue
+			}
+			if current.Status != types.ReceiptStatusSuccessful {
+				return fmt.Errorf("txHash(%s), status not success after reorg, current status is (%d)", txHash, current.Status)
+			}
+			receipt = current
+		}
+
+		confirmed, err := w.isConfirmed(receipt.BlockNumber)
+		if err != nil {
+			w.log.Warn("Get tx confirmations failed, please wait...", "tx", txHash, "err", err)
+			time.Sleep(w.queryInterval())
+			continue
+		}
+		if !confirmed {
+			w.log.Info("Tx is waiting for confirmations, please wait...", "tx", txHash, "block", receipt.BlockNumber,
+				"txConfirmations", w.cfg.TxConfirmations, "finalized", w.cfg.TxFinalized)
+			time.Sleep(w.queryInterval())
+			continue
+		}
+
+		current, err := w.currentReceipt(txHash)
+		if err != nil {
+			return err
+		}
+		if current == nil || current.BlockHash != receipt.BlockHash {
+			w.log.Warn("Tx block was reorged, wait for it again", "tx", txHash, "block", receipt.BlockNumber, "blockHash", receipt.BlockHash)
+			receipt = nil
+			continue
+		}
+		w.log.Info("Tx is confirmed", "tx", txHash, "block", receipt.BlockNumber, "txConfirmations", w.cfg.TxConfirmations,
+			"finalized", w.cfg.TxFinalized)
+		return nil
+	}
+}
+
+// currentReceipt returns the receipt of the tx on the canonical chain, nil if the tx is back in the pool
+// and constant.ErrTxVanished if the node does not know the tx anymore
+func (w *Writer) currentReceipt(txHash common.Hash) (*types.Receipt, error) {
+	for {
+		receipt, err := w.conn.Client().TransactionReceipt(w.ctx, txHash)
+		if err == nil {
+			return receipt, nil
+		}
+		if strings.Index(err.Error(), "not found") == -1 {
+			w.log.Warn("Get tx receipt failed, please wait...", "tx", txHash, "err", err)
+			time.Sleep(w.queryInterval())
+			continue
+		}
+		_, pending, err := w.conn.Client().TransactionByHash(w.ctx, txHash)
+		if err == nil && pending {
+			return nil, nil
+		}
+		if err != nil && strings.Index(err.Error(), "not found") == -1 {
+			w.log.Warn("Get tx failed, please wait...", "tx", txHash, "err", err)
+			time.Sleep(w.queryInterval())
+			continue
+		}
+		if err == nil {
+			// mined in a block the node has not indexed the receipt of yet
+			time.Sleep(w.queryInterval())
+			continue
+		}
+		w.log.Warn("Tx vanished from the chain, will resubmit", "tx", txHash)
+		return nil, constant.ErrTxVanished
+	}
+}
+
+// isConfirmed reports whether the block is deep enough for the configured txConfirmations
+func (w *Writer) isConfirmed(block *big.Int) (bool, error) {
+	if w.cfg.TxFinalized {
+		finalized, err := w.conn.Client().FinalizedBlockNumber(w.ctx)
+		if err != nil {
+			return false, err
+		}
+		return finalized.Cmp(block) >= 0, nil
+	}
+	latest, err := w.conn.LatestBlock(w.ctx)
+	if err != nil {
+		return false, err
+	}
+	depth := new(big.Int).Sub(latest, block)
+	return depth.Sign() >= 0 && depth.Uint64() >= w.cfg.TxConfirmations, nil
+}
+
 func (w *Writer) queryInterval() time.Duration {
 	switch w.cfg.Id {
 	case 22776:
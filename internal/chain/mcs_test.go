@@ -0,0 +1,193 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ChainSafe/log15"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/mapprotocol/compass/core"
+	"github.com/mapprotocol/compass/internal/constant"
+	"github.com/mapprotocol/compass/pkg/ethclient"
+)
+
+func TestPaidGasPrice(t *testing.T) {
+	legacy := types.NewTx(&types.LegacyTx{GasPrice: big.NewInt(30)})
+	dynamic := types.NewTx(&types.DynamicFeeTx{GasTipCap: big.NewInt(2), GasFeeCap: big.NewInt(50)})
+	for _, tc := range []struct {
+		name    string
+		tx      *types.Transaction
+		baseFee *big.Int
+		want    int64
+	}{
+		{"legacy", legacy, big.NewInt(10), 30},
+		{"base fee plus tip", dynamic, big.NewInt(10), 12},
+		{"capped", dynamic, big.NewInt(49), 50},
+		{"unknown base fee", dynamic, nil, 50},
+	} {
+		if got := paidGasPrice(tc.tx, tc.baseFee); got.Int64() != tc.want {
+			t.Errorf("%s: got %s, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+// stubNode answers eth_getTransactionReceipt with its receipts in turn, the last one repeated, and the finalized
+// block of eth_getBlockByNumber. The tx itself is unknown.
+type stubNode struct {
+	*httptest.Server
+	lock      sync.Mutex
+	receipts  []*types.Receipt // nil for a receipt which is not found
+	finalized uint64
+	calls     map[string]int
+}
+
+func newStubNode(t *testing.T, finalized uint64, receipts ...*types.Receipt) *stubNode {
+	n := &stubNode{receipts: receipts, finalized: finalized, calls: make(map[string]int)}
+	n.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Id     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		n.lock.Lock()
+		defer n.lock.Unlock()
+		n.calls[req.Method]++
+		result := []byte("null")
+		switch req.Method {
+		case "eth_getTransactionReceipt":
+			receipt := n.receipts[0]
+			if len(n.receipts) > 1 {
+				n.receipts = n.receipts[1:]
+			}
+			if receipt != nil {
+				result, _ = json.Marshal(receipt)
+			}
+		case "eth_getBlockByNumber":
+			result = []byte(fmt.Sprintf(`{"number":"0x%x"}`, n.finalized))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.Id, result)
+	}))
+	t.Cleanup(n.Close)
+	return n
+}
+
+func (n *stubNode) callsOf(method string) int {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.calls[method]
+}
+
+// stubConn is a connection of a stub node at the latest block
+type stubConn struct {
+	core.Connection
+	client *ethclient.Client
+	latest int64
+}
+
+func (c *stubConn) Client() *ethclient.Client {
+	return c.client
+}
+
+func (c *stubConn) LatestBlock(context.Context) (*big.Int, error) {
+	return big.NewInt(c.latest), nil
+}
+
+func newConfirmWriter(t *testing.T, cfg Config, latest int64, node *stubNode) *Writer {
+	client, err := ethclient.Dial(node.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Writer{cfg: cfg, conn: &stubConn{client: client, latest: latest}, log: log15.Root(),
+		stop: make(chan int), ctx: context.Background()}
+}
+
+func receiptAt(block int64, blockHash string, status uint64) *types.Receipt {
+	return &types.Receipt{Status: status, Logs: []*types.Log{}, BlockNumber: big.NewInt(block),
+		BlockHash: common.HexToHash(blockHash)}
+}
+
+func TestWaitConfirmations(t *testing.T) {
+	txHash := common.HexToHash("0x01")
+	mined := receiptAt(10, "0xa", types.ReceiptStatusSuccessful)
+	for _, tc := range []struct {
+		name     string
+		cfg      Config
+		latest   int64
+		current  []*types.Receipt // receipts the node returns in turn
+		err      error
+		receipts int // receipt calls expected, -1 to skip the check
+	}{
+		{name: "depth reached", cfg: Config{TxConfirmations: 2}, latest: 12,
+			current: []*types.Receipt{mined}, receipts: 1},
+		{name: "moved to another block", cfg: Config{TxConfirmations: 2}, latest: 13,
+			current: []*types.Receipt{receiptAt(11, "0xb", types.ReceiptStatusSuccessful)}, receipts: 3},
+		{name: "reverted in another block", cfg: Config{TxConfirmations: 2}, latest: 13,
+			current: []*types.Receipt{receiptAt(11, "0xb", types.ReceiptStatusFailed)},
+			err:     errors.New("status not success after reorg"), receipts: -1},
+		{name: "gone after a reorg", cfg: Config{TxConfirmations: 2}, latest: 12,
+			current: []*types.Receipt{nil}, err: constant.ErrTxVanished, receipts: -1},
+		{name: "finalized", cfg: Config{TxFinalized: true}, latest: 10,
+			current: []*types.Receipt{mined}, receipts: 1},
+		{name: "disabled", cfg: Config{}, latest: 10, receipts: 0},
+	} {
+		if len(tc.current) == 0 {
+			tc.current = []*types.Receipt{nil}
+		}
+		node := newStubNode(t, 10, tc.current...)
+		w := newConfirmWriter(t, tc.cfg, tc.latest, node)
+		err := w.waitConfirmations(txHash, mined)
+		if tc.err == nil && err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		} else if tc.err != nil && (err == nil || !containsErr(err, tc.err)) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.err, err)
+		}
+		if got := node.callsOf("eth_getTransactionReceipt"); tc.receipts >= 0 && got != tc.receipts {
+			t.Errorf("%s: expected %d receipt calls, got %d", tc.name, tc.receipts, got)
+		}
+	}
+}
+
+func containsErr(err, want error) bool {
+	return errors.Is(err, want) || strings.Contains(err.Error(), want.Error())
+}
+
+func TestIsConfirmed(t *testing.T) {
+	node := newStubNode(t, 10, nil)
+	for _, tc := range []struct {
+		name  string
+		cfg   Config
+		block int64
+		want  bool
+	}{
+		{"deep enough", Config{TxConfirmations: 2}, 10, true},
+		{"too shallow", Config{TxConfirmations: 3}, 10, false},
+		{"ahead of the head", Config{TxConfirmations: 0}, 13, false},
+		{"finalized", Config{TxFinalized: true}, 10, true},
+		{"not finalized", Config{TxFinalized: true}, 11, false},
+	} {
+		w := newConfirmWriter(t, tc.cfg, 12, node)
+		got, err := w.isConfirmed(big.NewInt(tc.block))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+	if node.callsOf("eth_getBlockByNumber") != 2 {
+		t.Errorf("Expected the finalized block to be queried for finalized txs only")
+	}
+}
@@ -8,13 +8,15 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/mapprotocol/compass/mapprotocol"
 	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/health"
+	"github.com/mapprotocol/compass/pkg/metrics"
 	"math/big"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/mapprotocol/compass/internal/constant"
-	"github.com/mapprotocol/compass/pkg/util"
+	"github.com/mapprotocol/compass/pkg/alert"
 )
 
 type Messenger struct {
@@ -30,7 +32,9 @@ func NewMessenger(cs *CommonSync) *Messenger {
 func (m *Messenger) Sync() error {
 	m.Log.Debug("Starting listener...")
 	go func() {
+		health.Started(m.Cfg.Id)
 		err := m.sync()
+		health.Stopped(m.Cfg.Id, err)
 		if err != nil {
 			m.Log.Error("Polling blocks failed", "err", err)
 		}
@@ -45,6 +49,7 @@ func (m *Messenger) Sync() error {
 // However，an error in synchronizing the log will cause the entire program to block
 func (m *Messenger) sync() error {
 	if !m.Cfg.SyncToMap && m.Cfg.Id != m.Cfg.MapChainID {
+		health.Idle(m.Cfg.Id)
 		time.Sleep(time.Hour * 2400)
 		return nil
 	}
@@ -55,12 +60,16 @@ func (m *Messenger) sync() error {
 		case <-m.Stop:
 			return errors.New("polling terminated")
 		default:
-			latestBlock, err := m.Conn.LatestBlock()
+			if m.Disconnected() {
+				continue
+			}
+			latestBlock, err := m.Conn.LatestBlock(m.Ctx)
 			if err != nil {
 				m.Log.Error("Unable to get latest block", "block", currentBlock, "err", err)
 				time.Sleep(constant.RetryLongInterval)
 				continue
 			}
+			metrics.Head(m.Cfg.Id, latestBlock)
 
 			// Sleep if the difference is less than BlockDelay; (latest - current) < BlockDelay
 			if big.NewInt(0).Sub(latestBlock, currentBlock).Cmp(m.BlockConfirmations) == -1 {
@@ -68,6 +77,9 @@ func (m *Messenger) sync() error {
 				time.Sleep(constant.BalanceRetryInterval)
 				continue
 			}
+			if m.QuorumPaused(currentBlock, m.Cfg.McsContract) {
+				continue
+			}
 			count, err := m.mosHandler(m, currentBlock)
 			if err != nil {
 				if errors.Is(err, NotVerifyAble) {
@@ -77,7 +89,8 @@ func (m *Messenger) sync() error {
 				}
 				m.Log.Error("Failed to get events for block", "block", currentBlock, "err", err)
 				time.Sleep(constant.BlockRetryInterval)
-				util.Alarm(context.Background(), fmt.Sprintf("mos failed, chain=%s, err is %s", m.Cfg.Name, err.Error()))
+				alert.Fire(context.Background(), alert.Warning, m.Cfg.Name, alert.KeyListener,
+					fmt.Sprintf("mos failed on block %s, err is %s", currentBlock, err.Error()))
 				continue
 			}
 
@@ -88,6 +101,9 @@ func (m *Messenger) sync() error {
 			if err != nil {
 				m.Log.Error("Failed to write latest block to blockstore", "block", currentBlock, "err", err)
 			}
+			metrics.Processed(m.Cfg.Id, currentBlock)
+			health.Processed(m.Cfg.Id)
+			alert.Resolve(context.Background(), m.Cfg.Name, alert.KeyListener)
 
 			currentBlock.Add(currentBlock, big.NewInt(1))
 			if latestBlock.Int64()-currentBlock.Int64() <= m.Cfg.BlockConfirmations.Int64() {
@@ -101,7 +117,7 @@ func defaultMosHandler(m *Messenger, blockNumber *big.Int) (int, error) {
 	count := 0
 	for idx, addr := range m.Cfg.McsContract {
 		query := m.BuildQuery(addr, m.Cfg.Events, blockNumber, blockNumber)
-		logs, err := m.Conn.Client().FilterLogs(context.Background(), query)
+		logs, err := m.FilterLogs(context.Background(), query)
 		if err != nil {
 			return 0, fmt.Errorf("unable to Filter Logs: %w", err)
 		}
@@ -12,7 +12,9 @@ import (
 	"github.com/mapprotocol/compass/internal/tx"
 	"github.com/mapprotocol/compass/mapprotocol"
 	"github.com/mapprotocol/compass/msg"
-	"github.com/mapprotocol/compass/pkg/util"
+	"github.com/mapprotocol/compass/pkg/alert"
+	"github.com/mapprotocol/compass/pkg/health"
+	"github.com/mapprotocol/compass/pkg/metrics"
 	"github.com/pkg/errors"
 	"math/big"
 	"time"
@@ -31,7 +33,9 @@ func NewOracle(cs *CommonSync) *Oracle {
 func (m *Oracle) Sync() error {
 	m.Log.Debug("Starting listener...")
 	go func() {
+		health.Started(m.Cfg.Id)
 		err := m.sync()
+		health.Stopped(m.Cfg.Id, err)
 		if err != nil {
 			m.Log.Error("Polling blocks failed", "err", err)
 		}
@@ -42,6 +46,7 @@ func (m *Oracle) Sync() error {
 
 func (m *Oracle) sync() error {
 	if !m.Cfg.SyncToMap && m.Cfg.Id != m.Cfg.MapChainID {
+		health.Idle(m.Cfg.Id)
 		time.Sleep(time.Hour * 2400)
 		return nil
 	}
@@ -52,12 +57,16 @@ func (m *Oracle) sync() error {
 		case <-m.Stop:
 			return errors.New("polling terminated")
 		default:
-			latestBlock, err := m.Conn.LatestBlock()
+			if m.Disconnected() {
+				continue
+			}
+			latestBlock, err := m.Conn.LatestBlock(m.Ctx)
 			if err != nil {
 				m.Log.Error("Unable to get latest block", "block", currentBlock, "err", err)
 				time.Sleep(constant.RetryLongInterval)
 				continue
 			}
+			metrics.Head(m.Cfg.Id, latestBlock)
 
 			if big.NewInt(0).Sub(latestBlock, currentBlock).Cmp(m.BlockConfirmations) == -1 {
 				m.Log.Debug("Block not ready, will retry", "currentBlock", currentBlock, "latest", latestBlock)
@@ -65,11 +74,15 @@ func (m *Oracle) sync() error {
 				continue
 			}
 
+			if m.QuorumPaused(currentBlock, []common.Address{m.Cfg.OracleNode}) {
+				continue
+			}
 			err = m.oracleHandler(m, currentBlock)
 			if err != nil {
 				m.Log.Error("Failed to get events for block", "block", currentBlock, "err", err)
 				time.Sleep(constant.BlockRetryInterval)
-				util.Alarm(context.Background(), fmt.Sprintf("mos failed, chain=%s, err is %s", m.Cfg.Name, err.Error()))
+				alert.Fire(context.Background(), alert.Warning, m.Cfg.Name, alert.KeyListener,
+					fmt.Sprintf("mos failed on block %s, err is %s", currentBlock, err.Error()))
 				continue
 			}
 
@@ -77,6 +90,9 @@ func (m *Oracle) sync() error {
 			if err != nil {
 				m.Log.Error("Failed to write latest block to blockstore", "block", currentBlock, "err", err)
 			}
+			metrics.Processed(m.Cfg.Id, currentBlock)
+			health.Processed(m.Cfg.Id)
+			alert.Resolve(context.Background(), m.Cfg.Name, alert.KeyListener)
 
 			currentBlock.Add(currentBlock, big.NewInt(1))
 			if latestBlock.Int64()-currentBlock.Int64() <= m.Cfg.BlockConfirmations.Int64() {
@@ -91,7 +107,7 @@ func DefaultOracleHandler(m *Oracle, latestBlock *big.Int) error {
 	count := 0
 	query := m.BuildQuery(m.Cfg.OracleNode, m.Cfg.Events, latestBlock, latestBlock)
 	// querying for logs
-	logs, err := m.Conn.Client().FilterLogs(context.Background(), query)
+	logs, err := m.FilterLogs(context.Background(), query)
 	if err != nil {
 		return fmt.Errorf("oracle unable to Filter Logs: %w", err)
 	}
@@ -107,7 +123,7 @@ func DefaultOracleHandler(m *Oracle, latestBlock *big.Int) error {
 		if err != nil {
 			return fmt.Errorf("unable to get tx hashes Logs: %w", err)
 		}
-		receipts, err := tx.GetReceiptsByTxsHash(m.Conn.Client(), txsHash)
+		receipts, err := tx.GetReceiptsByBlockNumber(m.Conn.Client(), latestBlock, txsHash)
 		if err != nil {
 			return fmt.Errorf("unable to get receipts hashes Logs: %w", err)
 		}
@@ -115,6 +131,8 @@ func DefaultOracleHandler(m *Oracle, latestBlock *big.Int) error {
 		tr = proof.DeriveTire(types.Receipts(receipts), tr)
 		m.Log.Info("oracle merlin receipt", "blockNumber", latestBlock, "hash", tr.Hash())
 		header.ReceiptHash = tr.Hash()
+	} else if err = m.CheckReceiptsRoot(latestBlock, header.ReceiptHash); err != nil {
+		return err
 	}
 	m.Log.Info("Find log", "block", latestBlock, "logs", len(logs))
 	var input []byte
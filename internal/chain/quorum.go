@@ -0,0 +1,123 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"time"
+
+	eth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/mapprotocol/compass/core"
+	"github.com/mapprotocol/compass/internal/constant"
+	"github.com/mapprotocol/compass/pkg/alert"
+	"github.com/mapprotocol/compass/pkg/ethclient"
+	"github.com/mapprotocol/compass/pkg/quorum"
+)
+
+var errNoQuorum = errors.New("connection does not support quorum reads")
+
+// agreedBlock is what the quorum of endpoints agreed on for the block being processed
+type agreedBlock struct {
+	number *big.Int
+	roots  *ethclient.BlockRoots
+	logs   map[common.Address]agreedLogs
+}
+
+type agreedLogs struct {
+	topics [][]common.Hash
+	logs   []types.Log
+}
+
+// QuorumPaused checks the block against the quorum of endpoints before it is used to build proofs or is synced.
+// Every endpoint must agree on the block hash and receipts root, and on the logs of contracts. It returns true if
+// the block must not be processed yet, in which case the caller retries it. Disagreements raise an alarm and pause
+// the chain for constant.QuorumPauseInterval. The agreed logs and roots are kept for FilterLogs and CheckReceiptsRoot.
+func (c *CommonSync) QuorumPaused(block *big.Int, contracts []common.Address) bool {
+	c.agreed = nil
+	if c.Cfg.Quorum == 0 {
+		return false
+	}
+	qc, ok := c.Conn.(core.QuorumConnection)
+	if !ok {
+		// rejected by the config, the block is never processed unchecked
+		c.Log.Error("Connection does not support quorum reads, pause the chain", "block", block, "pause", constant.QuorumPauseInterval)
+		time.Sleep(constant.QuorumPauseInterval)
+		return true
+	}
+	agreed, err := c.checkQuorum(qc.QuorumEndpoints(), block, contracts)
+	if err == nil {
+		c.agreed = agreed
+		alert.Resolve(context.Background(), c.Cfg.Name, alert.KeyQuorum)
+		return false
+	}
+	if errors.Is(err, quorum.ErrDisagree) {
+		c.Log.Error("Endpoints disagree on block, pause the chain", "block", block, "pause", constant.QuorumPauseInterval, "err", err)
+		alert.Fire(context.Background(), alert.Critical, c.Cfg.Name, alert.KeyQuorum,
+			fmt.Sprintf("%s endpoints disagree on block %s, chain paused, err is %s", c.Cfg.Name, block, err))
+		time.Sleep(constant.QuorumPauseInterval)
+		return true
+	}
+	c.Log.Warn("Quorum read failed, will retry", "block", block, "err", err)
+	time.Sleep(constant.BlockRetryInterval)
+	return true
+}
+
+func (c *CommonSync) checkQuorum(endpoints []quorum.Endpoint, block *big.Int, contracts []common.Address) (*agreedBlock, error) {
+	ctx := context.Background()
+	roots, err := quorum.Header(ctx, c.Cfg.Quorum, endpoints, block)
+	if err != nil {
+		return nil, err
+	}
+	agreed := &agreedBlock{number: new(big.Int).Set(block), roots: roots, logs: make(map[common.Address]agreedLogs)}
+	for _, addr := range contracts {
+		query := c.BuildQuery(addr, c.Cfg.Events, block, block)
+		logs, err := quorum.Logs(ctx, c.Cfg.Quorum, endpoints, query)
+		if err != nil {
+			return nil, err
+		}
+		agreed.logs[addr] = agreedLogs{topics: query.Topics, logs: logs}
+	}
+	return agreed, nil
+}
+
+// FilterLogs returns the logs the quorum agreed on when q is the query of a contract checked by QuorumPaused for the
+// block being processed, otherwise it runs q on Conn
+func (c *CommonSync) FilterLogs(ctx context.Context, q eth.FilterQuery) ([]types.Log, error) {
+	if a := c.agreed; a != nil && q.BlockHash == nil && len(q.Addresses) == 1 && q.FromBlock != nil && q.ToBlock != nil &&
+		q.FromBlock.Cmp(a.number) == 0 && q.ToBlock.Cmp(a.number) == 0 {
+		if l, ok := a.logs[q.Addresses[0]]; ok && reflect.DeepEqual(l.topics, q.Topics) {
+			return l.logs, nil
+		}
+	}
+	return c.Conn.Client().FilterLogs(ctx, q)
+}
+
+// CheckReceiptsRoot checks the receipts root of the header a proof is built on against the one the quorum agreed
+// on, the root of other blocks is read from the quorum. Only the receipts root is compared: proof.Verify ties the
+// receipts to it, while the header hash of chains with non-standard headers can not be recomputed.
+func (c *CommonSync) CheckReceiptsRoot(number *big.Int, root common.Hash) error {
+	if c.Cfg.Quorum == 0 {
+		return nil
+	}
+	var roots *ethclient.BlockRoots
+	if c.agreed != nil && c.agreed.number.Cmp(number) == 0 {
+		roots = c.agreed.roots
+	} else {
+		qc, ok := c.Conn.(core.QuorumConnection)
+		if !ok {
+			return errNoQuorum
+		}
+		var err error
+		if roots, err = quorum.Header(context.Background(), c.Cfg.Quorum, qc.QuorumEndpoints(), number); err != nil {
+			return err
+		}
+	}
+	if roots.ReceiptsRoot != root {
+		return fmt.Errorf("%w: block %s has receipts root %s, the quorum agreed on %s", quorum.ErrDisagree, number, root, roots.ReceiptsRoot)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package chain
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestCheckReceiptsRootWithoutQuorumConnection(t *testing.T) {
+	c := &CommonSync{Cfg: Config{Quorum: 2}, Conn: &stubConn{}}
+	if err := c.CheckReceiptsRoot(big.NewInt(1), common.Hash{}); !errors.Is(err, errNoQuorum) {
+		t.Fatalf("Expected the check to fail without quorum reads, got %v", err)
+	}
+	c.Cfg.Quorum = 0
+	if err := c.CheckReceiptsRoot(big.NewInt(1), common.Hash{}); err != nil {
+		t.Fatalf("Expected no check without quorum, got %v", err)
+	}
+}
@@ -2,42 +2,69 @@ package chain
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 	"strings"
 
 	"github.com/mapprotocol/compass/core"
+	"github.com/mapprotocol/compass/pkg/alert"
+	"github.com/mapprotocol/compass/pkg/journal"
+	"github.com/mapprotocol/compass/pkg/signer"
 
 	"github.com/mapprotocol/compass/internal/constant"
 
 	"github.com/ChainSafe/log15"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/mapprotocol/compass/msg"
 )
 
 type Writer struct {
-	cfg    Config
-	conn   core.Connection
-	log    log15.Logger
-	stop   <-chan int
-	sysErr chan<- error // Reports fatal error to core
+	cfg     Config
+	conn    core.Connection
+	keys    *KeyPool
+	journal *journal.Journal
+	log     log15.Logger
+	stop    <-chan int
+	ctx     context.Context // canceled when stop is closed
+	cancel  context.CancelFunc
+	sysErr  chan<- error // Reports fatal error to core
 }
 
-// NewWriter creates and returns Writer
-func NewWriter(conn core.Connection, cfg *Config, log log15.Logger, stop <-chan int, sysErr chan<- error) *Writer {
+// NewWriter creates and returns Writer, keys sign for the relayer addresses, the key of conn is used if empty.
+// Every tx is checked against the signing policy of cfg before it is signed, and recorded in jn before it is broadcast.
+func NewWriter(conn core.Connection, cfg *Config, keys []signer.Signer, jn *journal.Journal, log log15.Logger,
+	stop <-chan int, sysErr chan<- error) *Writer {
+	if len(keys) == 0 && conn.Keypair() != nil {
+		keys = []signer.Signer{signer.NewLocal(conn.Keypair())}
+	}
+	if cfg.Policy != nil {
+		guarded := make([]signer.Signer, 0, len(keys))
+		for _, k := range keys {
+			guarded = append(guarded, signer.NewGuard(k, cfg.Policy))
+		}
+		keys = guarded
+	}
+	ctx, cancel := stopContext(stop)
 	return &Writer{
-		cfg:    *cfg,
-		conn:   conn,
-		log:    log,
-		stop:   stop,
-		sysErr: sysErr,
+		cfg:     *cfg,
+		conn:    conn,
+		keys:    NewKeyPool(keys, cfg.KeyStrategy, cfg.DedicatedKeys),
+		journal: jn,
+		log:     log,
+		stop:    stop,
+		ctx:     ctx,
+		cancel:  cancel,
+		sysErr:  sysErr,
 	}
 }
 
-func (w *Writer) start() error {
+// Start reconciles the pending tx journal, it must be called before the listener starts to resubmit messages
+func (w *Writer) Start() error {
 	w.log.Debug("Starting Writer...")
-	return nil
+	return w.reconcile()
 }
 
 // ResolveMessage handles any given message based on type
@@ -62,27 +89,53 @@ func (w *Writer) ResolveMessage(m msg.Message) bool {
 	}
 }
 
-// sendTx send tx to an address with value and input data
-func (w *Writer) sendTx(toAddress *common.Address, value *big.Int, input []byte) (*types.Transaction, error) {
-	gasPrice := w.conn.Opts().GasPrice
-	nonce := w.conn.Opts().Nonce
-	from := w.conn.Keypair().Address
+// sendTx send tx to an address with value and input data, signed by a relayer key picked for the message type.
+// The gas settings are taken from opts, the nonce from the key pool.
+func (w *Writer) sendTx(opts *core.TxOpts, m msg.Message, toAddress *common.Address, value *big.Int, input []byte) (*types.Transaction, error) {
+	identity := journal.Identity(string(m.Type), toAddress.Hex(), input)
+	if tx, ok := w.resumeTx(identity); ok {
+		return tx, nil
+	}
+
+	key, err := w.keys.Acquire(m.Type)
+	if err != nil {
+		w.log.Error("Pick relayer key failed", "type", m.Type, "keys", w.keys.Len(), "error:", err.Error())
+		return nil, err
+	}
+	if err = w.checkBalance(key); err != nil {
+		w.keys.Return(key)
+		return nil, err
+	}
+
+	gasPrice := opts.GasPrice
+	from := key.Address()
 
-	msg := ethereum.CallMsg{
+	callMsg := ethereum.CallMsg{
 		From:     from,
 		To:       toAddress,
 		GasPrice: gasPrice,
 		Value:    value,
 		Data:     input,
 	}
-	gasLimit, err := w.conn.Client().EstimateGas(context.Background(), msg)
+	gasLimit, err := w.conn.Client().EstimateGas(w.ctx, callMsg)
 	if err != nil {
 		w.log.Error("EstimateGas failed sendTx", "error:", err.Error())
+		w.keys.Return(key)
+		return nil, err
+	}
+
+	n, err := w.keys.Nonce(key, func(addr common.Address) (uint64, error) {
+		return w.conn.Client().PendingNonceAt(w.ctx, addr)
+	})
+	if err != nil {
+		w.log.Error("Get relayer nonce failed", "from", from, "error:", err.Error())
+		w.keys.Return(key)
 		return nil, err
 	}
+	nonce := new(big.Int).SetUint64(n)
 
-	gasTipCap := w.conn.Opts().GasTipCap
-	gasFeeCap := w.conn.Opts().GasFeeCap
+	gasTipCap := opts.GasTipCap
+	gasFeeCap := opts.GasFeeCap
 	if w.cfg.LimitMultiplier > 1 {
 		gasLimit = uint64(float64(gasLimit) * w.cfg.LimitMultiplier)
 	}
@@ -90,7 +143,7 @@ func (w *Writer) sendTx(toAddress *common.Address, value *big.Int, input []byte)
 		gasTipCap = big.NewInt(int64(float64(gasTipCap.Uint64()) * w.cfg.GasMultiplier))
 		gasFeeCap = big.NewInt(int64(float64(gasFeeCap.Uint64()) * w.cfg.GasMultiplier))
 	}
-	w.log.Info("SendTx gasPrice", "gasPrice", gasPrice, "gasTipCap", gasTipCap, "gasFeeCap", gasFeeCap, "gasLimit", gasLimit,
+	w.log.Info("SendTx gasPrice", "from", from, "gasPrice", gasPrice, "gasTipCap", gasTipCap, "gasFeeCap", gasFeeCap, "gasLimit", gasLimit,
 		"limitMultiplier", w.cfg.LimitMultiplier, "gasMultiplier", w.cfg.GasMultiplier, "nonce", nonce.Uint64())
 	// td interface
 	var td types.TxData
@@ -120,26 +173,87 @@ func (w *Writer) sendTx(toAddress *common.Address, value *big.Int, input []byte)
 
 	tx := types.NewTx(td)
 	chainID := big.NewInt(int64(w.cfg.Id))
-	privateKey := w.conn.Keypair().PrivateKey
-
-	signedTx, err := types.SignTx(tx, types.NewLondonSigner(chainID), privateKey)
+	signedTx, err := key.signer.SignTx(w.ctx, tx, chainID)
 	if err != nil {
 		w.log.Error("SignTx failed", "error:", err.Error())
+		w.keys.Release(key, n, err)
 		return nil, err
 	}
+	if err = w.auditTx(m, from, signedTx); err != nil {
+		w.log.Error("Audit tx failed", "tx", signedTx.Hash(), "error:", err.Error())
+		w.keys.Release(key, n, nil)
+		return nil, err
+	}
+
+	if w.cfg.DryRun {
+		w.simulateTx(from, signedTx)
+		w.keys.Sent(key, signedTx.Hash())
+		return signedTx, nil
+	}
 
-	err = w.conn.Client().SendTransaction(context.Background(), signedTx)
+	if err = w.journalTx(identity, from, signedTx); err != nil {
+		w.log.Error("Journal tx failed", "tx", signedTx.Hash(), "error:", err.Error())
+		w.keys.Release(key, n, nil)
+		return nil, err
+	}
+	err = w.conn.Client().SendTransaction(w.ctx, signedTx)
 	if err != nil {
-		w.log.Error("SendTransaction failed", "error:", err.Error())
+		w.log.Error("SendTransaction failed", "from", from, "error:", err.Error())
+		w.forgetTx(signedTx.Hash())
+		if strings.Contains(err.Error(), "insufficient funds") && w.keys.Len() > 1 {
+			w.log.Warn("Relayer key out of funds, take it out of rotation", "from", from)
+			w.keys.Disable(key, constant.BalanceRetryInterval)
+		}
+		w.keys.Release(key, n, err)
 		return nil, err
 	}
+	w.keys.Sent(key, signedTx.Hash())
 	return signedTx, nil
 }
 
-func (w *Writer) needNonce(err error) bool {
-	if err == nil || err.Error() == constant.ErrNonceTooLow.Error() || strings.Index(err.Error(), "nonce too low") != -1 {
-		return true
+// checkBalance takes the key out of rotation if its balance is lower than the configured minimum
+func (w *Writer) checkBalance(key *relayerKey) error {
+	if w.cfg.MinBalance == nil || w.cfg.MinBalance.Sign() <= 0 || !w.keys.NeedBalanceCheck(key) {
+		return nil
 	}
+	balance, err := w.conn.Client().BalanceAt(w.ctx, key.Address(), nil)
+	if err != nil {
+		w.log.Warn("Get relayer balance failed", "from", key.Address(), "err", err)
+		return nil
+	}
+	w.keys.SetBalance(key, balance)
+	alertKey := fmt.Sprintf("%s/%s", alert.KeyBalance, key.Address())
+	if balance.Cmp(w.cfg.MinBalance) >= 0 {
+		alert.Resolve(context.Background(), w.cfg.Name, alertKey)
+		return nil
+	}
+	w.keys.Disable(key, constant.BalanceRetryInterval)
+	alert.Fire(context.Background(), alert.Warning, w.cfg.Name, alertKey, fmt.Sprintf(
+		"%s relayer %s balance %s is lower than %s, take it out of rotation", w.cfg.Name, key.Address(), balance, w.cfg.MinBalance))
+	return fmt.Errorf("relayer %s balance %s is lower than %s", key.Address(), balance, w.cfg.MinBalance)
+}
 
-	return false
+// simulateTx runs the signed tx through eth_call instead of broadcasting it, only used in dry run mode
+func (w *Writer) simulateTx(from common.Address, signedTx *types.Transaction) {
+	raw, err := signedTx.MarshalBinary()
+	if err != nil {
+		w.log.Warn("Dry run marshal tx failed", "tx", signedTx.Hash(), "err", err)
+	}
+	ret, err := w.conn.Client().CallContract(context.Background(), ethereum.CallMsg{
+		From:      from,
+		To:        signedTx.To(),
+		Gas:       signedTx.Gas(),
+		GasPrice:  signedTx.GasPrice(),
+		GasFeeCap: signedTx.GasFeeCap(),
+		GasTipCap: signedTx.GasTipCap(),
+		Value:     signedTx.Value(),
+		Data:      signedTx.Data(),
+	}, nil)
+	if err != nil {
+		w.log.Warn("Dry run simulate tx failed", "tx", signedTx.Hash(), "to", signedTx.To(), "nonce", signedTx.Nonce(),
+			"gasLimit", signedTx.Gas(), "input", hexutil.Encode(signedTx.Data()), "err", err)
+		return
+	}
+	w.log.Info("Dry run, tx simulated and not broadcast", "tx", signedTx.Hash(), "to", signedTx.To(), "nonce", signedTx.Nonce(),
+		"gasLimit", signedTx.Gas(), "input", hexutil.Encode(signedTx.Data()), "raw", hexutil.Encode(raw), "result", hexutil.Encode(ret))
 }
@@ -21,6 +21,8 @@ import (
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/mapprotocol/compass/internal/conflux/types"
+	"github.com/mapprotocol/compass/pkg/ethclient"
+	"github.com/mapprotocol/compass/pkg/instrument"
 	"github.com/pkg/errors"
 )
 
@@ -42,7 +44,7 @@ type Client struct {
 	idCounter uint32
 }
 
-func NewClient(endpoint string) (*Client, error) {
+func NewClient(chain, endpoint string) (*Client, error) {
 	// Sanity chck URL so we don't end up with a client that will fail every request.
 	_, err := url.Parse(endpoint)
 	if err != nil {
@@ -53,7 +55,7 @@ func NewClient(endpoint string) (*Client, error) {
 	headers.Set("accept", contentType)
 	headers.Set("content-type", contentType)
 	return &Client{
-		client:    new(http.Client),
+		client:    ethclient.NewHTTPClient(chain, endpoint, 0),
 		url:       endpoint,
 		closeOnce: sync.Once{},
 		closch:    make(chan interface{}),
@@ -195,6 +197,13 @@ func (op *requestOp) wait(ctx context.Context) (*jsonrpcMessage, error) {
 }
 
 func (c *Client) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	done := instrument.Start("conflux", instrument.Endpoint(c.url), method, args...)
+	err := c.callContext(ctx, result, method, args...)
+	done(err)
+	return err
+}
+
+func (c *Client) callContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
 	if result != nil && reflect.TypeOf(result).Kind() != reflect.Ptr {
 		return fmt.Errorf("call result parameter must be pointer or nil interface: %v", result)
 	}
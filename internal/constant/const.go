@@ -14,6 +14,8 @@ const (
 var (
 	ErrNonceTooLow  = errors.New("nonce too low")
 	ErrUnWantedSync = errors.New("unwanted Sync")
+	ErrTxPending    = errors.New("The Tx pending state is too long")
+	ErrTxVanished   = errors.New("tx vanished from the chain before it was confirmed")
 )
 
 var (
@@ -33,6 +35,11 @@ var (
 
 var (
 	BalanceRetryInterval = time.Second * 60
+	QuorumPauseInterval  = time.Second * 60 // Time a chain pauses after its endpoints disagree on a block
+)
+
+var (
+	JournalEntryTTL = time.Hour * 24 // Journaled txs older than this are dropped on startup
 )
 
 var IgnoreError = map[string]struct{}{
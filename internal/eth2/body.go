@@ -0,0 +1,171 @@
+package eth2
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// hash tree roots of the beacon block body types, as defined by the consensus specs
+
+func (h *hasher) eth1Data(d *Eth1Data) [32]byte {
+	return container(
+		h.root("deposit_root", d.DepositRoot),
+		h.uint64("deposit_count", d.DepositCount),
+		h.root("block_hash", d.BlockHash),
+	)
+}
+
+func (h *hasher) signedHeader(s *Header) [32]byte {
+	header := container(
+		h.uint64("slot", s.Message.Slot),
+		h.uint64("proposer_index", s.Message.ProposerIndex),
+		h.root("parent_root", s.Message.ParentRoot),
+		h.root("state_root", s.Message.StateRoot),
+		h.root("body_root", s.Message.BodyRoot),
+	)
+	return container(header, h.vector("signature", s.Signature, 96))
+}
+
+func (h *hasher) proposerSlashings(ss []ProposerSlashing) [32]byte {
+	roots := make([][32]byte, 0, len(ss))
+	for i := range ss {
+		roots = append(roots, container(h.signedHeader(&ss[i].SignedHeader1), h.signedHeader(&ss[i].SignedHeader2)))
+	}
+	return list(roots, maxProposerSlashings)
+}
+
+func (h *hasher) checkpoint(c *Source) [32]byte {
+	return container(h.uint64("epoch", c.Epoch), h.root("root", c.Root))
+}
+
+func (h *hasher) attestationData(d *AttestationData) [32]byte {
+	source, target := Source(d.Source), Source(d.Target)
+	return container(
+		h.uint64("slot", d.Slot),
+		h.uint64("index", d.Index),
+		h.root("beacon_block_root", d.BeaconBlockRoot),
+		h.checkpoint(&source),
+		h.checkpoint(&target),
+	)
+}
+
+func (h *hasher) indexedAttestation(a *IndexedAttestation) [32]byte {
+	return container(
+		h.uint64List("attesting_indices", a.AttestingIndices, maxValidatorsPerCommittee),
+		h.attestationData(&a.Data),
+		h.vector("signature", a.Signature, 96),
+	)
+}
+
+func (h *hasher) attesterSlashings(ss []AttesterSlashing) [32]byte {
+	roots := make([][32]byte, 0, len(ss))
+	for i := range ss {
+		roots = append(roots, container(h.indexedAttestation(&ss[i].Attestation1), h.indexedAttestation(&ss[i].Attestation2)))
+	}
+	return list(roots, maxAttesterSlashings)
+}
+
+func (h *hasher) attestations(as []Attestations) [32]byte {
+	roots := make([][32]byte, 0, len(as))
+	for i := range as {
+		roots = append(roots, container(
+			h.bitlist("aggregation_bits", as[i].AggregationBits, maxValidatorsPerCommittee),
+			h.attestationData(&as[i].Data),
+			h.vector("signature", as[i].Signature, 96),
+		))
+	}
+	return list(roots, maxAttestations)
+}
+
+func (h *hasher) deposits(ds []Deposit) [32]byte {
+	roots := make([][32]byte, 0, len(ds))
+	for i := range ds {
+		if h.err == nil && len(ds[i].Proof) != depositProofLength {
+			h.err = fmt.Errorf("invalid deposit proof length %d, want %d", len(ds[i].Proof), depositProofLength)
+		}
+		proof := make([][32]byte, 0, len(ds[i].Proof))
+		for _, p := range ds[i].Proof {
+			proof = append(proof, h.root("deposit proof", p))
+		}
+		data := container(
+			h.vector("pubkey", ds[i].Data.Pubkey, 48),
+			h.root("withdrawal_credentials", ds[i].Data.WithdrawalCredentials),
+			h.uint64("amount", ds[i].Data.Amount),
+			h.vector("signature", ds[i].Data.Signature, 96),
+		)
+		roots = append(roots, container(merkleize(proof, depositProofLength), data))
+	}
+	return list(roots, maxDeposits)
+}
+
+func (h *hasher) voluntaryExits(es []SignedVoluntaryExit) [32]byte {
+	roots := make([][32]byte, 0, len(es))
+	for i := range es {
+		exit := container(h.uint64("epoch", es[i].Message.Epoch), h.uint64("validator_index", es[i].Message.ValidatorIndex))
+		roots = append(roots, container(exit, h.vector("signature", es[i].Signature, 96)))
+	}
+	return list(roots, maxVoluntaryExits)
+}
+
+func (h *hasher) syncAggregate(s *SyncAggregate) [32]byte {
+	return container(
+		h.vector("sync_committee_bits", s.SyncCommitteeBits, syncCommitteeSize/8),
+		h.vector("sync_committee_signature", s.SyncCommitteeSignature, 96),
+	)
+}
+
+func (h *hasher) blsToExecutionChanges(cs []SignedBLSToExecutionChange) [32]byte {
+	roots := make([][32]byte, 0, len(cs))
+	for i := range cs {
+		change := container(
+			h.uint64("validator_index", cs[i].Message.ValidatorIndex),
+			h.vector("from_bls_pubkey", cs[i].Message.FromBlsPubkey, 48),
+			h.vector("to_execution_address", cs[i].Message.ToExecutionAddress, 20),
+		)
+		roots = append(roots, container(change, h.vector("signature", cs[i].Signature, 96)))
+	}
+	return list(roots, maxBlsToExecutionChanges)
+}
+
+// executionPayload returns the root of the payload, and the roots of its transactions and withdrawals
+func (h *hasher) executionPayload(e *Execution, version string) ([32]byte, common.Hash, common.Hash) {
+	txs := make([][32]byte, 0, len(e.Transactions))
+	for _, tx := range e.Transactions {
+		txs = append(txs, h.byteList("transaction", tx, maxBytesPerTransaction))
+	}
+	txRoot := list(txs, maxTransactionsPerPayload)
+
+	wds := make([][32]byte, 0, len(e.Withdrawals))
+	for _, w := range e.Withdrawals {
+		wds = append(wds, container(
+			h.uint64("withdrawal index", w.Index),
+			h.uint64("validator_index", w.ValidatorIndex),
+			h.vector("address", w.Address, 20),
+			h.uint64("amount", w.Amount),
+		))
+	}
+	wdRoot := list(wds, maxWithdrawalsPerPayload)
+
+	fields := [][32]byte{
+		h.root("parent_hash", e.ParentHash),
+		h.vector("fee_recipient", e.FeeRecipient, 20),
+		h.root("state_root", e.StateRoot),
+		h.root("receipts_root", e.ReceiptsRoot),
+		h.vector("logs_bloom", e.LogsBloom, bytesPerLogsBloom),
+		h.root("prev_randao", e.PrevRandao),
+		h.uint64("block_number", e.BlockNumber),
+		h.uint64("gas_limit", e.GasLimit),
+		h.uint64("gas_used", e.GasUsed),
+		h.uint64("timestamp", e.Timestamp),
+		h.byteList("extra_data", e.ExtraData, maxExtraDataBytes),
+		h.uint256("base_fee_per_gas", e.BaseFeePerGas),
+		h.root("block_hash", e.BlockHash),
+		txRoot,
+		wdRoot,
+	}
+	if version == "deneb" {
+		fields = append(fields, h.uint64("blob_gas_used", e.BlobGasUsed), h.uint64("excess_blob_gas", e.ExcessBlobGas))
+	}
+	return container(fields...), txRoot, wdRoot
+}
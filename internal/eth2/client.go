@@ -10,11 +10,14 @@ import (
 	"net/url"
 	"reflect"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/mapprotocol/compass/internal/constant"
+	"github.com/mapprotocol/compass/pkg/ethclient"
+	"github.com/mapprotocol/compass/pkg/instrument"
 )
 
 const (
@@ -35,7 +38,7 @@ type Client struct {
 	idCounter uint32
 }
 
-func DialHttp(endpoint string) (*Client, error) {
+func DialHttp(chain, endpoint string) (*Client, error) {
 	// Sanity chck URL so we don't end up with a client that will fail every request.
 	_, err := url.Parse(endpoint)
 	if err != nil {
@@ -45,9 +48,7 @@ func DialHttp(endpoint string) (*Client, error) {
 	headers := make(http.Header, 2)
 	headers.Set("accept", contentType)
 	headers.Set("content-type", contentType)
-	client := &http.Client{
-		Timeout: time.Second * 10,
-	}
+	client := ethclient.NewHTTPClient(chain, endpoint, time.Second*10)
 	return &Client{
 		client:    client,
 		endpoint:  endpoint,
@@ -170,6 +171,28 @@ func (c *Client) sendHTTP(ctx context.Context, url string, op *requestOp) error
 }
 
 func (c *Client) doRequest(ctx context.Context, url string) (io.ReadCloser, error) {
+	done := instrument.Start("eth2", instrument.Endpoint(c.endpoint), c.method(url), url)
+	body, err := c.request(ctx, url)
+	done(err)
+	return body, err
+}
+
+// method returns the path of a beacon api url as the method of the call, block ids and numbers replaced by {id}
+func (c *Client) method(rawurl string) string {
+	p := strings.TrimPrefix(rawurl, strings.TrimSuffix(c.endpoint, "/"))
+	if i := strings.Index(p, "?"); i >= 0 {
+		p = p[:i]
+	}
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	for i, seg := range segments {
+		if _, err := strconv.ParseUint(seg, 10, 64); err == nil || strings.HasPrefix(seg, "0x") {
+			segments[i] = "{id}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+func (c *Client) request(ctx context.Context, url string) (io.ReadCloser, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
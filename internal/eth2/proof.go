@@ -1,17 +1,13 @@
 package eth2
 
 import (
+	"fmt"
 	"github.com/mapprotocol/compass/internal/constant"
 	"github.com/mapprotocol/compass/internal/mapo"
 	"github.com/mapprotocol/compass/internal/proof"
 	"github.com/mapprotocol/compass/pkg/util"
 	"math/big"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"strings"
 
-	log "github.com/ChainSafe/log15"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -20,37 +16,62 @@ import (
 	"github.com/pkg/errors"
 )
 
-var execPath = "./eth2-proof"
+// executionPayloadIndex is the index of the execution payload in the fields of a beacon block body
+const executionPayloadIndex = 9
 
-func init() {
-	if filepath.Dir(os.Args[0]) == "." {
-		return
-	}
-	execPath = filepath.Join(filepath.Dir(os.Args[0]), "eth2-proof")
+// ExecutionProof is the proof of the execution payload of a beacon block
+type ExecutionProof struct {
+	Branch           [][32]byte // siblings of the payload root in the body tree, from the leaf up
+	TransactionsRoot common.Hash
+	WithdrawalsRoot  common.Hash
+	BodyRoot         common.Hash // root the branch proves the payload against
 }
 
-func Generate(slot, endpoint string) ([][32]byte, string, string, error) {
-	c := exec.Command(execPath, "generate", "--slot", slot, "--endpoint", endpoint)
-	log.Info("eth exec", "path", execPath, "cmd", c.String())
-	subOutPut, err := c.CombinedOutput()
-	if err != nil {
-		return nil, "", "", errors.Wrap(err, "command exec failed")
+// Generate computes the ssz merkle proof of the execution payload in the body of a capella or deneb block, with
+// the transactions and withdrawals roots of the payload header
+func Generate(block *BlocksResp) (*ExecutionProof, error) {
+	var (
+		h    hasher
+		body = &block.Data.Message.Body
+	)
+	switch block.Version {
+	case "capella", "deneb":
+	default:
+		return nil, fmt.Errorf("unsupported block version %q", block.Version)
 	}
 
-	outPuts := strings.Split(string(subOutPut), "\n")
-	ret := make([][32]byte, 0, len(outPuts))
-	var txRoot, wdRoot string
-	for _, op := range outPuts {
-		if strings.HasPrefix(op, "0x") {
-			ret = append(ret, common.HexToHash(op))
-		} else if strings.HasPrefix(op, "txRoot") {
-			txRoot = strings.TrimSpace(strings.TrimPrefix(op, "txRoot"))
-		} else if strings.HasPrefix(op, "wdRoot") {
-			wdRoot = strings.TrimSpace(strings.TrimPrefix(op, "wdRoot"))
+	payload, txRoot, wdRoot := h.executionPayload(&body.ExecutionPayload, block.Version)
+	fields := [][32]byte{
+		h.vector("randao_reveal", body.RandaoReveal, 96),
+		h.eth1Data(&body.Eth1Data),
+		h.root("graffiti", body.Graffiti),
+		h.proposerSlashings(body.ProposerSlashings),
+		h.attesterSlashings(body.AttesterSlashings),
+		h.attestations(body.Attestations),
+		h.deposits(body.Deposits),
+		h.voluntaryExits(body.VoluntaryExits),
+		h.syncAggregate(&body.SyncAggregate),
+		payload,
+		h.blsToExecutionChanges(body.BlsToExecutionChanges),
+	}
+	if block.Version == "deneb" {
+		commitments := make([][32]byte, 0, len(body.BlobKzgCommitments))
+		for _, c := range body.BlobKzgCommitments {
+			commitments = append(commitments, h.vector("blob_kzg_commitment", c, 48))
 		}
+		fields = append(fields, list(commitments, maxBlobCommitments))
+	}
+	if h.err != nil {
+		return nil, errors.Wrap(h.err, "hash beacon block body")
 	}
 
-	return ret, txRoot, wdRoot, nil
+	root, branch := merkleProof(fields, uint64(len(fields)), executionPayloadIndex)
+	return &ExecutionProof{
+		Branch:           branch,
+		TransactionsRoot: txRoot,
+		WithdrawalsRoot:  wdRoot,
+		BodyRoot:         root,
+	}, nil
 }
 
 func GenerateByApi(slot []string) [][32]byte {
@@ -83,6 +104,9 @@ func AssembleProof(header BlockHeader, log types.Log, receipts []*types.Receipt,
 	if err != nil {
 		return nil, err
 	}
+	if err = proof.Verify(receipts, pr, header.ReceiptsRoot, txIndex, prf); err != nil {
+		return nil, fmt.Errorf("verify receipt proof of block %d: %w", log.BlockNumber, err)
+	}
 	var key []byte
 	key = rlp.AppendUint64(key[:0], uint64(txIndex))
 
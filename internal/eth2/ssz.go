@@ -0,0 +1,227 @@
+package eth2
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// mainnet preset limits of the ssz lists in a beacon block body
+const (
+	maxProposerSlashings      = 16
+	maxAttesterSlashings      = 2
+	maxAttestations           = 128
+	maxDeposits               = 16
+	maxVoluntaryExits         = 16
+	maxBlsToExecutionChanges  = 16
+	maxBlobCommitments        = 4096
+	maxValidatorsPerCommittee = 2048
+	maxTransactionsPerPayload = 1 << 20
+	maxBytesPerTransaction    = 1 << 30
+	maxWithdrawalsPerPayload  = 16
+	maxExtraDataBytes         = 32
+	bytesPerLogsBloom         = 256
+	syncCommitteeSize         = 512
+	depositProofLength        = 33 // DEPOSIT_CONTRACT_TREE_DEPTH + 1
+)
+
+// zeroHashes[i] is the root of a tree of depth i whose leaves are zero chunks
+var zeroHashes [64][32]byte
+
+func init() {
+	for i := 1; i < len(zeroHashes); i++ {
+		zeroHashes[i] = hashPair(zeroHashes[i-1], zeroHashes[i-1])
+	}
+}
+
+func hashPair(a, b [32]byte) [32]byte {
+	var buf [64]byte
+	copy(buf[:32], a[:])
+	copy(buf[32:], b[:])
+	return sha256.Sum256(buf[:])
+}
+
+// merkleize returns the root of the chunks padded with zero chunks to the power of two above limit
+func merkleize(chunks [][32]byte, limit uint64) [32]byte {
+	root, _ := merkleProof(chunks, limit, 0)
+	return root
+}
+
+// merkleProof returns the root of the chunks like merkleize, and the branch proving the chunk at index,
+// ordered from the leaf up
+func merkleProof(chunks [][32]byte, limit uint64, index uint64) ([32]byte, [][32]byte) {
+	depth := 0
+	for uint64(1)<<depth < limit {
+		depth++
+	}
+	branch := make([][32]byte, 0, depth)
+	layer := make([][32]byte, len(chunks))
+	copy(layer, chunks)
+	for d := 0; d < depth; d++ {
+		if len(layer)%2 == 1 {
+			layer = append(layer, zeroHashes[d])
+		}
+		if sibling := index ^ 1; sibling < uint64(len(layer)) {
+			branch = append(branch, layer[sibling])
+		} else {
+			branch = append(branch, zeroHashes[d])
+		}
+		next := make([][32]byte, len(layer)/2)
+		for i := range next {
+			next[i] = hashPair(layer[2*i], layer[2*i+1])
+		}
+		layer, index = next, index/2
+	}
+	if len(layer) == 0 {
+		return zeroHashes[depth], branch
+	}
+	return layer[0], branch
+}
+
+func mixInLength(root [32]byte, length uint64) [32]byte {
+	var l [32]byte
+	binary.LittleEndian.PutUint64(l[:], length)
+	return hashPair(root, l)
+}
+
+// pack splits b in chunks, the last one padded with zeros
+func pack(b []byte) [][32]byte {
+	chunks := make([][32]byte, (len(b)+31)/32)
+	for i := range chunks {
+		copy(chunks[i][:], b[i*32:])
+	}
+	return chunks
+}
+
+// hasher computes the ssz hash tree roots of the hex and decimal strings of the beacon api, the first
+// malformed value is kept in err and later calls return zero roots
+type hasher struct {
+	err error
+}
+
+func (h *hasher) bytes(field, v string) []byte {
+	if h.err != nil {
+		return nil
+	}
+	b, err := hexutil.Decode(v)
+	if err != nil {
+		h.err = fmt.Errorf("invalid %s %q: %v", field, v, err)
+	}
+	return b
+}
+
+func (h *hasher) fixedBytes(field, v string, n int) []byte {
+	b := h.bytes(field, v)
+	if h.err == nil && len(b) != n {
+		h.err = fmt.Errorf("invalid %s length %d, want %d", field, len(b), n)
+	}
+	return b
+}
+
+// root returns the root of a Bytes32
+func (h *hasher) root(field, v string) [32]byte {
+	var ret [32]byte
+	copy(ret[:], h.fixedBytes(field, v, 32))
+	return ret
+}
+
+// vector returns the root of a ByteVector[n]
+func (h *hasher) vector(field, v string, n int) [32]byte {
+	return merkleize(pack(h.fixedBytes(field, v, n)), uint64((n+31)/32))
+}
+
+// byteList returns the root of a ByteList[max]
+func (h *hasher) byteList(field, v string, max int) [32]byte {
+	b := h.bytes(field, v)
+	if h.err == nil && len(b) > max {
+		h.err = fmt.Errorf("%s of %d bytes exceeds %d", field, len(b), max)
+	}
+	return mixInLength(merkleize(pack(b), uint64((max+31)/32)), uint64(len(b)))
+}
+
+// bitlist returns the root of a Bitlist[max], v holds the bits followed by the length delimiting bit
+func (h *hasher) bitlist(field, v string, max int) [32]byte {
+	b := h.bytes(field, v)
+	if h.err != nil {
+		return [32]byte{}
+	}
+	if len(b) == 0 || b[len(b)-1] == 0 {
+		h.err = fmt.Errorf("invalid %s, missing length bit", field)
+		return [32]byte{}
+	}
+	last := b[len(b)-1]
+	msb := 7
+	for last>>uint(msb) == 0 {
+		msb--
+	}
+	length := (len(b)-1)*8 + msb
+	if length > max {
+		h.err = fmt.Errorf("%s of %d bits exceeds %d", field, length, max)
+		return [32]byte{}
+	}
+	bits := make([]byte, len(b))
+	copy(bits, b)
+	bits[len(bits)-1] ^= 1 << uint(msb)
+	bits = bits[:(length+7)/8]
+	return mixInLength(merkleize(pack(bits), uint64((max+255)/256)), uint64(length))
+}
+
+func (h *hasher) parseUint(field, v string) uint64 {
+	if h.err != nil {
+		return 0
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		h.err = fmt.Errorf("invalid %s %q: %v", field, v, err)
+	}
+	return n
+}
+
+// uint64 returns the root of a uint64 given in decimal
+func (h *hasher) uint64(field, v string) [32]byte {
+	var ret [32]byte
+	binary.LittleEndian.PutUint64(ret[:], h.parseUint(field, v))
+	return ret
+}
+
+// uint256 returns the root of a uint256 given in decimal
+func (h *hasher) uint256(field, v string) [32]byte {
+	var ret [32]byte
+	if h.err != nil {
+		return ret
+	}
+	n, ok := new(big.Int).SetString(strings.TrimSpace(v), 10)
+	if !ok || n.Sign() < 0 || n.BitLen() > 256 {
+		h.err = fmt.Errorf("invalid %s %q", field, v)
+		return ret
+	}
+	be := n.FillBytes(make([]byte, 32))
+	for i := range ret {
+		ret[i] = be[31-i]
+	}
+	return ret
+}
+
+// uint64List returns the root of a List[uint64, max]
+func (h *hasher) uint64List(field string, vs []string, max int) [32]byte {
+	b := make([]byte, 8*len(vs))
+	for i, v := range vs {
+		binary.LittleEndian.PutUint64(b[8*i:], h.parseUint(field, v))
+	}
+	return mixInLength(merkleize(pack(b), uint64((8*max+31)/32)), uint64(len(vs)))
+}
+
+// list returns the root of a List of composite elements, given their roots
+func list(roots [][32]byte, max int) [32]byte {
+	return mixInLength(merkleize(roots, uint64(max)), uint64(len(roots)))
+}
+
+// container returns the root of a container, given the roots of its fields
+func container(fields ...[32]byte) [32]byte {
+	return merkleize(fields, uint64(len(fields)))
+}
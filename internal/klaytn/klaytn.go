@@ -212,6 +212,9 @@ func AssembleProof(cli *Client, header Header, log *types.Log, fId msg.ChainId,
 	if err != nil {
 		return nil, err
 	}
+	if err = proof.Verify(receipts, receiptRlps, common.BytesToHash(header.ReceiptsRoot), log.TxIndex, prf); err != nil {
+		return nil, errors.Wrapf(err, "verify receipt proof of block %d", log.BlockNumber)
+	}
 	var key []byte
 	key = rlp.AppendUint64(key[:0], uint64(log.TxIndex))
 	ek := util.Key2Hex(key, len(prf))
@@ -26,7 +26,10 @@ import (
 	"github.com/pkg/errors"
 )
 
-func AssembleEthProof(conn *ethclient.Client, log *types.Log, receipts []*types.Receipt, method string, fId msg.ChainId, proofType int64) ([]byte, error) {
+// AssembleEthProof builds the proof of the receipt of log, verified against the receipts root of header, the header of
+// the block of log
+func AssembleEthProof(conn *ethclient.Client, log *types.Log, receipts []*types.Receipt, header *types.Header, method string,
+	fId msg.ChainId, proofType int64) ([]byte, error) {
 	var payloads []byte
 	switch proofType {
 	case constant.ProofTypeOfOrigin:
@@ -37,7 +40,7 @@ func AssembleEthProof(conn *ethclient.Client, log *types.Log, receipts []*types.
 			return nil, err
 		}
 
-		prf, err := ethProof(conn, fId, log.TxIndex, receipts)
+		prf, err := ethProof(conn, fId, log, receipts, header.ReceiptHash)
 		if err != nil {
 			return nil, err
 		}
@@ -68,7 +71,7 @@ func AssembleEthProof(conn *ethclient.Client, log *types.Log, receipts []*types.
 	return pack, nil
 }
 
-func ethProof(conn *ethclient.Client, fId msg.ChainId, txIdx uint, receipts []*types.Receipt) ([][]byte, error) {
+func ethProof(conn *ethclient.Client, fId msg.ChainId, log *types.Log, receipts []*types.Receipt, root common.Hash) ([][]byte, error) {
 	var dls proof.DerivableList
 	switch fId {
 	case constant.ArbChainId, constant.ArbTestnetChainId:
@@ -100,10 +103,13 @@ func ethProof(conn *ethclient.Client, fId msg.ChainId, txIdx uint, receipts []*t
 	default:
 		dls = types.Receipts(receipts)
 	}
-	ret, err := proof.Get(dls, txIdx)
+	ret, err := proof.Get(dls, log.TxIndex)
 	if err != nil {
 		return nil, err
 	}
+	if err = proof.Verify(receipts, dls, root, log.TxIndex, ret); err != nil {
+		return nil, errors.Wrapf(err, "verify receipt proof of block %d", log.BlockNumber)
+	}
 	return ret, nil
 }
 
@@ -118,10 +124,16 @@ func AssembleMapProof(cli *ethclient.Client, log *types.Log, receipts []*types.R
 	}
 
 	receipt, err := mapprotocol.GetTxReceipt(receipts[txIndex])
+	if err != nil {
+		return 0, nil, err
+	}
 	prf, err := proof.Get(types.Receipts(receipts), txIndex)
 	if err != nil {
 		return 0, nil, err
 	}
+	if err = proof.Verify(receipts, types.Receipts(receipts), header.ReceiptHash, txIndex, prf); err != nil {
+		return 0, nil, errors.Wrapf(err, "verify receipt proof of block %d", log.BlockNumber)
+	}
 
 	var key []byte
 	key = rlp.AppendUint64(key[:0], uint64(txIndex))
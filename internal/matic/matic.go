@@ -1,6 +1,7 @@
 package matic
 
 import (
+	"fmt"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -91,6 +92,9 @@ func AssembleProof(headers []BlockHeader, log *types.Log, fId msg.ChainId, recei
 	if err != nil {
 		return nil, err
 	}
+	if err = proof.Verify(receipts, types.Receipts(receipts), common.BytesToHash(headers[0].ReceiptsRoot), txIndex, prf); err != nil {
+		return nil, fmt.Errorf("verify receipt proof of block %d: %w", log.BlockNumber, err)
+	}
 
 	var key []byte
 	key = rlp.AppendUint64(key[:0], uint64(txIndex))
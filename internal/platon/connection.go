@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/event"
 	"math/big"
 	"sync"
 	"time"
@@ -13,8 +14,6 @@ import (
 	"github.com/ChainSafe/log15"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethcommon "github.com/ethereum/go-ethereum/common"
-	ethcrypto "github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/mapprotocol/compass/internal/constant"
 	"github.com/mapprotocol/compass/pkg/ethclient"
 )
@@ -27,17 +26,17 @@ type Connection struct {
 	maxGasPrice   *big.Int
 	gasMultiplier *big.Float
 	conn          *ethclient.Client
-	opts          *bind.TransactOpts
-	callOpts      *bind.CallOpts
-	nonce         uint64
-	optsLock      sync.Mutex
+	connLock      sync.RWMutex
+	stateFeed     event.Feed // transport state changes
+	chain         string     // name of the chain, whose rate limit applies to the connection
 	log           log15.Logger
 	stop          chan int // All routines should exit when this channel is closed
 }
 
 // NewConn returns an uninitialized connection, must call Connection.Connect() before using.
+// Platon connections use a single endpoint, so only the name of the connection options is used.
 func NewConn(endpoint string, http bool, kp *keystore.Key, log log15.Logger, gasLimit, gasPrice *big.Int,
-	gasMultiplier float64) core.Connection {
+	gasMultiplier float64, connOpts core.ConnOpts) core.Connection {
 	bigFloat := new(big.Float).SetFloat64(gasMultiplier)
 	conn := Connection{
 		endpoint:      endpoint,
@@ -48,64 +47,27 @@ func NewConn(endpoint string, http bool, kp *keystore.Key, log log15.Logger, gas
 		gasMultiplier: bigFloat,
 		log:           log,
 		stop:          make(chan int),
+		chain:         connOpts.Name,
 	}
 	return &conn
 }
 
 // Connect starts the ethereum WS connection
-func (c *Connection) Connect() error {
+func (c *Connection) Connect(ctx context.Context) error {
 	c.log.Info("Connecting to ethereum chain...", "url", c.endpoint)
-	var rpcClient *rpc.Client
-	var err error
-	// Start http or ws client
-	if c.http {
-		rpcClient, err = rpc.DialHTTP(c.endpoint)
-	} else {
-		rpcClient, err = rpc.DialContext(context.Background(), c.endpoint)
-	}
+	conn, err := c.dial(ctx)
 	if err != nil {
 		return err
 	}
-	c.conn = ethclient.NewClient(rpcClient, c.endpoint)
-
-	// Construct tx opts, call opts, and nonce mechanism
-	opts, _, err := c.newTransactOpts(big.NewInt(0), c.gasLimit, c.maxGasPrice)
-	if err != nil {
-		return err
+	c.conn = conn
+	if !c.http {
+		go c.monitorTransport()
 	}
-	c.opts = opts
-	c.nonce = 0
-	c.callOpts = &bind.CallOpts{From: c.kp.Address}
 	return nil
 }
 
-// newTransactOpts builds the TransactOpts for the connection's keypair.
-func (c *Connection) newTransactOpts(value, gasLimit, gasPrice *big.Int) (*bind.TransactOpts, uint64, error) {
-	privateKey := c.kp.PrivateKey
-	address := ethcrypto.PubkeyToAddress(privateKey.PublicKey)
-
-	nonce, err := c.conn.PendingNonceAt(context.Background(), address)
-	if err != nil {
-		return nil, 0, err
-	}
-
-	id, err := c.conn.ChainID(context.Background())
-	if err != nil {
-		return nil, 0, err
-	}
-
-	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, id)
-	if err != nil {
-		return nil, 0, err
-	}
-
-	auth.Nonce = big.NewInt(int64(nonce))
-	auth.Value = value
-	auth.GasLimit = uint64(gasLimit.Int64())
-	auth.GasPrice = gasPrice
-	auth.Context = context.Background()
-
-	return auth, nonce, nil
+func (c *Connection) dial(ctx context.Context) (*ethclient.Client, error) {
+	return ethclient.DialClient(ctx, c.chain, c.endpoint)
 }
 
 func (c *Connection) Keypair() *keystore.Key {
@@ -113,21 +75,20 @@ func (c *Connection) Keypair() *keystore.Key {
 }
 
 func (c *Connection) Client() *ethclient.Client {
+	c.connLock.RLock()
+	defer c.connLock.RUnlock()
 	return c.conn
 }
 
-func (c *Connection) Opts() *bind.TransactOpts {
-	return c.opts
-}
-
-func (c *Connection) CallOpts() *bind.CallOpts {
-	return c.callOpts
+// CallOpts returns the options of a contract call from the keypair, canceled with ctx
+func (c *Connection) CallOpts(ctx context.Context) *bind.CallOpts {
+	return &bind.CallOpts{From: c.kp.Address, Context: ctx}
 }
 
 func (c *Connection) SafeEstimateGas(ctx context.Context) (*big.Int, error) {
 	var suggestedGasPrice *big.Int
 	c.log.Debug("Fetching gasPrice from node")
-	nodePriceEstimate, err := c.conn.SuggestGasPrice(ctx)
+	nodePriceEstimate, err := c.Client().SuggestGasPrice(ctx)
 	if err != nil {
 		return nil, err
 	} else {
@@ -138,7 +99,7 @@ func (c *Connection) SafeEstimateGas(ctx context.Context) (*big.Int, error) {
 
 	// Check we aren't exceeding our limit
 	if gasPrice.Cmp(c.maxGasPrice) == 1 {
-		return c.maxGasPrice, nil
+		return new(big.Int).Set(c.maxGasPrice), nil
 	} else {
 		return gasPrice, nil
 	}
@@ -154,7 +115,7 @@ func (c *Connection) EstimateGasLondon(ctx context.Context, baseFee *big.Int) (*
 		return maxPriorityFeePerGas, maxFeePerGas, nil
 	}
 
-	maxPriorityFeePerGas, err := c.conn.SuggestGasTipCap(ctx)
+	maxPriorityFeePerGas, err := c.Client().SuggestGasTipCap(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -169,7 +130,7 @@ func (c *Connection) EstimateGasLondon(ctx context.Context, baseFee *big.Int) (*
 	if maxFeePerGas.Cmp(c.maxGasPrice) == 1 {
 		c.log.Info("EstimateGasLondon maxFeePerGas more than set", "maxFeePerGas", maxFeePerGas, "baseFee", baseFee)
 		maxPriorityFeePerGas.Sub(c.maxGasPrice, baseFee)
-		maxFeePerGas = c.maxGasPrice
+		maxFeePerGas = new(big.Int).Set(c.maxGasPrice)
 	}
 	return maxPriorityFeePerGas, maxFeePerGas, nil
 }
@@ -182,61 +143,18 @@ func multiplyGasPrice(gasEstimate *big.Int, gasMultiplier *big.Float) *big.Int {
 	return gasPrice
 }
 
-// LockAndUpdateOpts acquires a lock on the opts before updating the nonce
-// and gas price.
-func (c *Connection) LockAndUpdateOpts(needNewNonce bool) error {
-	//c.optsLock.Lock()
-	//head, err := c.conn.PlatonGetBlockByNumber(context.TODO(), nil)
-	//// cos map chain dont have this section in return,this err will be raised
-	//if err != nil && err.Error() != "missing required field 'sha3Uncles' for Header" {
-	//	c.UnlockOpts()
-	//	c.log.Error("LockAndUpdateOpts HeaderByNumber", "err", err)
-	//	return err
-	//}
-
-	//if head.BaseFee != nil {
-	//	c.opts.GasTipCap, c.opts.GasFeeCap, err = c.EstimateGasLondon(context.TODO(), head.BaseFee)
-	//
-	//	// Both gasPrice and (maxFeePerGas or maxPriorityFeePerGas) cannot be specified: https://github.com/ethereum/go-ethereum/blob/95bbd46eabc5d95d9fb2108ec232dd62df2f44ab/accounts/abi/bind/base.go#L254
-	//	c.opts.GasPrice = nil
-	//	if err != nil {
-	//		// if EstimateGasLondon failed, fall back to suggestGasPrice
-	//		c.opts.GasPrice, err = c.conn.SuggestGasPrice(context.TODO())
-	//		if err != nil {
-	//			c.UnlockOpts()
-	//			return err
-	//		}
-	//	}
-	//	c.log.Info("LockAndUpdateOpts ", "head.BaseFee", head.BaseFee, "maxGasPrice", c.maxGasPrice)
-	//} else {
-	var gasPrice *big.Int
-	gasPrice, err := c.SafeEstimateGas(context.TODO())
-	if err != nil {
-		//c.UnlockOpts()
-		return err
-	}
-	c.opts.GasPrice = gasPrice
-	//}
-
-	if !needNewNonce {
-		return nil
-	}
-	nonce, err := c.conn.PendingNonceAt(context.Background(), c.opts.From)
+// PrepareTx returns the gas price of a new tx
+func (c *Connection) PrepareTx(ctx context.Context) (*core.TxOpts, error) {
+	gasPrice, err := c.SafeEstimateGas(ctx)
 	if err != nil {
-		//c.optsLock.Unlock()
-		return err
+		return nil, err
 	}
-	c.opts.Nonce.SetUint64(nonce)
-	return nil
-}
-
-func (c *Connection) UnlockOpts() {
-	//c.optsLock.Unlock()
+	return &core.TxOpts{From: c.kp.Address, GasLimit: c.gasLimit.Uint64(), GasPrice: gasPrice}, nil
 }
 
 // LatestBlock returns the latest block from the current chain
-func (c *Connection) LatestBlock() (*big.Int, error) {
-	bnum, err := c.conn.BlockNumber(context.Background())
+func (c *Connection) LatestBlock(ctx context.Context) (*big.Int, error) {
+	bnum, err := c.Client().BlockNumber(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -244,8 +162,8 @@ func (c *Connection) LatestBlock() (*big.Int, error) {
 }
 
 // EnsureHasBytecode asserts if contract code exists at the specified address
-func (c *Connection) EnsureHasBytecode(addr ethcommon.Address) error {
-	//code, err := c.conn.CodeAt(context.Background(), addr, nil)
+func (c *Connection) EnsureHasBytecode(ctx context.Context, addr ethcommon.Address) error {
+	//code, err := c.Client().CodeAt(ctx, addr, nil)
 	//if err != nil {
 	//	return err
 	//}
@@ -258,13 +176,15 @@ func (c *Connection) EnsureHasBytecode(addr ethcommon.Address) error {
 
 // WaitForBlock will poll for the block number until the current block is equal or greater.
 // If delay is provided it will wait until currBlock - delay = targetBlock
-func (c *Connection) WaitForBlock(targetBlock *big.Int, delay *big.Int) error {
+func (c *Connection) WaitForBlock(ctx context.Context, targetBlock *big.Int, delay *big.Int) error {
 	for {
 		select {
 		case <-c.stop:
 			return errors.New("connection terminated")
+		case <-ctx.Done():
+			return ctx.Err()
 		default:
-			currBlock, err := c.LatestBlock()
+			currBlock, err := c.LatestBlock(ctx)
 			if err != nil {
 				return err
 			}
@@ -278,16 +198,19 @@ func (c *Connection) WaitForBlock(targetBlock *big.Int, delay *big.Int) error {
 				return nil
 			}
 			c.log.Trace("Block not ready, waiting", "target", targetBlock, "current", currBlock, "delay", delay)
-			time.Sleep(constant.BlockRetryInterval)
-			continue
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(constant.BlockRetryInterval):
+			}
 		}
 	}
 }
 
 // Close terminates the client connection and stops any running routines
 func (c *Connection) Close() {
-	if c.conn != nil {
-		c.conn.Close()
-	}
 	close(c.stop)
+	if conn := c.Client(); conn != nil {
+		conn.Close()
+	}
 }
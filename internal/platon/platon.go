@@ -2,6 +2,7 @@ package platon
 
 import (
 	"context"
+	"fmt"
 	"github.com/mapprotocol/compass/internal/mapo"
 	"math/big"
 
@@ -104,6 +105,9 @@ func AssembleProof(block *UpdateBlock, log *types.Log, receipts []*types.Receipt
 	if err != nil {
 		return nil, err
 	}
+	if err = proof.Verify(receipts, types.Receipts(receipts), common.BytesToHash(block.Header.ReceiptsRoot), txIndex, pr); err != nil {
+		return nil, fmt.Errorf("verify receipt proof of block %d: %w", log.BlockNumber, err)
+	}
 
 	var key []byte
 	key = rlp.AppendUint64(key[:0], uint64(txIndex))
@@ -0,0 +1,85 @@
+package platon
+
+import (
+	"context"
+	"time"
+
+	eth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/mapprotocol/compass/core"
+	"github.com/mapprotocol/compass/pkg/instrument"
+)
+
+const (
+	keepAliveInterval = 10 * time.Second
+	keepAliveTimeout  = 5 * time.Second
+)
+
+// SubscribeState subscribes to the transport state changes of the endpoint. The connection waits for every
+// subscriber to receive an event, so ch should be buffered and drained.
+func (c *Connection) SubscribeState(ch chan<- core.ConnEvent) event.Subscription {
+	return c.stateFeed.Subscribe(ch)
+}
+
+// SubscribeFilterLogs subscribes to the logs matching q, the subscription is made again after the endpoint drops
+func (c *Connection) SubscribeFilterLogs(q eth.FilterQuery, ch chan<- types.Log) event.Subscription {
+	return core.Resubscribe(c.log, "logs", func(ctx context.Context) (eth.Subscription, error) {
+		return c.Client().SubscribeFilterLogs(ctx, q, ch)
+	})
+}
+
+// SubscribeNewHead subscribes to the new heads, the subscription is made again after the endpoint drops
+func (c *Connection) SubscribeNewHead(ch chan<- *types.Header) event.Subscription {
+	return core.Resubscribe(c.log, "newHeads", func(ctx context.Context) (eth.Subscription, error) {
+		return c.Client().SubscribeNewHead(ctx, ch)
+	})
+}
+
+// monitorTransport pings the websocket endpoint and redials it when the transport is closed
+func (c *Connection) monitorTransport() {
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), keepAliveTimeout)
+		_, err := c.Client().BlockNumber(ctx)
+		cancel()
+		if !core.TransportClosed(err) {
+			continue
+		}
+		c.log.Warn("Endpoint transport closed, reconnecting", "url", c.endpoint, "err", err)
+		c.stateFeed.Send(core.ConnEvent{Endpoint: instrument.Endpoint(c.endpoint), State: core.ConnDisconnected, Err: err})
+		if !c.reconnect() {
+			return
+		}
+		c.stateFeed.Send(core.ConnEvent{Endpoint: instrument.Endpoint(c.endpoint), State: core.ConnReconnected, Connected: 1})
+	}
+}
+
+// reconnect redials the endpoint with backoff until it succeeds or the connection is closed. The old client is
+// closed, which ends its subscriptions so they are made again.
+func (c *Connection) reconnect() bool {
+	for retries := 0; ; retries++ {
+		conn, err := c.dial(context.Background())
+		if err == nil {
+			c.connLock.Lock()
+			old := c.conn
+			c.conn = conn
+			c.connLock.Unlock()
+			old.Close()
+			c.log.Info("Endpoint reconnected", "url", c.endpoint)
+			return true
+		}
+		c.log.Debug("Redial endpoint failed", "url", c.endpoint, "retries", retries, "err", err)
+		select {
+		case <-c.stop:
+			return false
+		case <-time.After(core.ReconnectBackoff(retries)):
+		}
+	}
+}
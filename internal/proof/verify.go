@@ -0,0 +1,97 @@
+package proof
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/light"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// MismatchError is returned by Verify when the receipts do not hash to the root of the header
+type MismatchError struct {
+	Root     common.Hash // receipts root of the header
+	Computed common.Hash // root of the fetched receipts
+	Count    int
+	Problems []string
+}
+
+func (e *MismatchError) Error() string {
+	msg := fmt.Sprintf("receipts root mismatch, header has %s, %d receipts hash to %s", e.Root, e.Count, e.Computed)
+	if len(e.Problems) != 0 {
+		msg += ": " + strings.Join(e.Problems, "; ")
+	}
+	return msg
+}
+
+// Verify recomputes the receipts root of dls, the encoding of receipts used by the chain, checks it against
+// the root of the header and verifies prf for the receipt at txIndex, so a wrong receipt list is found
+// before the proof is submitted and reverted on chain
+func Verify(receipts []*types.Receipt, dls DerivableList, root common.Hash, txIndex uint, prf [][]byte) error {
+	for _, r := range receipts {
+		if r == nil {
+			return &MismatchError{Root: root, Count: len(receipts), Problems: Diagnose(receipts)}
+		}
+	}
+	if int(txIndex) >= dls.Len() {
+		return fmt.Errorf("receipt index %d out of %d receipts", txIndex, dls.Len())
+	}
+	tr, err := trie.New(common.Hash{}, trie.NewDatabase(memorydb.New()))
+	if err != nil {
+		return err
+	}
+	if computed := DeriveTire(dls, tr).Hash(); computed != root {
+		return &MismatchError{Root: root, Computed: computed, Count: dls.Len(), Problems: Diagnose(receipts)}
+	}
+
+	key, err := rlp.EncodeToBytes(txIndex)
+	if err != nil {
+		return err
+	}
+	nodes := light.NewNodeSet()
+	for _, node := range prf {
+		nodes.Put(crypto.Keccak256(node), node)
+	}
+	value, err := trie.VerifyProof(root, key, nodes)
+	if err != nil {
+		return fmt.Errorf("invalid proof of receipt %d: %w", txIndex, err)
+	}
+	valueBuf := encodeBufferPool.Get().(*bytes.Buffer)
+	defer encodeBufferPool.Put(valueBuf)
+	if !bytes.Equal(value, encodeForDerive(dls, int(txIndex), valueBuf)) {
+		return fmt.Errorf("proof of receipt %d proves a different receipt", txIndex)
+	}
+	return nil
+}
+
+// Diagnose reports the receipts that are missing or out of place in a block's receipt list
+func Diagnose(receipts []*types.Receipt) []string {
+	var (
+		problems   []string
+		cumulative uint64
+	)
+	for i, r := range receipts {
+		if r == nil {
+			problems = append(problems, fmt.Sprintf("receipt %d not found", i))
+			continue
+		}
+		if r.TxHash == (common.Hash{}) && r.BlockHash == (common.Hash{}) {
+			// the system receipts some chains add to the block carry no position
+			continue
+		}
+		if r.TransactionIndex != uint(i) {
+			problems = append(problems, fmt.Sprintf("receipt %d of tx %s has transaction index %d", i, r.TxHash, r.TransactionIndex))
+		}
+		if r.CumulativeGasUsed < cumulative {
+			problems = append(problems, fmt.Sprintf("receipt %d of tx %s has cumulative gas %d below the previous %d", i, r.TxHash, r.CumulativeGasUsed, cumulative))
+		}
+		cumulative = r.CumulativeGasUsed
+	}
+	return problems
+}
@@ -0,0 +1,131 @@
+package proof_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/mapprotocol/compass/internal/arb"
+	"github.com/mapprotocol/compass/internal/klaytn"
+	"github.com/mapprotocol/compass/internal/op"
+	"github.com/mapprotocol/compass/internal/proof"
+)
+
+func testReceipts(n int) []*types.Receipt {
+	receipts := make([]*types.Receipt, 0, n)
+	for i := 0; i < n; i++ {
+		receipts = append(receipts, &types.Receipt{
+			Type:              uint8(i % 3),
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: uint64(21000 * (i + 1)),
+			Logs:              []*types.Log{{Address: common.BigToAddress(common.Big1), Data: []byte{byte(i)}}},
+			TxHash:            common.BigToHash(common.Big2),
+			BlockHash:         common.BigToHash(common.Big3),
+			TransactionIndex:  uint(i),
+		})
+	}
+	return receipts
+}
+
+func TestVerify(t *testing.T) {
+	// more than 0x80 receipts to cover the key ordering of DeriveTire
+	receipts := testReceipts(130)
+	root := types.DeriveSha(types.Receipts(receipts), trie.NewStackTrie(nil))
+
+	for _, idx := range []uint{0, 1, 127, 128, 129} {
+		prf, err := proof.Get(types.Receipts(receipts), idx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = proof.Verify(receipts, types.Receipts(receipts), root, idx, prf); err != nil {
+			t.Fatalf("receipt %d: %v", idx, err)
+		}
+	}
+
+	prf, _ := proof.Get(types.Receipts(receipts), 1)
+	if err := proof.Verify(receipts, types.Receipts(receipts), root, 2, prf); err == nil {
+		t.Error("Expected the proof of another receipt to fail")
+	}
+	if err := proof.Verify(receipts, types.Receipts(receipts), root, 200, prf); err == nil {
+		t.Error("Expected an index out of the list to fail")
+	}
+}
+
+func TestVerifyMismatch(t *testing.T) {
+	receipts := testReceipts(4)
+	root := types.DeriveSha(types.Receipts(receipts), trie.NewStackTrie(nil))
+
+	swapped := testReceipts(4)
+	swapped[1], swapped[2] = swapped[2], swapped[1]
+	prf, _ := proof.Get(types.Receipts(swapped), 1)
+	err := proof.Verify(swapped, types.Receipts(swapped), root, 1, prf)
+	var mismatch *proof.MismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected a mismatch, got %v", err)
+	}
+	if mismatch.Computed == root || len(mismatch.Problems) != 3 || !strings.Contains(err.Error(), "has transaction index 2") {
+		t.Errorf("Unexpected diagnostic: %v", err)
+	}
+
+	missing := testReceipts(4)
+	missing[3] = nil
+	err = proof.Verify(missing, types.Receipts(missing), root, 1, prf)
+	if !errors.As(err, &mismatch) || !strings.Contains(err.Error(), "receipt 3 not found") {
+		t.Errorf("Expected the missing receipt to be reported, got %v", err)
+	}
+}
+
+// verifyEncoding checks that the receipts verify against the root of their chain encoding and not against the
+// root of the ethereum encoding
+func verifyEncoding(t *testing.T, receipts []*types.Receipt, dls proof.DerivableList) {
+	t.Helper()
+	root := types.DeriveSha(dls, trie.NewStackTrie(nil))
+	for _, idx := range []uint{0, 1, uint(len(receipts) - 1)} {
+		prf, err := proof.Get(dls, idx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = proof.Verify(receipts, dls, root, idx, prf); err != nil {
+			t.Fatalf("receipt %d: %v", idx, err)
+		}
+	}
+	prf, _ := proof.Get(types.Receipts(receipts), 1)
+	var mismatch *proof.MismatchError
+	if err := proof.Verify(receipts, types.Receipts(receipts), root, 1, prf); !errors.As(err, &mismatch) {
+		t.Errorf("Expected the ethereum encoding to mismatch the root, got %v", err)
+	}
+}
+
+func TestVerifyArbitrum(t *testing.T) {
+	receipts := testReceipts(4)
+	receipts[1].Type = arb.ArbitrumLegacyTxType
+	dls := arb.Receipts{}
+	for _, r := range receipts {
+		dls = append(dls, &arb.Receipt{Receipt: r})
+	}
+	verifyEncoding(t, receipts, dls)
+}
+
+func TestVerifyOp(t *testing.T) {
+	receipts := testReceipts(4)
+	receipts[2].Type = op.DepositTxType
+	dls := op.Receipts{}
+	for i, r := range receipts {
+		version, nonce := uint64(1), uint64(i)
+		dls = append(dls, &op.Receipt{Receipt: r, DepositReceiptVersion: &version, DepositNonce: &nonce})
+	}
+	verifyEncoding(t, receipts, dls)
+}
+
+func TestVerifyKlaytn(t *testing.T) {
+	receipts := testReceipts(4)
+	dls := make(klaytn.ReceiptRlps, 0, len(receipts))
+	for _, r := range receipts {
+		r.GasUsed = 21000
+		dls = append(dls, &klaytn.ReceiptRLP{Status: uint(r.Status), GasUsed: r.GasUsed, Bloom: r.Bloom, Logs: r.Logs})
+	}
+	verifyEncoding(t, receipts, dls)
+}
@@ -3,6 +3,7 @@ package tx
 import (
 	"context"
 	"errors"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/mapprotocol/compass/pkg/ethclient"
@@ -23,123 +24,40 @@ func GetTxsHashByBlockNumber(conn *ethclient.Client, number *big.Int) ([]common.
 	return txs, nil
 }
 
-func GetReceiptsByTxsHash(conn *ethclient.Client, txsHash []common.Hash) ([]*types.Receipt, error) {
-	type ele struct {
-		r   *types.Receipt
-		idx int
+// GetReceiptsByBlockNumber returns the receipts of txsHash, the txs of the block in order, in as few requests as
+// the node allows
+func GetReceiptsByBlockNumber(conn *ethclient.Client, number *big.Int, txsHash []common.Hash) ([]*types.Receipt, error) {
+	rs, err := conn.BlockReceipts(context.Background(), number, txsHash)
+	if err != nil {
+		return nil, err
 	}
-	var (
-		count      = len(txsHash)
-		errReceive = make(chan error)
-		receive    = make(chan *ele, len(txsHash))
-		rs         = make([]*types.Receipt, len(txsHash))
-	)
-	go func() {
-		for idx, h := range txsHash {
-			tmpIdx := idx
-			tmpHash := h
-			go func(i int, tx common.Hash) {
-				for {
-					r, err := conn.TransactionReceipt(context.Background(), tx)
-					if err != nil {
-						if err.Error() == "not found" {
-							time.Sleep(time.Millisecond * 100)
-							continue
-						}
-						errReceive <- err
-						return
-					}
-					receive <- &ele{
-						r:   r,
-						idx: i,
-					}
-					break
-				}
-			}(tmpIdx, tmpHash)
-
-			if idx%30 == 0 {
-				time.Sleep(time.Millisecond * 500)
-			}
-		}
-	}()
+	return waitMissing(conn, txsHash, rs)
+}
 
-	for {
-		select {
-		case v, ok := <-receive:
-			if !ok {
-				return nil, errors.New("receive chan is closed")
-			}
-			if v != nil {
-				rs[v.idx] = v.r
-			}
-			count--
-			if count == 0 {
-				return rs, nil
-			}
-		case err := <-errReceive:
-			return nil, err
-		}
+func GetReceiptsByTxsHash(conn *ethclient.Client, txsHash []common.Hash) ([]*types.Receipt, error) {
+	rs, err := conn.TransactionReceipts(context.Background(), txsHash)
+	if err != nil {
+		return nil, err
 	}
+	return waitMissing(conn, txsHash, rs)
 }
 
+// GetMaticReceiptsByTxsHash returns the receipts of txsHash, the receipt of a tx the node doesn't know is nil
 func GetMaticReceiptsByTxsHash(conn *ethclient.Client, txsHash []common.Hash) ([]*types.Receipt, error) {
-	type ele struct {
-		r   *types.Receipt
-		idx int
-	}
-	var (
-		count      = len(txsHash)
-		errReceive = make(chan error)
-		receive    = make(chan *ele, len(txsHash))
-		rs         = make([]*types.Receipt, len(txsHash))
-	)
-	go func() {
-		for idx, h := range txsHash {
-			tmpIdx := idx
-			tmpHash := h
-			go func(i int, tx common.Hash) {
-				for {
-					r, err := conn.TransactionReceipt(context.Background(), tx)
-					if err != nil {
-						if err.Error() == "not found" {
-							receive <- &ele{
-								r:   nil,
-								idx: i,
-							}
-							break
-						}
-						errReceive <- err
-						return
-					}
-					receive <- &ele{
-						r:   r,
-						idx: i,
-					}
-					break
-				}
-			}(tmpIdx, tmpHash)
-
-			if idx%30 == 0 {
-				time.Sleep(time.Millisecond * 500)
-			}
-		}
-	}()
+	return conn.TransactionReceipts(context.Background(), txsHash)
+}
 
-	for {
-		select {
-		case v, ok := <-receive:
-			if !ok {
-				return nil, errors.New("receive chan is closed")
-			}
-			if v != nil {
-				rs[v.idx] = v.r
-			}
-			count--
-			if count == 0 {
-				return rs, nil
+// waitMissing polls the receipts the node has not indexed yet
+func waitMissing(conn *ethclient.Client, txsHash []common.Hash, rs []*types.Receipt) ([]*types.Receipt, error) {
+	var err error
+	for i := range rs {
+		for rs[i] == nil {
+			time.Sleep(time.Millisecond * 100)
+			rs[i], err = conn.TransactionReceipt(context.Background(), txsHash[i])
+			if err != nil && !errors.Is(err, ethereum.NotFound) {
+				return nil, err
 			}
-		case err := <-errReceive:
-			return nil, err
 		}
 	}
+	return rs, nil
 }
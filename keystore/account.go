@@ -0,0 +1,163 @@
+package keystore
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/mapprotocol/near-api-go/pkg/types/key"
+)
+
+// Types of the keys handled by the accounts command
+const (
+	TypeEthereum = "ethereum"
+	TypeTron     = "tron"
+	TypeNear     = "near"
+)
+
+// scrypt parameters of the keys written, lowered by tests
+var scryptN, scryptP = keystore.StandardScryptN, keystore.StandardScryptP
+
+// Account is a key found on disk
+type Account struct {
+	Type    string
+	Address string // 0x address, tron base58 address or near account id
+	Network string // near network of the credentials
+	Path    string
+}
+
+// WriteEthKey encrypts pk with password into dir, in the keystore format read by KeypairFromEth, and returns the
+// path of the file
+func WriteEthKey(dir string, pk *ecdsa.PrivateKey, password string) (string, error) {
+	key := &keystore.Key{Id: uuid.New(), Address: crypto.PubkeyToAddress(pk.PublicKey), PrivateKey: pk}
+	data, err := keystore.EncryptKey(key, password, scryptN, scryptP)
+	if err != nil {
+		return "", err
+	}
+	return writeKeyFile(filepath.Join(dir, key.Address.Hex()+".json"), data)
+}
+
+// ImportEthKey copies the keystore at src into dir after checking password decrypts it, and returns the path
+// of the copy
+func ImportEthKey(dir, src string, password string) (string, error) {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return "", err
+	}
+	key, err := keystore.DecryptKey(data, password)
+	if err != nil {
+		return "", fmt.Errorf("decrypt %s failed, err:%s", src, err)
+	}
+	return writeKeyFile(filepath.Join(dir, key.Address.Hex()+".json"), data)
+}
+
+// NearCredentialsPath returns the path of the near credentials of id, home being the directory holding
+// .near-credentials as in NearKeyPairFrom
+func NearCredentialsPath(home, network, id string) string {
+	return filepath.Join(home, ".near-credentials", network, id+".json")
+}
+
+// NearImplicitAccount returns the implicit account id of a near key, the hex of its public key
+func NearImplicitAccount(kp key.KeyPair) string {
+	return hex.EncodeToString(kp.PublicKey.ToPublicKey().Value())
+}
+
+// WriteNearKey writes the near credentials of id in the format of near-cli, encrypted with password like an
+// ethereum keystore unless it is empty, and returns the path of the file
+func WriteNearKey(home, network, id string, kp key.KeyPair, password string) (string, error) {
+	data, err := json.Marshal(map[string]string{
+		"account_id":  id,
+		"public_key":  kp.PublicKey.String(),
+		"private_key": kp.PrivateEncoded(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if password != "" {
+		cj, err := keystore.EncryptDataV3(data, []byte(password), scryptN, scryptP)
+		if err != nil {
+			return "", err
+		}
+		if data, err = json.Marshal(map[string]interface{}{"account_id": id, "crypto": cj}); err != nil {
+			return "", err
+		}
+	}
+	return writeKeyFile(NearCredentialsPath(home, network, id), data)
+}
+
+func writeKeyFile(path string, data []byte) (string, error) {
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("key file %s already exists", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, data, 0600)
+}
+
+// keyFile holds the fields of ethereum keystores and near credentials which are readable without the password
+type keyFile struct {
+	Address   string          `json:"address"`
+	Crypto    json.RawMessage `json:"crypto"`
+	AccountID string          `json:"account_id"`
+}
+
+// AddressOf returns the account of an ethereum keystore or near credentials file, the password isn't needed
+func AddressOf(path string) (Account, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Account{}, err
+	}
+	var kf keyFile
+	if err = json.Unmarshal(data, &kf); err != nil {
+		return Account{}, fmt.Errorf("%s is not a key file: %v", path, err)
+	}
+	switch {
+	case kf.AccountID != "":
+		return Account{Type: TypeNear, Address: kf.AccountID, Network: filepath.Base(filepath.Dir(path)), Path: path}, nil
+	case kf.Address != "" && len(kf.Crypto) != 0:
+		return Account{Type: TypeEthereum, Address: common.HexToAddress(kf.Address).Hex(), Path: path}, nil
+	}
+	return Account{}, errors.New(path + " is not an ethereum keystore or near credentials file")
+}
+
+// ListAccounts returns the ethereum keystores in dir and the near credentials below home, files which aren't keys
+// are skipped
+func ListAccounts(dir, home string) ([]Account, error) {
+	var ret []Account
+	ethFiles, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range ethFiles {
+		if acc, err := AddressOf(f); err == nil && acc.Type == TypeEthereum {
+			ret = append(ret, acc)
+		}
+	}
+	nearFiles, err := filepath.Glob(filepath.Join(home, ".near-credentials", "*", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range nearFiles {
+		if acc, err := AddressOf(f); err == nil && acc.Type == TypeNear {
+			ret = append(ret, acc)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Type != ret[j].Type {
+			return ret[i].Type < ret[j].Type
+		}
+		return strings.ToLower(ret[i].Address) < strings.ToLower(ret[j].Address)
+	})
+	return ret, nil
+}
@@ -0,0 +1,103 @@
+package keystore
+
+import (
+	"crypto/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mapprotocol/near-api-go/pkg/types"
+	"github.com/mapprotocol/near-api-go/pkg/types/key"
+)
+
+func init() {
+	scryptN, scryptP = keystore.LightScryptN, keystore.LightScryptP
+}
+
+func TestEthKeyRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	pk, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := crypto.PubkeyToAddress(pk.PublicKey)
+	path, err := WriteEthKey(dir, pk, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = WriteEthKey(dir, pk, "secret"); err == nil {
+		t.Error("Expected an existing key not to be overwritten")
+	}
+
+	acc, err := AddressOf(path)
+	if err != nil || acc.Type != TypeEthereum || acc.Address != addr.Hex() {
+		t.Fatalf("Unexpected account %+v, err %v", acc, err)
+	}
+	key, err := KeypairFromEth(path, addr.Hex(), PasswordSource{Env: "TEST_ROUND_TRIP_PASSWORD"})
+	if err == nil {
+		t.Fatal("Expected no password to fail")
+	}
+	t.Setenv("TEST_ROUND_TRIP_PASSWORD", "secret")
+	if key, err = KeypairFromEth(path, addr.Hex(), PasswordSource{Env: "TEST_ROUND_TRIP_PASSWORD"}); err != nil {
+		t.Fatal(err)
+	}
+	if key.Address != addr {
+		t.Errorf("Decrypted %s, want %s", key.Address, addr)
+	}
+
+	imported, err := ImportEthKey(t.TempDir(), path, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ImportEthKey(t.TempDir(), path, "wrong"); err == nil {
+		t.Error("Expected a wrong password to fail the import")
+	}
+	if acc, err = AddressOf(imported); err != nil || acc.Address != addr.Hex() {
+		t.Errorf("Unexpected imported account %+v, err %v", acc, err)
+	}
+}
+
+func TestNearKeyRoundTrip(t *testing.T) {
+	home := t.TempDir()
+	for _, password := range []string{"", "secret"} {
+		kp, err := key.GenerateKeyPair(key.KeyTypeED25519, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := NearImplicitAccount(kp)
+		if len(id) != 64 {
+			t.Fatalf("Unexpected implicit account %s", id)
+		}
+		path, err := WriteNearKey(home, "testnet", id, kp, password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path != NearCredentialsPath(home, "testnet", id) {
+			t.Errorf("Unexpected path %s", path)
+		}
+
+		src := PasswordSource{Env: "TEST_NEAR_PASSWORD"}
+		t.Setenv("TEST_NEAR_PASSWORD", password)
+		got, err := NearKeyPairFrom("testnet", home, types.AccountID(id), src)
+		if err != nil {
+			t.Fatalf("password %q: %v", password, err)
+		}
+		if got.PublicKey.String() != kp.PublicKey.String() {
+			t.Errorf("Read key %s, want %s", got.PublicKey, kp.PublicKey)
+		}
+	}
+
+	accounts, err := ListAccounts(t.TempDir(), home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 2 {
+		t.Fatalf("Expected 2 near accounts, got %+v", accounts)
+	}
+	for _, acc := range accounts {
+		if acc.Type != TypeNear || acc.Network != "testnet" || filepath.Dir(acc.Path) != filepath.Join(home, ".near-credentials", "testnet") {
+			t.Errorf("Unexpected account %+v", acc)
+		}
+	}
+}
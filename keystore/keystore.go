@@ -12,12 +12,13 @@ import (
 )
 
 const (
-	EnvPassword = "KEYSTORE_PASSWORD"
+	EnvPassword = "KEYSTORE_PASSWORD" // password shared by all keys without a password of their own
 )
 
 var pswCache = make(map[string][]byte)
 
-func KeypairFromEth(path string) (*keystore.Key, error) {
+// KeypairFromEth decrypts the keystore of address at path, with the password from src
+func KeypairFromEth(path, address string, src PasswordSource) (*keystore.Key, error) {
 	// Make sure key exists before prompting password
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return nil, fmt.Errorf("key file not found: %s", path)
@@ -25,7 +26,10 @@ func KeypairFromEth(path string) (*keystore.Key, error) {
 
 	var pswd = pswCache[path]
 	if len(pswd) == 0 {
-		pswd = GetPassword(fmt.Sprintf("Enter password for key %s:", path))
+		var err error
+		if pswd, err = src.Password(address, path); err != nil {
+			return nil, err
+		}
 	}
 
 	file, err := os.ReadFile(path)
@@ -41,7 +45,9 @@ func KeypairFromEth(path string) (*keystore.Key, error) {
 	return ret, nil
 }
 
-func NearKeyPairFrom(networkName, path string, id types.AccountID) (kp key.KeyPair, err error) {
+// NearKeyPairFrom reads the near credentials of id, which may be encrypted like an ethereum keystore, then
+// the password is taken from src
+func NearKeyPairFrom(networkName, path string, id types.AccountID, src PasswordSource) (kp key.KeyPair, err error) {
 	var creds struct {
 		AccountID  types.AccountID     `json:"account_id"`
 		PublicKey  key.Base58PublicKey `json:"public_key"`
@@ -58,13 +64,28 @@ func NearKeyPairFrom(networkName, path string, id types.AccountID) (kp key.KeyPa
 
 	credsFile := filepath.Join(home, ".near-credentials", networkName, fmt.Sprintf("%s.json", id))
 
-	var cf *os.File
-	if cf, err = os.Open(credsFile); err != nil {
+	data, err := os.ReadFile(credsFile)
+	if err != nil {
+		return
+	}
+	var encrypted struct {
+		Crypto *keystore.CryptoJSON `json:"crypto"`
+	}
+	if err = json.Unmarshal(data, &encrypted); err != nil {
 		return
 	}
-	defer cf.Close()
+	if encrypted.Crypto != nil {
+		var pswd []byte
+		if pswd, err = src.Password(string(id), credsFile); err != nil {
+			return
+		}
+		if data, err = keystore.DecryptDataV3(*encrypted.Crypto, string(pswd)); err != nil {
+			err = fmt.Errorf("decrypt near credentials %s failed, err:%s", credsFile, err)
+			return
+		}
+	}
 
-	if err = json.NewDecoder(cf).Decode(&creds); err != nil {
+	if err = json.Unmarshal(data, &creds); err != nil {
 		return
 	}
 
@@ -0,0 +1,65 @@
+package keystore
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	terminal "golang.org/x/term"
+)
+
+// PasswordSource is where the password of a key is looked up. The sources are tried in order: the env var of
+// the key, the password file, the provisioned passwords, the shared KEYSTORE_PASSWORD env var and finally the
+// prompt, which is only shown when stdin is a terminal. The prompt is replaced by waiting for the password to be
+// provisioned when AwaitProvisioning is set
+type PasswordSource struct {
+	Env  string // env var holding the password of this key, KeyEnv of the key address if empty
+	File string // file holding the password, a trailing newline is ignored
+}
+
+// KeyEnv returns the default env var of the password of a key, KEYSTORE_PASSWORD_ followed by the
+// upper-cased address or account of the key without 0x
+func KeyEnv(address string) string {
+	address = strings.TrimPrefix(strings.TrimPrefix(address, "0x"), "0X")
+	return EnvPassword + "_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, address)
+}
+
+// Password returns the password of the key of address stored at path
+func (s PasswordSource) Password(address, path string) ([]byte, error) {
+	env := s.Env
+	if env == "" {
+		env = KeyEnv(address)
+	}
+	if v := os.Getenv(env); v != "" {
+		return []byte(v), nil
+	}
+	if s.File != "" {
+		data, err := os.ReadFile(s.File)
+		if err != nil {
+			return nil, fmt.Errorf("read password file of key %s failed, err:%s", path, err)
+		}
+		return []byte(strings.TrimRight(string(data), "\r\n")), nil
+	}
+	if v, ok := provisionedPassword(address, false); ok {
+		return v, nil
+	}
+	if v := os.Getenv(EnvPassword); v != "" {
+		return []byte(v), nil
+	}
+	if v, ok := provisionedPassword(address, true); ok {
+		return v, nil
+	}
+	if !terminal.IsTerminal(int(syscall.Stdin)) {
+		return nil, fmt.Errorf("no password for key %s, set %s or %s, or a password file", path, env, EnvPassword)
+	}
+	return GetPassword(fmt.Sprintf("Enter password for key %s:", path)), nil
+}
@@ -0,0 +1,117 @@
+package keystore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+)
+
+func TestKeyEnv(t *testing.T) {
+	if env := KeyEnv("0xAbc123"); env != "KEYSTORE_PASSWORD_ABC123" {
+		t.Errorf("Unexpected env %s", env)
+	}
+	if env := KeyEnv("relayer.near"); env != "KEYSTORE_PASSWORD_RELAYER_NEAR" {
+		t.Errorf("Unexpected env %s", env)
+	}
+}
+
+func TestPasswordOrder(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("from file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvPassword, "shared")
+
+	cases := []struct {
+		name string
+		src  PasswordSource
+		env  map[string]string
+		want string
+	}{
+		{"shared", PasswordSource{}, nil, "shared"},
+		{"file", PasswordSource{File: file}, nil, "from file"},
+		{"key env", PasswordSource{File: file}, map[string]string{"KEYSTORE_PASSWORD_AB": "key"}, "key"},
+		{"configured env", PasswordSource{Env: "RELAYER_PASSWORD", File: file},
+			map[string]string{"RELAYER_PASSWORD": "configured", "KEYSTORE_PASSWORD_AB": "key"}, "configured"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for k, v := range c.env {
+				t.Setenv(k, v)
+			}
+			pswd, err := c.src.Password("0xab", "key.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(pswd) != c.want {
+				t.Errorf("password = %q, want %q", pswd, c.want)
+			}
+		})
+	}
+}
+
+func TestPasswordMissing(t *testing.T) {
+	t.Setenv(EnvPassword, "")
+	if _, err := (PasswordSource{}).Password("0xab", "key.json"); err == nil {
+		t.Error("Expected a missing password to fail without a terminal")
+	}
+	if _, err := (PasswordSource{File: filepath.Join(t.TempDir(), "none")}).Password("0xab", "key.json"); err == nil {
+		t.Error("Expected a missing password file to fail")
+	}
+}
+
+func TestKeypairFromEth(t *testing.T) {
+	pk, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := &keystore.Key{Id: uuid.New(), Address: crypto.PubkeyToAddress(pk.PublicKey), PrivateKey: pk}
+	data, err := keystore.EncryptKey(key, "secret", keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.json")
+	if err = os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(EnvPassword, "")
+	t.Setenv(KeyEnv(key.Address.Hex()), "secret")
+	got, err := KeypairFromEth(path, key.Address.Hex(), PasswordSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Address != key.Address {
+		t.Errorf("Unexpected address %s", got.Address)
+	}
+}
+
+func TestProvisionedPassword(t *testing.T) {
+	t.Setenv(EnvPassword, "shared")
+	Provision("0xCD", []byte("provisioned"))
+	pswd, err := (PasswordSource{}).Password("0xcd", "key.json")
+	if err != nil || string(pswd) != "provisioned" {
+		t.Errorf("Expected the provisioned password before the shared one, got %q, err %v", pswd, err)
+	}
+
+	t.Setenv(EnvPassword, "")
+	AwaitProvisioning(time.Second)
+	defer AwaitProvisioning(0)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		Provision("0xef", []byte("late"))
+	}()
+	if pswd, err = (PasswordSource{}).Password("0xEF", "key.json"); err != nil || string(pswd) != "late" {
+		t.Errorf("Expected to wait for the password, got %q, err %v", pswd, err)
+	}
+
+	AwaitProvisioning(50 * time.Millisecond)
+	if _, err = (PasswordSource{}).Password("0x12", "key.json"); err == nil {
+		t.Error("Expected a password never provisioned to fail")
+	}
+}
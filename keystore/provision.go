@@ -0,0 +1,60 @@
+package keystore
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// provisioned holds the passwords provisioned at runtime, such as over the attestation server, by lower-cased address
+var provisioned = struct {
+	sync.Mutex
+	passwords map[string][]byte
+	changed   chan struct{} // closed and replaced when a password is provisioned
+	wait      time.Duration // how long Password waits for a missing password, 0 doesn't wait
+}{passwords: make(map[string][]byte), changed: make(chan struct{})}
+
+// Provision stores the password of the key of address, Password uses it before the shared KEYSTORE_PASSWORD env var.
+// Only the first password of a key is kept, false is returned if it already has one.
+func Provision(address string, password []byte) bool {
+	provisioned.Lock()
+	defer provisioned.Unlock()
+	key := strings.ToLower(address)
+	if _, ok := provisioned.passwords[key]; ok {
+		return false
+	}
+	provisioned.passwords[key] = append([]byte(nil), password...)
+	close(provisioned.changed)
+	provisioned.changed = make(chan struct{})
+	return true
+}
+
+// AwaitProvisioning makes Password wait up to d for the password of a key to be provisioned when no other source
+// has it, instead of prompting
+func AwaitProvisioning(d time.Duration) {
+	provisioned.Lock()
+	defer provisioned.Unlock()
+	provisioned.wait = d
+}
+
+// provisionedPassword returns the provisioned password of address, waiting for it if wait is set
+func provisionedPassword(address string, wait bool) ([]byte, bool) {
+	var timeout <-chan time.Time
+	for {
+		provisioned.Lock()
+		pswd, ok := provisioned.passwords[strings.ToLower(address)]
+		changed, d := provisioned.changed, provisioned.wait
+		provisioned.Unlock()
+		if ok || !wait || d == 0 {
+			return pswd, ok
+		}
+		if timeout == nil {
+			timeout = time.After(d)
+		}
+		select {
+		case <-changed:
+		case <-timeout:
+			return nil, false
+		}
+	}
+}
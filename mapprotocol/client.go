@@ -16,8 +16,8 @@ import (
 	maptypes "github.com/mapprotocol/atlas/core/types"
 	"github.com/mapprotocol/compass/internal/constant"
 	"github.com/mapprotocol/compass/msg"
+	"github.com/mapprotocol/compass/pkg/alert"
 	"github.com/mapprotocol/compass/pkg/ethclient"
-	"github.com/mapprotocol/compass/pkg/util"
 )
 
 func GetMapTransactionsHashByBlockNumber(conn *ethclient.Client, number *big.Int) ([]common.Hash, error) {
@@ -109,7 +109,8 @@ func GetZkProof(endpoint string, cid msg.ChainId, height uint64) ([]*big.Int, er
 	for {
 		resp, err := http.Get(fmt.Sprintf("%s/proof?chain_id=%d&height=%d", endpoint, cid, height))
 		if err != nil {
-			util.Alarm(context.Background(), fmt.Sprintf("GetZkProof cid(%d) request failed, err is %v", height, err))
+			alert.Fire(context.Background(), alert.Warning, OnlineChaId[cid], alert.KeyZkProof,
+				fmt.Sprintf("GetZkProof cid(%d) height(%d) request failed, err is %v", cid, height, err))
 			log.Error("GetZkProof request failed", "err", err, "height", height, "cid", cid)
 			time.Sleep(constant.BlockRetryInterval)
 			continue
@@ -124,14 +125,16 @@ func GetZkProof(endpoint string, cid msg.ChainId, height uint64) ([]*big.Int, er
 		zk := &Zk{}
 		err = json.Unmarshal(body, zk)
 		if err != nil {
-			util.Alarm(context.Background(), fmt.Sprintf("GetZkProof cid(%d) Unmarshal failed, err is %v", height, err))
+			alert.Fire(context.Background(), alert.Warning, OnlineChaId[cid], alert.KeyZkProof,
+				fmt.Sprintf("GetZkProof cid(%d) height(%d) Unmarshal failed, err is %v", cid, height, err))
 			log.Error("GetZkProof Unmarshal failed", "err", err, "data", string(body))
 			time.Sleep(constant.BlockRetryInterval)
 			continue
 		}
+		alert.Resolve(context.Background(), OnlineChaId[cid], alert.KeyZkProof)
 		// check status
 		if zk.Data.Status != 3 {
-			//util.Alarm(context.Background(), fmt.Sprintf("GetZkProof cid(%d) height(%d) Proof Not Ready", cid, height))
+			//alert.Fire(context.Background(), alert.Info, OnlineChaId[cid], alert.KeyZkProof, fmt.Sprintf("GetZkProof cid(%d) height(%d) Proof Not Ready", cid, height))
 			log.Info("GetZkProof Proof Not Read", "cid", cid, "height", height)
 			time.Sleep(constant.BalanceRetryInterval)
 			continue
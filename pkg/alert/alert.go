@@ -0,0 +1,344 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package alert
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/ChainSafe/log15"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Defaults of the alerting config
+const (
+	DefaultDedupWindow = 5 * time.Minute
+	DefaultRateLimit   = 30 // notifications per sink and minute
+)
+
+const (
+	queueSize   = 64               // notifications waiting for each sink, more are dropped
+	sendTimeout = 30 * time.Second // of a single notification
+)
+
+// Severity of an alert
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Critical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Critical:
+		return "critical"
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// ParseSeverity parses info, warning or critical, an empty string is info
+func ParseSeverity(v string) (Severity, error) {
+	switch strings.ToLower(v) {
+	case "", "info":
+		return Info, nil
+	case "warning", "warn":
+		return Warning, nil
+	case "critical":
+		return Critical, nil
+	}
+	return Info, fmt.Errorf("unknown alert severity %q", v)
+}
+
+// Keys of the conditions alerted on
+const (
+	KeyListener     = "listener"      // the listener failed to handle a block
+	KeyHeaderSync   = "header-sync"   // syncing headers to a light client failed
+	KeyLightClient  = "light-client"  // updating the light client failed
+	KeyOrderId      = "order-id"      // checking whether an order was executed failed
+	KeyBalance      = "balance"       // a relayer ran low on balance, suffixed with its address
+	KeyQuorum       = "quorum"        // the endpoints of the chain disagree on a block
+	KeySignerPolicy = "signer-policy" // the signer refused a tx
+	KeyZkProof      = "zk-proof"      // requesting a zk proof failed
+	KeyMos          = "mos"           // a message failed, suffixed with its source tx
+)
+
+// Alert is a notification about a condition of a chain, identified by its key. An alert fires until it is resolved,
+// repeats of a firing alert are only notified once per dedup window.
+type Alert struct {
+	Severity Severity
+	Chain    string // name of the chain, empty for alerts about the whole process
+	Key      string // condition of the chain, e.g. header-sync
+	Text     string
+	Resolved bool // the notification tells the condition cleared
+	Time     time.Time
+}
+
+// Sink delivers notifications
+type Sink interface {
+	Send(ctx context.Context, env string, a Alert) error
+}
+
+var alarms = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "compass",
+	Name:      "alarms_total",
+	Help:      "Number of alarms fired, repeats of a firing alarm within the dedup window are not counted",
+}, []string{"severity"})
+
+func init() {
+	prometheus.MustRegister(alarms)
+}
+
+type route struct {
+	chains   map[string]bool // all chains if empty
+	severity Severity        // lowest severity routed
+	sinks    []string
+}
+
+func (r route) match(a Alert) bool {
+	return a.Severity >= r.severity && (len(r.chains) == 0 || r.chains[strings.ToLower(a.Chain)])
+}
+
+type firing struct {
+	alert    Alert
+	notified time.Time
+}
+
+// Alerter routes alerts to sinks, deduplicates them and limits the rate of notifications of each sink. Each sink
+// delivers its notifications in the background, so a slow sink never blocks the caller or the other sinks.
+type Alerter struct {
+	env     string
+	window  time.Duration
+	limit   int
+	sinks   map[string]Sink
+	names   []string // sink names in config order
+	routes  []route
+	log     log.Logger
+	queues  map[string]chan Alert // notifications waiting for each sink
+	pending sync.WaitGroup        // notifications queued but not delivered yet
+
+	lock    sync.Mutex
+	firing  map[string]*firing     // firing alerts by chain and key
+	sent    map[string][]time.Time // notifications of each sink within the last minute
+	dropped map[string]int         // notifications dropped by the rate limit of each sink since its last one
+}
+
+// New returns an alerter of the sinks and routes of cfg, env prefixes every notification
+func New(env string, cfg Config) (*Alerter, error) {
+	a := &Alerter{
+		env:     env,
+		window:  DefaultDedupWindow,
+		limit:   DefaultRateLimit,
+		sinks:   make(map[string]Sink),
+		queues:  make(map[string]chan Alert),
+		log:     log.New("system", "alert"),
+		firing:  make(map[string]*firing),
+		sent:    make(map[string][]time.Time),
+		dropped: make(map[string]int),
+	}
+	if cfg.DedupWindow != "" {
+		d, err := time.ParseDuration(cfg.DedupWindow)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alert dedupWindow %q", cfg.DedupWindow)
+		}
+		a.window = d
+	}
+	if cfg.RateLimit != 0 {
+		a.limit = cfg.RateLimit
+	}
+	for _, sc := range cfg.Sinks {
+		if _, ok := a.sinks[sc.Name]; ok || sc.Name == "" {
+			return nil, fmt.Errorf("alert sink name %q is empty or not unique", sc.Name)
+		}
+		s, err := NewSink(sc)
+		if err != nil {
+			return nil, fmt.Errorf("alert sink %s: %w", sc.Name, err)
+		}
+		a.sinks[sc.Name] = s
+		a.names = append(a.names, sc.Name)
+	}
+	for _, name := range a.names {
+		q := make(chan Alert, queueSize)
+		a.queues[name] = q
+		go a.deliver(name, q)
+	}
+	for i, rc := range cfg.Routes {
+		sev, err := ParseSeverity(rc.Severity)
+		if err != nil {
+			return nil, fmt.Errorf("alert route %d: %w", i, err)
+		}
+		r := route{chains: make(map[string]bool), severity: sev, sinks: rc.Sinks}
+		for _, c := range rc.Chains {
+			r.chains[strings.ToLower(c)] = true
+		}
+		for _, name := range rc.Sinks {
+			if _, ok := a.sinks[name]; !ok {
+				return nil, fmt.Errorf("alert route %d: unknown sink %s", i, name)
+			}
+		}
+		a.routes = append(a.routes, r)
+	}
+	return a, nil
+}
+
+// sinksOf returns the names of the sinks alert is routed to, every sink if there are no routes
+func (a *Alerter) sinksOf(alert Alert) []string {
+	if len(a.routes) == 0 {
+		return a.names
+	}
+	var (
+		ret  []string
+		seen = make(map[string]bool)
+	)
+	for _, r := range a.routes {
+		if !r.match(alert) {
+			continue
+		}
+		for _, name := range r.sinks {
+			if !seen[name] {
+				seen[name] = true
+				ret = append(ret, name)
+			}
+		}
+	}
+	return ret
+}
+
+// Fire raises the alert, it is not notified again while it fires within the dedup window
+func (a *Alerter) Fire(ctx context.Context, alert Alert) {
+	id := alert.Chain + "/" + alert.Key
+	now := time.Now()
+	a.lock.Lock()
+	if f, ok := a.firing[id]; ok && now.Sub(f.notified) < a.window {
+		a.lock.Unlock()
+		return
+	}
+	alert.Time, alert.Resolved = now, false
+	a.firing[id] = &firing{alert: alert, notified: now}
+	a.lock.Unlock()
+
+	alarms.WithLabelValues(alert.Severity.String()).Inc()
+	a.notify(ctx, alert)
+}
+
+// Resolve clears the alert of key on chain, a resolved notification is sent if it was firing
+func (a *Alerter) Resolve(ctx context.Context, chain, key string) {
+	id := chain + "/" + key
+	a.lock.Lock()
+	f, ok := a.firing[id]
+	delete(a.firing, id)
+	a.lock.Unlock()
+	if !ok {
+		return
+	}
+	alert := f.alert
+	alert.Time, alert.Resolved = time.Now(), true
+	a.notify(ctx, alert)
+}
+
+// notify queues alert for its sinks, ctx is only used by the caller as the delivery outlives it
+func (a *Alerter) notify(_ context.Context, alert Alert) {
+	names := a.sinksOf(alert)
+	if len(names) == 0 {
+		a.log.Warn("Alert has no sink", "severity", alert.Severity, "chain", alert.Chain, "key", alert.Key,
+			"resolved", alert.Resolved, "text", alert.Text)
+		return
+	}
+	for _, name := range names {
+		dropped, ok := a.allow(name)
+		if !ok {
+			a.log.Warn("Alert sink rate limit reached, notification dropped", "sink", name, "chain", alert.Chain, "key", alert.Key)
+			continue
+		}
+		n := alert
+		if dropped > 0 {
+			n.Text = fmt.Sprintf("%s (%d notifications dropped by the rate limit)", n.Text, dropped)
+		}
+		a.pending.Add(1)
+		select {
+		case a.queues[name] <- n:
+		default:
+			a.pending.Done()
+			a.log.Warn("Alert sink queue is full, notification dropped", "sink", name, "chain", alert.Chain, "key", alert.Key)
+		}
+	}
+}
+
+// deliver sends the notifications queued for the sink, each within sendTimeout
+func (a *Alerter) deliver(name string, queue <-chan Alert) {
+	for alert := range queue {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		err := a.sinks[name].Send(ctx, a.env, alert)
+		cancel()
+		if err != nil {
+			a.log.Warn("Send alert failed", "sink", name, "chain", alert.Chain, "key", alert.Key, "err", err)
+		} else {
+			a.log.Info("Alert sent", "sink", name, "severity", alert.Severity, "chain", alert.Chain, "key", alert.Key,
+				"resolved", alert.Resolved)
+		}
+		a.pending.Done()
+	}
+}
+
+// allow takes a notification of the sink from its rate limit, it returns the number of notifications dropped since
+// the previous one
+func (a *Alerter) allow(sink string) (int, bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	now := time.Now()
+	sent := a.sent[sink]
+	for len(sent) > 0 && now.Sub(sent[0]) >= time.Minute {
+		sent = sent[1:]
+	}
+	if a.limit > 0 && len(sent) >= a.limit {
+		a.sent[sink] = sent
+		a.dropped[sink]++
+		return 0, false
+	}
+	a.sent[sink] = append(sent, now)
+	dropped := a.dropped[sink]
+	a.dropped[sink] = 0
+	return dropped, true
+}
+
+var (
+	lock   sync.RWMutex
+	std, _ = New("", Config{}) // without sinks until Init
+)
+
+// Init sets the alerter of the process
+func Init(env string, cfg Config) error {
+	a, err := New(env, cfg)
+	if err != nil {
+		return err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	std = a
+	return nil
+}
+
+func current() *Alerter {
+	lock.RLock()
+	defer lock.RUnlock()
+	return std
+}
+
+// Fire raises an alert of severity about the condition key of chain with the alerter of the process
+func Fire(ctx context.Context, severity Severity, chain, key, text string) {
+	current().Fire(ctx, Alert{Severity: severity, Chain: chain, Key: key, Text: text})
+}
+
+// Resolve clears the alert of key on chain with the alerter of the process
+func Resolve(ctx context.Context, chain, key string) {
+	current().Resolve(ctx, chain, key)
+}
@@ -0,0 +1,405 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package alert
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	lock    sync.Mutex
+	alerts  []Alert
+	pending *sync.WaitGroup // of the alerter, waited for before reading the alerts
+}
+
+func (r *recorder) Send(_ context.Context, _ string, a Alert) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.alerts = append(r.alerts, a)
+	return nil
+}
+
+func (r *recorder) sent() []Alert {
+	r.pending.Wait()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]Alert(nil), r.alerts...)
+}
+
+// newAlerter returns an alerter of cfg whose sinks are replaced by recorders
+func newAlerter(t *testing.T, cfg Config) (*Alerter, map[string]*recorder) {
+	t.Helper()
+	a, err := New("test", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorders := make(map[string]*recorder)
+	for name := range a.sinks {
+		recorders[name] = &recorder{pending: &a.pending}
+		a.sinks[name] = recorders[name]
+	}
+	return a, recorders
+}
+
+func webhooks(names ...string) []SinkConfig {
+	ret := make([]SinkConfig, 0, len(names))
+	for _, name := range names {
+		ret = append(ret, SinkConfig{Name: name, Type: SinkWebhook, Url: "http://127.0.0.1:0"})
+	}
+	return ret
+}
+
+func TestNew(t *testing.T) {
+	for _, cfg := range []Config{
+		{Sinks: []SinkConfig{{Name: "a", Type: "pager"}}},
+		{Sinks: []SinkConfig{{Name: "a", Type: SinkSlack}}},
+		{Sinks: []SinkConfig{{Name: "a", Type: SinkTelegram, Token: "t"}}},
+		{Sinks: []SinkConfig{{Name: "a", Type: SinkEmail, Smtp: "localhost:25", From: "compass@example.com"}}},
+		{Sinks: append(webhooks("a"), webhooks("a")...)},
+		{Sinks: webhooks("a"), Routes: []RouteConfig{{Sinks: []string{"b"}}}},
+		{Sinks: webhooks("a"), Routes: []RouteConfig{{Severity: "fatal", Sinks: []string{"a"}}}},
+		{DedupWindow: "5"},
+	} {
+		if _, err := New("", cfg); err == nil {
+			t.Errorf("Expected config %+v to fail", cfg)
+		}
+	}
+}
+
+func TestRouting(t *testing.T) {
+	a, sinks := newAlerter(t, Config{
+		Sinks: webhooks("ops", "eth", "pager"),
+		Routes: []RouteConfig{
+			{Sinks: []string{"ops"}},
+			{Chains: []string{"eth"}, Sinks: []string{"eth"}},
+			{Severity: "critical", Sinks: []string{"pager", "ops"}},
+		},
+	})
+	ctx := context.Background()
+	a.Fire(ctx, Alert{Severity: Warning, Chain: "bsc", Key: "listener", Text: "mos failed"})
+	a.Fire(ctx, Alert{Severity: Info, Chain: "ETH", Key: "listener", Text: "mos failed"})
+	a.Fire(ctx, Alert{Severity: Critical, Chain: "bsc", Key: "quorum", Text: "endpoints disagree"})
+	for name, want := range map[string]int{"ops": 3, "eth": 1, "pager": 1} {
+		if got := len(sinks[name].sent()); got != want {
+			t.Errorf("Expected %d alerts on %s, got %d", want, name, got)
+		}
+	}
+	if got := sinks["pager"].sent()[0]; got.Key != "quorum" || got.Chain != "bsc" {
+		t.Errorf("Unexpected alert on pager %+v", got)
+	}
+}
+
+func TestDedupAndResolve(t *testing.T) {
+	a, sinks := newAlerter(t, Config{Sinks: webhooks("ops")})
+	ctx := context.Background()
+	a.Resolve(ctx, "bsc", "listener")
+	a.Fire(ctx, Alert{Severity: Warning, Chain: "bsc", Key: "listener", Text: "block 1 failed"})
+	a.Fire(ctx, Alert{Severity: Warning, Chain: "bsc", Key: "listener", Text: "block 2 failed"})
+	a.Fire(ctx, Alert{Severity: Warning, Chain: "eth", Key: "listener", Text: "block 1 failed"})
+	if got := sinks["ops"].sent(); len(got) != 2 {
+		t.Fatalf("Expected a firing alert to be sent once per chain, got %+v", got)
+	}
+
+	a.Resolve(ctx, "bsc", "listener")
+	a.Resolve(ctx, "bsc", "listener")
+	got := sinks["ops"].sent()
+	if len(got) != 3 || !got[2].Resolved || got[2].Chain != "bsc" || got[2].Text != "block 1 failed" {
+		t.Fatalf("Expected a single resolved notification, got %+v", got)
+	}
+
+	a.Fire(ctx, Alert{Severity: Warning, Chain: "bsc", Key: "listener", Text: "block 3 failed"})
+	if got = sinks["ops"].sent(); len(got) != 4 || got[3].Resolved {
+		t.Errorf("Expected a resolved alert to fire again, got %+v", got)
+	}
+
+	a.window = time.Millisecond
+	time.Sleep(2 * time.Millisecond)
+	a.Fire(ctx, Alert{Severity: Warning, Chain: "eth", Key: "listener", Text: "block 2 failed"})
+	if got = sinks["ops"].sent(); len(got) != 5 {
+		t.Errorf("Expected an alert to repeat after the dedup window, got %+v", got)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	a, sinks := newAlerter(t, Config{RateLimit: 2, Sinks: webhooks("ops")})
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		a.Fire(ctx, Alert{Severity: Critical, Chain: "bsc", Key: fmt.Sprintf("mos/%d", i), Text: "mos failed"})
+	}
+	if got := sinks["ops"].sent(); len(got) != 2 {
+		t.Fatalf("Expected the rate limit to drop notifications, got %+v", got)
+	}
+
+	a.lock.Lock()
+	for i := range a.sent["ops"] {
+		a.sent["ops"][i] = a.sent["ops"][i].Add(-time.Minute)
+	}
+	a.lock.Unlock()
+	a.Resolve(ctx, "bsc", "mos/4")
+	got := sinks["ops"].sent()
+	if len(got) != 3 || !got[2].Resolved || !strings.Contains(got[2].Text, "(3 notifications dropped by the rate limit)") {
+		t.Errorf("Expected the next notification to tell the dropped count, got %+v", got)
+	}
+}
+
+func TestConcurrentFire(t *testing.T) {
+	a, sinks := newAlerter(t, Config{Sinks: webhooks("ops")})
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			a.Fire(context.Background(), Alert{Severity: Warning, Chain: "bsc", Key: "listener", Text: fmt.Sprintf("block %d failed", i)})
+			a.Fire(context.Background(), Alert{Severity: Warning, Chain: "eth", Key: fmt.Sprintf("mos/%d", i), Text: "mos failed"})
+		}(i)
+	}
+	wg.Wait()
+	got := sinks["ops"].sent()
+	bsc := 0
+	for _, alert := range got {
+		if alert.Chain == "bsc" {
+			bsc++
+		}
+	}
+	if len(got) != DefaultRateLimit || bsc != 1 {
+		t.Errorf("Expected the rate limit of alerts with a single bsc one, got %d with %d bsc", len(got), bsc)
+	}
+}
+
+// blocking is a sink which never answers until released
+type blocking chan struct{}
+
+func (b blocking) Send(ctx context.Context, _ string, _ Alert) error {
+	select {
+	case <-b:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func TestSlowSink(t *testing.T) {
+	a, sinks := newAlerter(t, Config{RateLimit: -1, Sinks: webhooks("slow", "ops")})
+	slow := make(blocking)
+	a.sinks["slow"] = slow
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < queueSize+10; i++ {
+			a.Fire(context.Background(), Alert{Severity: Warning, Chain: "bsc", Key: fmt.Sprintf("mos/%d", i), Text: "mos failed"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a slow sink not to block firing alerts")
+	}
+	close(slow)
+	if got := sinks["ops"].sent(); len(got) < queueSize {
+		t.Errorf("Expected the alerts on the other sink, got %d", len(got))
+	}
+}
+
+func TestFormat(t *testing.T) {
+	a := Alert{Severity: Critical, Chain: "eth", Key: "mos/0x01", Text: "mos eth2map failed"}
+	if got := Format("prod", a); got != "prod [CRITICAL] eth: mos eth2map failed" {
+		t.Errorf("Unexpected notification %q", got)
+	}
+	a.Resolved, a.Chain = true, ""
+	if got := Format("", a); got != "[RESOLVED] mos eth2map failed" {
+		t.Errorf("Unexpected resolved notification %q", got)
+	}
+}
+
+// stub serves a single request and returns its path and JSON body
+func stub(t *testing.T, response string) (*httptest.Server, func() (string, map[string]interface{})) {
+	t.Helper()
+	var (
+		path string
+		body map[string]interface{}
+		done = make(chan struct{}, 1)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		_, _ = w.Write([]byte(response))
+		done <- struct{}{}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() (string, map[string]interface{}) {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Stub server got no request")
+		}
+		return path, body
+	}
+}
+
+func TestSinks(t *testing.T) {
+	ctx := context.Background()
+	a := Alert{Severity: Warning, Chain: "bsc", Key: "listener", Text: "mos failed", Time: time.Now()}
+
+	srv, req := stub(t, "ok")
+	if err := (Slack{Url: srv.URL}).Send(ctx, "prod", a); err != nil {
+		t.Fatal(err)
+	}
+	if _, body := req(); body["text"] != "prod [WARNING] bsc: mos failed" {
+		t.Errorf("Unexpected slack body %v", body)
+	}
+
+	srv, req = stub(t, `{"code":0,"msg":"success"}`)
+	if err := (Lark{Url: srv.URL}).Send(ctx, "prod", a); err != nil {
+		t.Fatal(err)
+	}
+	if _, body := req(); body["msg_type"] != "text" || body["content"].(map[string]interface{})["text"] != "prod [WARNING] bsc: mos failed" {
+		t.Errorf("Unexpected lark body %v", body)
+	}
+	srv, _ = stub(t, `{"code":19001,"msg":"param invalid"}`)
+	if err := (Lark{Url: srv.URL}).Send(ctx, "prod", a); err == nil || !strings.Contains(err.Error(), "19001") {
+		t.Errorf("Expected a lark error code to fail, got %v", err)
+	}
+
+	srv, req = stub(t, `{"ok":true}`)
+	if err := (Telegram{Api: srv.URL, Token: "123:abc", ChatId: "-42"}).Send(ctx, "prod", a); err != nil {
+		t.Fatal(err)
+	}
+	if path, body := req(); path != "/bot123:abc/sendMessage" || body["chat_id"] != "-42" || body["text"] != "prod [WARNING] bsc: mos failed" {
+		t.Errorf("Unexpected telegram request %s %v", path, body)
+	}
+
+	srv, req = stub(t, "")
+	a.Resolved = true
+	if err := (Webhook{Url: srv.URL}).Send(ctx, "prod", a); err != nil {
+		t.Fatal(err)
+	}
+	if _, body := req(); body["env"] != "prod" || body["severity"] != "warning" || body["chain"] != "bsc" ||
+		body["key"] != "listener" || body["resolved"] != true {
+		t.Errorf("Unexpected webhook body %v", body)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad token "+r.URL.Path, http.StatusUnauthorized)
+	}))
+	defer failing.Close()
+	err := (Telegram{Api: failing.URL, Token: "123:abc", ChatId: "-42"}).Send(ctx, "", a)
+	if err == nil || strings.Contains(err.Error(), "123:abc") {
+		t.Errorf("Expected a failed request without the token, got %v", err)
+	}
+}
+
+// smtpStub accepts a single mail and returns its recipients and data
+func smtpStub(t *testing.T) (string, func() ([]string, string)) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	type mail struct {
+		to   []string
+		data string
+	}
+	done := make(chan mail, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var (
+			m      mail
+			r      = bufio.NewReader(conn)
+			inData bool
+		)
+		reply := func(s string) { _, _ = fmt.Fprintf(conn, "%s\r\n", s) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					reply("250 OK")
+					continue
+				}
+				m.data += line
+				continue
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				m.to = append(m.to, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case cmd == "DATA":
+				inData = true
+				reply("354 go ahead")
+			case cmd == "QUIT":
+				reply("221 bye")
+				done <- m
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return l.Addr().String(), func() ([]string, string) {
+		select {
+		case m := <-done:
+			return m.to, m.data
+		case <-time.After(time.Second):
+			t.Fatal("SMTP stub got no mail")
+		}
+		return nil, ""
+	}
+}
+
+func TestEmail(t *testing.T) {
+	addr, mail := smtpStub(t)
+	s, err := NewSink(SinkConfig{Name: "mail", Type: SinkEmail, Smtp: addr, From: "compass@example.com",
+		To: []string{"ops@example.com", "dev@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := Alert{Severity: Critical, Chain: "eth", Key: "quorum", Text: "endpoints disagree", Time: time.Now()}
+	if err = s.Send(context.Background(), "prod", a); err != nil {
+		t.Fatal(err)
+	}
+	to, data := mail()
+	if len(to) != 2 || to[0] != "ops@example.com" || to[1] != "dev@example.com" {
+		t.Errorf("Unexpected recipients %v", to)
+	}
+	if !strings.Contains(data, "Subject: prod [CRITICAL] eth: endpoints disagree\r\n") || !strings.Contains(data, "key: quorum") {
+		t.Errorf("Unexpected mail %q", data)
+	}
+
+	// A server which never greets fails the send once ctx expires
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	s = Email{Addr: l.Addr().String(), From: "compass@example.com", To: []string{"ops@example.com"}}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err = s.Send(ctx, "prod", a); err == nil || time.Since(start) > time.Second {
+		t.Errorf("Expected the send to time out, got %v after %v", err, time.Since(start))
+	}
+}
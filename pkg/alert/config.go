@@ -0,0 +1,42 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package alert
+
+// Config is the alert section of the config file
+type Config struct {
+	DedupWindow string        `json:"dedupWindow,omitempty"` // e.g. 5m, DefaultDedupWindow if empty
+	RateLimit   int           `json:"rateLimit,omitempty"`   // notifications per sink and minute, DefaultRateLimit if 0, unlimited if negative
+	Sinks       []SinkConfig  `json:"sinks,omitempty"`
+	Routes      []RouteConfig `json:"routes,omitempty"` // alerts go to every sink if empty
+}
+
+// Types of sinks
+const (
+	SinkSlack    = "slack"
+	SinkLark     = "lark"
+	SinkTelegram = "telegram"
+	SinkWebhook  = "webhook"
+	SinkEmail    = "email"
+)
+
+// SinkConfig configures a sink, the fields used depend on its type
+type SinkConfig struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Url      string   `json:"url,omitempty"`      // slack, lark and webhook url, telegram api url
+	Token    string   `json:"token,omitempty"`    // telegram bot token
+	ChatId   string   `json:"chatId,omitempty"`   // telegram chat
+	Smtp     string   `json:"smtp,omitempty"`     // smtp server host:port
+	Username string   `json:"username,omitempty"` // smtp username, no authentication if empty
+	Password string   `json:"password,omitempty"` // smtp password
+	From     string   `json:"from,omitempty"`     // email sender
+	To       []string `json:"to,omitempty"`       // email recipients
+}
+
+// RouteConfig sends the alerts of chains with at least severity to sinks
+type RouteConfig struct {
+	Chains   []string `json:"chains,omitempty"`   // chain names, every chain if empty
+	Severity string   `json:"severity,omitempty"` // info, warning or critical, info if empty
+	Sinks    []string `json:"sinks"`
+}
@@ -0,0 +1,244 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// DefaultTelegramApi is the url of the telegram bot api
+const DefaultTelegramApi = "https://api.telegram.org"
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// NewSink returns the sink configured by cfg
+func NewSink(cfg SinkConfig) (Sink, error) {
+	switch strings.ToLower(cfg.Type) {
+	case SinkSlack, SinkLark, SinkWebhook:
+		if cfg.Url == "" {
+			return nil, errors.New("url is required")
+		}
+		switch strings.ToLower(cfg.Type) {
+		case SinkSlack:
+			return Slack{Url: cfg.Url}, nil
+		case SinkLark:
+			return Lark{Url: cfg.Url}, nil
+		}
+		return Webhook{Url: cfg.Url}, nil
+	case SinkTelegram:
+		if cfg.Token == "" || cfg.ChatId == "" {
+			return nil, errors.New("token and chatId are required")
+		}
+		api := cfg.Url
+		if api == "" {
+			api = DefaultTelegramApi
+		}
+		return Telegram{Api: api, Token: cfg.Token, ChatId: cfg.ChatId}, nil
+	case SinkEmail:
+		if cfg.Smtp == "" || cfg.From == "" || len(cfg.To) == 0 {
+			return nil, errors.New("smtp, from and to are required")
+		}
+		return Email{Addr: cfg.Smtp, Username: cfg.Username, Password: cfg.Password, From: cfg.From, To: cfg.To}, nil
+	}
+	return nil, fmt.Errorf("unknown sink type %q", cfg.Type)
+}
+
+// Format returns the text of a notification, e.g. "prod [CRITICAL] eth: mos eth2map failed"
+func Format(env string, a Alert) string {
+	status := strings.ToUpper(a.Severity.String())
+	if a.Resolved {
+		status = "RESOLVED"
+	}
+	text := fmt.Sprintf("[%s] %s", status, a.Text)
+	if a.Chain != "" {
+		text = fmt.Sprintf("[%s] %s: %s", status, a.Chain, a.Text)
+	}
+	if env != "" {
+		text = env + " " + text
+	}
+	return text
+}
+
+func postJSON(ctx context.Context, url string, body interface{}) ([]byte, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	ret, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(ret)))
+	}
+	return ret, nil
+}
+
+// Slack posts to an incoming webhook, other chat tools accepting {"text": ...} work too
+type Slack struct {
+	Url string
+}
+
+func (s Slack) Send(ctx context.Context, env string, a Alert) error {
+	_, err := postJSON(ctx, s.Url, map[string]string{"text": Format(env, a)})
+	return err
+}
+
+// Lark posts to a Lark or Feishu custom bot
+type Lark struct {
+	Url string
+}
+
+func (l Lark) Send(ctx context.Context, env string, a Alert) error {
+	data, err := postJSON(ctx, l.Url, map[string]interface{}{
+		"msg_type": "text",
+		"content":  map[string]string{"text": Format(env, a)},
+	})
+	if err != nil {
+		return err
+	}
+	// lark answers errors with 200 and a non zero code
+	var ret struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err = json.Unmarshal(data, &ret); err == nil && ret.Code != 0 {
+		return fmt.Errorf("lark error %d: %s", ret.Code, ret.Msg)
+	}
+	return nil
+}
+
+// Telegram sends a message to a chat through a bot
+type Telegram struct {
+	Api    string
+	Token  string
+	ChatId string
+}
+
+func (t Telegram) Send(ctx context.Context, env string, a Alert) error {
+	url := fmt.Sprintf("%s/bot%s/sendMessage", strings.TrimSuffix(t.Api, "/"), t.Token)
+	_, err := postJSON(ctx, url, map[string]string{"chat_id": t.ChatId, "text": Format(env, a)})
+	if err != nil {
+		// the url holds the token
+		return errors.New(strings.ReplaceAll(err.Error(), t.Token, "***"))
+	}
+	return nil
+}
+
+// Webhook posts the alert as JSON
+type Webhook struct {
+	Url string
+}
+
+type webhookBody struct {
+	Env      string    `json:"env,omitempty"`
+	Severity string    `json:"severity"`
+	Chain    string    `json:"chain,omitempty"`
+	Key      string    `json:"key"`
+	Text     string    `json:"text"`
+	Resolved bool      `json:"resolved"`
+	Time     time.Time `json:"time"`
+}
+
+func (w Webhook) Send(ctx context.Context, env string, a Alert) error {
+	_, err := postJSON(ctx, w.Url, webhookBody{Env: env, Severity: a.Severity.String(), Chain: a.Chain, Key: a.Key,
+		Text: a.Text, Resolved: a.Resolved, Time: a.Time})
+	return err
+}
+
+// Email sends a mail through an SMTP server, with PLAIN authentication if a username is set
+type Email struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (e Email) Send(ctx context.Context, env string, a Alert) error {
+	host, _, err := net.SplitHostPort(e.Addr)
+	if err != nil {
+		return err
+	}
+	subject := Format(env, a)
+	if i := strings.IndexByte(subject, '\n'); i >= 0 {
+		subject = subject[:i]
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n"+
+		"%s\r\n\r\nseverity: %s\r\nchain: %s\r\nkey: %s\r\ntime: %s\r\n",
+		e.From, strings.Join(e.To, ", "), subject, a.Time.Format(time.RFC1123Z),
+		a.Text, a.Severity, a.Chain, a.Key, a.Time.Format(time.RFC3339))
+	return e.send(ctx, host, []byte(msg))
+}
+
+// send delivers msg like smtp.SendMail, the connection is bounded by the deadline of ctx or by sendTimeout
+func (e Email) send(ctx context.Context, host string, msg []byte) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(sendTimeout)
+	}
+	conn, err := (&net.Dialer{Deadline: deadline}).DialContext(ctx, "tcp", e.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if e.Username != "" {
+		if err = c.Auth(smtp.PlainAuth("", e.Username, e.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(e.From); err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		if err = c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
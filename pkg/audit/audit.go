@@ -0,0 +1,272 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mapprotocol/compass/mapprotocol"
+	"github.com/mapprotocol/compass/msg"
+)
+
+const (
+	PathPostfix = ".compass/audit"
+	fileSuffix  = ".audit"
+)
+
+// Kinds of entries
+const (
+	KindSigned  = "signed"  // a tx signed by a relayer key
+	KindReceipt = "receipt" // the final status of a signed tx
+)
+
+// Receipt statuses
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// Entry is a record of the audit log. Every entry holds the hash of the previous one, so an entry which is changed,
+// removed or inserted breaks the chain of hashes.
+type Entry struct {
+	Seq       uint64      `json:"seq"`
+	Time      int64       `json:"time"`
+	Kind      string      `json:"kind"`
+	Chain     string      `json:"chain"`
+	ChainId   msg.ChainId `json:"chainId"`
+	Tx        string      `json:"tx"`
+	From      string      `json:"from,omitempty"`
+	Nonce     uint64      `json:"nonce,omitempty"`
+	To        string      `json:"to,omitempty"`
+	Selector  string      `json:"selector,omitempty"` // 4 byte selector of the calldata, or the near method
+	DataHash  string      `json:"dataHash,omitempty"` // keccak256 of the calldata
+	Gas       uint64      `json:"gas,omitempty"`      // gas limit, energy fee limit on tron
+	GasPrice  string      `json:"gasPrice,omitempty"`
+	GasTipCap string      `json:"gasTipCap,omitempty"`
+	GasFeeCap string      `json:"gasFeeCap,omitempty"`
+	DryRun    bool        `json:"dryRun,omitempty"` // signed but only simulated
+	Source    *Source     `json:"source,omitempty"` // event the tx was signed for
+	Status    string      `json:"status,omitempty"` // receipt status, StatusSuccess or StatusFailed
+	Reason    string      `json:"reason,omitempty"` // why the tx failed, if known
+	Prev      string      `json:"prev"`
+	Hash      string      `json:"hash"`
+}
+
+// Source is the event which triggered a signed tx
+type Source struct {
+	Chain   string           `json:"chain"`
+	ChainId msg.ChainId      `json:"chainId"`
+	Type    msg.TransferType `json:"type"`
+	Tx      string           `json:"tx,omitempty"`
+	OrderId string           `json:"orderId,omitempty"`
+}
+
+// SourceOf returns the source of message m, the order id and source tx are only known for cross-chain swaps
+func SourceOf(m msg.Message) *Source {
+	s := &Source{Chain: chainName(m.Source), ChainId: m.Source, Type: m.Type}
+	if m.Type == msg.SyncToMap || m.Type == msg.SyncFromMap {
+		return s
+	}
+	if len(m.Payload) > 1 {
+		switch id := m.Payload[1].(type) {
+		case []byte:
+			s.OrderId = hexutil.Encode(id)
+		case [32]byte:
+			s.OrderId = hexutil.Encode(id[:])
+		case common.Hash:
+			s.OrderId = id.Hex()
+		}
+	}
+	if len(m.Payload) > 3 && m.Payload[3] != nil {
+		s.Tx = fmt.Sprint(m.Payload[3])
+	}
+	return s
+}
+
+// DataHash returns the keccak256 of calldata, as recorded in entries
+func DataHash(data []byte) string {
+	return crypto.Keccak256Hash(data).Hex()
+}
+
+func chainName(id msg.ChainId) string {
+	if name, ok := mapprotocol.OnlineChaId[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", id)
+}
+
+var (
+	lock sync.Mutex
+	file string
+	seq  uint64
+	prev string
+)
+
+// Dir returns the directory audit logs are stored in, the home directory is used if path is empty
+func Dir(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, PathPostfix), nil
+}
+
+// Init sets the audit log of this process, dir/<role>.audit, and continues the chain of hashes of its last entry
+func Init(dir string, role mapprotocol.Role) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s%s", role, fileSuffix))
+	last, err := lastEntry(path)
+	if err != nil {
+		return err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	file, seq, prev = path, 0, ""
+	if last != nil {
+		seq, prev = last.Seq+1, last.Hash
+	}
+	return nil
+}
+
+func lastEntry(path string) (*Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var last *Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			last = &e
+		}
+	}
+	return last, scanner.Err()
+}
+
+// Signed records a tx signed for message m, e holds the fields of the tx
+func Signed(e Entry, m msg.Message) error {
+	e.Kind = KindSigned
+	e.Chain = chainName(e.ChainId)
+	e.Source = SourceOf(m)
+	return add(e)
+}
+
+// Receipt records the final status of a signed tx of chain, with the reason of a failure if known
+func Receipt(chain msg.ChainId, tx, status, reason string) error {
+	return add(Entry{Kind: KindReceipt, Chain: chainName(chain), ChainId: chain, Tx: tx, Status: status, Reason: reason})
+}
+
+func add(e Entry) error {
+	lock.Lock()
+	defer lock.Unlock()
+	if file == "" {
+		return nil
+	}
+	e.Seq, e.Prev, e.Time = seq, prev, time.Now().Unix()
+	hash, err := e.hash()
+	if err != nil {
+		return err
+	}
+	e.Hash = hash
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	seq, prev = e.Seq+1, e.Hash
+	return nil
+}
+
+// hash returns the sha256 of the entry without its hash, which covers the hash of the previous entry
+func (e Entry) hash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Files returns the audit logs of all roles stored in dir
+func Files(dir string) ([]string, error) {
+	return filepath.Glob(filepath.Join(dir, "*"+fileSuffix))
+}
+
+// ErrBroken is returned by Verify when the chain of hashes is broken
+var ErrBroken = errors.New("audit log chain of hashes is broken")
+
+// Verify checks the chain of hashes of the audit log at path and returns the number of entries
+func Verify(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var (
+		count    int
+		prevHash string
+	)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var e Entry
+		if err = json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return count, fmt.Errorf("%w: line %d is not an entry: %v", ErrBroken, line, err)
+		}
+		if e.Seq != uint64(count) {
+			return count, fmt.Errorf("%w: line %d has seq %d, want %d", ErrBroken, line, e.Seq, count)
+		}
+		if e.Prev != prevHash {
+			return count, fmt.Errorf("%w: entry %d follows %s instead of %s", ErrBroken, e.Seq, e.Prev, prevHash)
+		}
+		hash, err := e.hash()
+		if err != nil {
+			return count, err
+		}
+		if hash != e.Hash {
+			return count, fmt.Errorf("%w: entry %d was changed, its hash is %s instead of %s", ErrBroken, e.Seq, hash, e.Hash)
+		}
+		prevHash = e.Hash
+		count++
+	}
+	return count, scanner.Err()
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package audit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mapprotocol/compass/mapprotocol"
+	"github.com/mapprotocol/compass/msg"
+)
+
+func record(t *testing.T, dir string, txs ...string) string {
+	t.Helper()
+	if err := Init(dir, mapprotocol.RoleOfMessenger); err != nil {
+		t.Fatal(err)
+	}
+	m := msg.Message{Source: 1, Destination: 22776, Type: msg.SwapWithProof,
+		Payload: []interface{}{[]byte{0x01}, []byte{0xab, 0xcd}, nil, "0xsource"}}
+	for _, tx := range txs {
+		if err := Signed(Entry{ChainId: 22776, Tx: tx, Nonce: 1, DataHash: DataHash([]byte{0x01})}, m); err != nil {
+			t.Fatal(err)
+		}
+		if err := Receipt(22776, tx, StatusSuccess, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, string(mapprotocol.RoleOfMessenger)+fileSuffix)
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	record(t, dir, "0x01", "0x02")
+	// a restart continues the chain of hashes
+	file := record(t, dir, "0x03")
+
+	n, err := Verify(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 6 {
+		t.Errorf("Expected: %d got: %d", 6, n)
+	}
+	files, err := Files(dir)
+	if err != nil || len(files) != 1 || files[0] != file {
+		t.Errorf("Unexpected audit logs %v, err %v", files, err)
+	}
+}
+
+func TestVerifyBroken(t *testing.T) {
+	for name, tamper := range map[string]func(lines []string) []string{
+		"changed": func(lines []string) []string {
+			lines[2] = strings.Replace(lines[2], `"tx":"0x02"`, `"tx":"0x04"`, 1)
+			return lines
+		},
+		"removed": func(lines []string) []string {
+			return append(lines[:2], lines[4:]...)
+		},
+		"inserted": func(lines []string) []string {
+			return append(lines[:2], append([]string{lines[0]}, lines[2:]...)...)
+		},
+		"truncated": func(lines []string) []string {
+			lines[2] = lines[2][:len(lines[2])/2]
+			return lines
+		},
+	} {
+		file := record(t, t.TempDir(), "0x01", "0x02", "0x03")
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := tamper(strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"))
+		if err = os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := Verify(file); !errors.Is(err, ErrBroken) || n != 2 {
+			t.Errorf("%s: expected the chain to break after %d entries, got %d, err %v", name, 2, n, err)
+		}
+	}
+}
+
+func TestSourceOf(t *testing.T) {
+	mapprotocol.OnlineChaId[1] = "eth"
+	s := SourceOf(msg.Message{Source: 1, Type: msg.SwapWithProof,
+		Payload: []interface{}{[]byte{0x01}, []byte{0xab, 0xcd}, nil, "0xsource"}})
+	if s.Chain != "eth" || s.OrderId != "0xabcd" || s.Tx != "0xsource" {
+		t.Errorf("Unexpected source %+v", s)
+	}
+	s = SourceOf(msg.Message{Source: 1, Type: msg.SyncToMap, Payload: []interface{}{[]byte{0x01}, []byte{0x02}}})
+	if s.OrderId != "" || s.Tx != "" {
+		t.Errorf("Expected no order id and source tx of a header sync, got %+v", s)
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package cost
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/ChainSafe/log15"
+	"github.com/mapprotocol/compass/mapprotocol"
+	"github.com/mapprotocol/compass/msg"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	PathPostfix = ".compass/costs"
+	fileSuffix  = ".costs"
+)
+
+// Decimals of the native token fees are paid in
+const (
+	EvmDecimals  = 18
+	NearDecimals = 24
+	TronDecimals = 6
+)
+
+var (
+	txCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "compass",
+		Name:      "writer_tx_total",
+		Help:      "Number of confirmed writer transactions",
+	}, []string{"chain", "route", "type"})
+	gasUsed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "compass",
+		Name:      "writer_tx_gas_used_total",
+		Help:      "Gas (energy on tron) used by confirmed writer transactions",
+	}, []string{"chain", "route", "type"})
+	feeSpent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "compass",
+		Name:      "writer_tx_fee_total",
+		Help:      "Fee paid by confirmed writer transactions, in the native token of the chain",
+	}, []string{"chain", "route", "type"})
+)
+
+func init() {
+	prometheus.MustRegister(txCount, gasUsed, feeSpent)
+}
+
+// Record is the cost of a single confirmed writer transaction
+type Record struct {
+	Time     int64            `json:"time"`
+	Chain    string           `json:"chain"` // name of the chain the fee was paid on
+	ChainId  msg.ChainId      `json:"chainId"`
+	Route    string           `json:"route"`
+	Type     msg.TransferType `json:"type"`
+	Tx       string           `json:"tx"`
+	GasUsed  uint64           `json:"gasUsed"`
+	GasPrice string           `json:"gasPrice"` // effective gas price in the smallest unit
+	Fee      string           `json:"fee"`      // native fee in the smallest unit
+	Decimals int              `json:"decimals"`
+}
+
+// Route returns the name of a route in the same form as alarms use, e.g. eth2map
+func Route(src, dst msg.ChainId) string {
+	return fmt.Sprintf("%s2%s", chainName(src), chainName(dst))
+}
+
+func chainName(id msg.ChainId) string {
+	if name, ok := mapprotocol.OnlineChaId[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", id)
+}
+
+var (
+	lock sync.Mutex
+	file string
+)
+
+// Init sets the file confirmed transactions of this process are appended to
+func Init(path string, role mapprotocol.Role) error {
+	dir, err := Dir(path)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	file = filepath.Join(dir, fmt.Sprintf("%s%s", role, fileSuffix))
+	return nil
+}
+
+// Dir returns the directory cost records are stored in, the home directory is used if path is empty
+func Dir(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, PathPostfix), nil
+}
+
+// Add records the cost of a confirmed transaction of message m sent on chain
+func Add(m msg.Message, chain msg.ChainId, tx string, used uint64, price, fee *big.Int, decimals int) {
+	r := Record{
+		Time:     time.Now().Unix(),
+		Chain:    chainName(chain),
+		ChainId:  chain,
+		Route:    Route(m.Source, m.Destination),
+		Type:     m.Type,
+		Tx:       tx,
+		GasUsed:  used,
+		GasPrice: price.String(),
+		Fee:      fee.String(),
+		Decimals: decimals,
+	}
+	txCount.WithLabelValues(r.Chain, r.Route, string(r.Type)).Inc()
+	gasUsed.WithLabelValues(r.Chain, r.Route, string(r.Type)).Add(float64(used))
+	feeSpent.WithLabelValues(r.Chain, r.Route, string(r.Type)).Add(toFloat(fee, decimals))
+	if err := appendRecord(r); err != nil {
+		log.Warn("Record tx cost failed", "tx", tx, "err", err)
+	}
+}
+
+func appendRecord(r Record) error {
+	lock.Lock()
+	defer lock.Unlock()
+	if file == "" {
+		return nil
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Load reads the records of all roles stored in dir which are not older than since
+func Load(dir string, since time.Time) ([]Record, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+fileSuffix))
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]Record, 0)
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			var r Record
+			if err = json.Unmarshal([]byte(line), &r); err != nil {
+				// a crash may leave a partial last line behind
+				continue
+			}
+			if r.Time >= since.Unix() {
+				ret = append(ret, r)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// Summary is the total cost of a chain, route and message type
+type Summary struct {
+	Chain    string
+	Route    string
+	Type     msg.TransferType
+	Count    int
+	GasUsed  uint64
+	Fee      *big.Int
+	Decimals int
+}
+
+// FeeString returns the fee in the native token of the chain
+func (s *Summary) FeeString() string {
+	return new(big.Float).Quo(new(big.Float).SetInt(s.Fee), decimalsOf(s.Decimals)).Text('f', 6)
+}
+
+// Aggregate sums the records by chain, route and message type
+func Aggregate(records []Record) []*Summary {
+	idx := make(map[string]*Summary)
+	for _, r := range records {
+		key := fmt.Sprintf("%s|%s|%s", r.Chain, r.Route, r.Type)
+		s, ok := idx[key]
+		if !ok {
+			s = &Summary{Chain: r.Chain, Route: r.Route, Type: r.Type, Fee: new(big.Int), Decimals: r.Decimals}
+			idx[key] = s
+		}
+		s.Count++
+		s.GasUsed += r.GasUsed
+		if fee, ok := new(big.Int).SetString(r.Fee, 10); ok {
+			s.Fee.Add(s.Fee, fee)
+		}
+	}
+	ret := make([]*Summary, 0, len(idx))
+	for _, s := range idx {
+		ret = append(ret, s)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Chain != ret[j].Chain {
+			return ret[i].Chain < ret[j].Chain
+		}
+		if ret[i].Route != ret[j].Route {
+			return ret[i].Route < ret[j].Route
+		}
+		return ret[i].Type < ret[j].Type
+	})
+	return ret
+}
+
+func toFloat(v *big.Int, decimals int) float64 {
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(v), decimalsOf(decimals)).Float64()
+	return f
+}
+
+func decimalsOf(decimals int) *big.Float {
+	return new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+}
@@ -0,0 +1,58 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package cost
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mapprotocol/compass/mapprotocol"
+	"github.com/mapprotocol/compass/msg"
+)
+
+func TestAddLoadAndAggregate(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "costs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mapprotocol.OnlineChaId[1] = "eth"
+	mapprotocol.OnlineChaId[22776] = "map"
+	if err = Init(dir, mapprotocol.RoleOfMessenger); err != nil {
+		t.Fatal(err)
+	}
+
+	toMap := msg.Message{Source: 1, Destination: 22776, Type: msg.SwapWithProof}
+	Add(toMap, 22776, "0x01", 100, big.NewInt(2), big.NewInt(200), EvmDecimals)
+	Add(toMap, 22776, "0x02", 50, big.NewInt(2), big.NewInt(100), EvmDecimals)
+	Add(msg.Message{Source: 22776, Destination: 1, Type: msg.SyncFromMap}, 1, "0x03", 10, big.NewInt(1), big.NewInt(10), EvmDecimals)
+
+	records, err := Load(dir, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected: %d got: %d", 3, len(records))
+	}
+	if records, _ = Load(dir, time.Now().Add(time.Hour)); len(records) != 0 {
+		t.Fatalf("Expected old records to be filtered, got %d", len(records))
+	}
+
+	records, _ = Load(dir, time.Time{})
+	summaries := Aggregate(records)
+	if len(summaries) != 2 {
+		t.Fatalf("Expected: %d got: %d", 2, len(summaries))
+	}
+	s := summaries[1]
+	if s.Chain != "map" || s.Route != "eth2map" || s.Type != msg.SwapWithProof {
+		t.Fatalf("Unexpected summary %+v", s)
+	}
+	if s.Count != 2 || s.GasUsed != 150 || s.Fee.Int64() != 300 {
+		t.Fatalf("Unexpected totals %+v", s)
+	}
+}
@@ -0,0 +1,246 @@
+package ethclient
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
+)
+
+// Auth holds the credentials sent to an endpoint
+type Auth struct {
+	Header    http.Header // static headers sent with every request
+	JWTSecret []byte      // HS256 secret, a bearer token with a fresh iat claim is sent with every request
+	Username  string      // basic auth, replaced by the jwt if both are set
+	Password  string
+	TLS       *tls.Config // client certificate and CA of the endpoint
+
+	transportOnce sync.Once
+	transport     *http.Transport // transport using TLS
+}
+
+// ReadSecret returns the value of a secret option: "env:NAME" reads the environment variable NAME, "file:PATH"
+// reads the file at PATH without trailing whitespace, other values are returned as is
+func ReadSecret(v string) (string, error) {
+	switch {
+	case strings.HasPrefix(v, "env:"):
+		name := strings.TrimPrefix(v, "env:")
+		ret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return ret, nil
+	case strings.HasPrefix(v, "file:"):
+		data, err := ioutil.ReadFile(strings.TrimPrefix(v, "file:"))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return v, nil
+}
+
+// ParseJWTSecret decodes a hex encoded HS256 secret of at least 32 bytes, as used by the engine API
+func ParseJWTSecret(v string) ([]byte, error) {
+	secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(v), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwt secret: %v", err)
+	}
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("jwt secret must be at least 32 bytes, got %d", len(secret))
+	}
+	return secret, nil
+}
+
+// NewTLSConfig returns the TLS config of a client certificate and a custom CA, both optional
+func NewTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// Headers returns the headers carrying the credentials, with a new jwt if a secret is set
+func (a *Auth) Headers() (http.Header, error) {
+	h := a.Header.Clone()
+	if h == nil {
+		h = make(http.Header)
+	}
+	if a.Username != "" || a.Password != "" {
+		h.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(a.Username+":"+a.Password)))
+	}
+	if len(a.JWTSecret) > 0 {
+		token, err := a.token(time.Now())
+		if err != nil {
+			return nil, err
+		}
+		h.Set("Authorization", "Bearer "+token)
+	}
+	return h, nil
+}
+
+// token returns a HS256 jwt with an iat claim, the only claim the engine API requires
+func (a *Auth) token(now time.Time) (string, error) {
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	claims := enc.EncodeToString([]byte(fmt.Sprintf(`{"iat":%d}`, now.Unix())))
+	mac := hmac.New(sha256.New, a.JWTSecret)
+	if _, err := mac.Write([]byte(header + "." + claims)); err != nil {
+		return "", err
+	}
+	return header + "." + claims + "." + enc.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (a *Auth) httpTransport() *http.Transport {
+	a.transportOnce.Do(func() {
+		a.transport = &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+			TLSClientConfig:       a.TLS,
+		}
+	})
+	return a.transport
+}
+
+var (
+	authsLock sync.RWMutex
+	auths     = make(map[string]*Auth) // endpoint -> credentials
+)
+
+// SetAuth sets the credentials all clients send to the endpoint, nil removes them
+func SetAuth(endpoint string, auth *Auth) {
+	authsLock.Lock()
+	defer authsLock.Unlock()
+	key := strings.TrimSuffix(endpoint, "/")
+	if auth == nil {
+		delete(auths, key)
+		return
+	}
+	auths[key] = auth
+}
+
+// AuthOf returns the credentials of the endpoint, nil if it has none. Urls below an endpoint, like the beacon api
+// paths of an eth2 endpoint, use the credentials of the endpoint.
+func AuthOf(endpoint string) *Auth {
+	authsLock.RLock()
+	defer authsLock.RUnlock()
+	key := strings.TrimSuffix(endpoint, "/")
+	if auth, ok := auths[key]; ok {
+		return auth
+	}
+	var (
+		ret     *Auth
+		longest int
+	)
+	for k, auth := range auths {
+		if len(k) > longest && strings.HasPrefix(key, k) && strings.ContainsAny(key[len(k):len(k)+1], "/?") {
+			ret, longest = auth, len(k)
+		}
+	}
+	return ret
+}
+
+// AuthTransport returns a RoundTripper sending the credentials set by SetAuth with the requests to their endpoint,
+// through a transport using the TLS config of the endpoint if it has one. Other requests are sent through base.
+func AuthTransport(base http.RoundTripper) http.RoundTripper {
+	return &authTransport{base: base}
+}
+
+type authTransport struct {
+	base http.RoundTripper
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	auth := AuthOf(req.URL.String())
+	if auth == nil {
+		return t.base.RoundTrip(req)
+	}
+	h, err := auth.Headers()
+	if err != nil {
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	for k, vs := range h {
+		r.Header[k] = vs
+	}
+	if auth.TLS != nil {
+		return auth.httpTransport().RoundTrip(r)
+	}
+	return t.base.RoundTrip(r)
+}
+
+// DialRPC connects an rpc client to the endpoint with its credentials. Http endpoints use NewHTTPClient with the
+// rate limit of the chain, websocket endpoints send the credentials with the handshake and are limited by DialClient.
+func DialRPC(ctx context.Context, chain, endpoint string) (*rpc.Client, error) {
+	switch {
+	case strings.HasPrefix(endpoint, "http"):
+		return rpc.DialHTTPWithClient(endpoint, NewHTTPClient(chain, endpoint, 0))
+	case strings.HasPrefix(endpoint, "ws"):
+		auth := AuthOf(endpoint)
+		if auth == nil {
+			return rpc.DialContext(ctx, endpoint)
+		}
+		return rpc.DialWebsocketWithDialer(ctx, endpoint, "", websocketDialer(auth))
+	}
+	return rpc.DialContext(ctx, endpoint)
+}
+
+// websocketDialer returns a dialer sending the credentials with the handshake request. The rpc package doesn't
+// allow to set the headers of the handshake, they are set in the Proxy hook, which the dialer calls with the
+// handshake request right before writing it, on every redial.
+func websocketDialer(auth *Auth) websocket.Dialer {
+	return websocket.Dialer{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		TLSClientConfig: auth.TLS,
+		Proxy: func(req *http.Request) (*url.URL, error) {
+			h, err := auth.Headers()
+			if err != nil {
+				return nil, err
+			}
+			for k, vs := range h {
+				req.Header[k] = vs
+			}
+			return http.ProxyFromEnvironment(req)
+		},
+	}
+}
@@ -0,0 +1,104 @@
+package ethclient
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// checkAuth fails requests missing the custom header or carrying a bearer token not signed with testSecret
+func checkAuth(t *testing.T, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "key" {
+			http.Error(w, "missing api key", http.StatusUnauthorized)
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		parts := strings.Split(token, ".")
+		if len(parts) != 3 {
+			http.Error(w, "missing token", http.StatusUnauthorized)
+			return
+		}
+		mac := hmac.New(sha256.New, testSecret)
+		mac.Write([]byte(parts[0] + "." + parts[1]))
+		if base64.RawURLEncoding.EncodeToString(mac.Sum(nil)) != parts[2] {
+			t.Errorf("invalid token signature %s", token)
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type authService struct{}
+
+func (authService) BlockNumber() string { return "0x64" }
+
+func TestAuth(t *testing.T) {
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", authService{}); err != nil {
+		t.Fatal(err)
+	}
+	auth := &Auth{Header: http.Header{"X-Api-Key": []string{"key"}}, JWTSecret: testSecret}
+
+	for name, handler := range map[string]http.Handler{
+		"http": server,
+		"ws":   server.WebsocketHandler([]string{"*"}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(checkAuth(t, handler))
+			defer srv.Close()
+			endpoint := strings.Replace(srv.URL, "http", name, 1)
+
+			if c, err := Dial(endpoint); err == nil {
+				if _, err = c.BlockNumber(context.Background()); err == nil {
+					t.Fatal("expected a request without credentials to fail")
+				}
+				c.Close()
+			}
+
+			SetAuth(endpoint, auth)
+			defer SetAuth(endpoint, nil)
+			c, err := Dial(endpoint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			head, err := c.BlockNumber(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if head != 100 {
+				t.Fatalf("head = %d, want 100", head)
+			}
+		})
+	}
+}
+
+func TestAuthOf(t *testing.T) {
+	auth := &Auth{}
+	SetAuth("http://beacon:5052/", auth)
+	defer SetAuth("http://beacon:5052", nil)
+
+	for url, want := range map[string]*Auth{
+		"http://beacon:5052":                            auth,
+		"http://beacon:5052/eth/v1/beacon/headers/head": auth,
+		"http://beacon:5052?x=1":                        auth,
+		"http://beacon:50521":                           nil,
+		"http://other:5052":                             nil,
+	} {
+		if got := AuthOf(url); got != want {
+			t.Errorf("AuthOf(%s) = %v, want %v", url, got, want)
+		}
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/mapprotocol/compass/pkg/instrument"
 	"github.com/mapprotocol/compass/pkg/platon"
 	"math/big"
-	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
@@ -19,8 +21,15 @@ import (
 
 // Client defines typed wrappers for the Ethereum RPC API.
 type Client struct {
-	c   *rpc.Client
-	url string
+	c     *rpcClient
+	url   string
+	chain string // chain whose rate limit applies to the client
+
+	peersLock sync.RWMutex
+	peers     []*Client // signed txs are broadcast to the peers as well
+
+	receiptBatch  int32 // receipts requested in one batch call, 0 means DefaultReceiptBatchSize
+	blockReceipts int32 // whether the node supports eth_getBlockReceipts
 }
 
 // Dial connects a client to the given URL.
@@ -29,16 +38,41 @@ func Dial(rawurl string) (*Client, error) {
 }
 
 func DialContext(ctx context.Context, rawurl string) (*Client, error) {
-	c, err := rpc.DialContext(ctx, rawurl)
+	return DialClient(ctx, "", rawurl)
+}
+
+// DialClient connects a client to the endpoint of the chain, its calls are rate limited by the limit of the chain
+// for the endpoint, on websocket endpoints as well
+func DialClient(ctx context.Context, chain, endpoint string) (*Client, error) {
+	c, err := DialRPC(ctx, chain, endpoint)
 	if err != nil {
 		return nil, err
 	}
-	return NewClient(c, rawurl), nil
+	ec := NewClient(c, endpoint)
+	ec.chain = chain
+	if !strings.HasPrefix(endpoint, "http") {
+		// http requests are limited by the transport
+		ec.c.limit = bucketOf(chain, endpoint)
+	}
+	return ec, nil
 }
 
 // NewClient creates a client that uses the given RPC client.
 func NewClient(c *rpc.Client, url string) *Client {
-	return &Client{c, url}
+	return &Client{c: &rpcClient{Client: c, endpoint: instrument.Endpoint(url)}, url: url}
+}
+
+// SetPeers sets the clients SendTransaction broadcasts signed txs to in addition to this client
+func (ec *Client) SetPeers(peers []*Client) {
+	ec.peersLock.Lock()
+	defer ec.peersLock.Unlock()
+	ec.peers = peers
+}
+
+// CallStats returns the number of calls sent through the client and how many of them the endpoint failed to
+// answer, since the previous CallStats
+func (ec *Client) CallStats() (calls, failed int64) {
+	return atomic.SwapInt64(&ec.c.calls, 0), atomic.SwapInt64(&ec.c.failed, 0)
 }
 
 func (ec *Client) Close() {
@@ -173,6 +207,54 @@ func (ec *Client) HeaderByNumber(ctx context.Context, number *big.Int) (*types.H
 	return head, err
 }
 
+// FinalizedBlockNumber returns the number of the latest finalized block, it requires the node to support the
+// "finalized" block tag.
+func (ec *Client) FinalizedBlockNumber(ctx context.Context) (*big.Int, error) {
+	var head *struct {
+		Number *hexutil.Big `json:"number"`
+	}
+	err := ec.c.CallContext(ctx, &head, "eth_getBlockByNumber", "finalized", false)
+	if err == nil && (head == nil || head.Number == nil) {
+		err = ethereum.NotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return head.Number.ToInt(), nil
+}
+
+// BlockRoots is the hash and receipts root of a block as reported by the node
+type BlockRoots struct {
+	Hash         common.Hash `json:"hash"`
+	ReceiptsRoot common.Hash `json:"receiptsRoot"`
+}
+
+// BlockRootsByNumber returns the hash and receipts root the node reports for the block. The hash is not
+// recomputed from the header, so it works for chains with non-standard headers as well.
+func (ec *Client) BlockRootsByNumber(ctx context.Context, number *big.Int) (*BlockRoots, error) {
+	var roots *BlockRoots
+	err := ec.c.CallContext(ctx, &roots, "eth_getBlockByNumber", toBlockNumArg(number), false)
+	if err == nil && roots == nil {
+		err = ethereum.NotFound
+	}
+	return roots, err
+}
+
+// EffectiveGasPrice returns the gas price actually paid by the tx, as reported by its receipt
+func (ec *Client) EffectiveGasPrice(ctx context.Context, txHash common.Hash) (*big.Int, error) {
+	var r *struct {
+		EffectiveGasPrice *hexutil.Big `json:"effectiveGasPrice"`
+	}
+	err := ec.c.CallContext(ctx, &r, "eth_getTransactionReceipt", txHash)
+	if err == nil && (r == nil || r.EffectiveGasPrice == nil) {
+		err = ethereum.NotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.EffectiveGasPrice.ToInt(), nil
+}
+
 type rpcTransaction struct {
 	tx *types.Transaction
 	txExtraInfo
@@ -508,7 +590,25 @@ func (ec *Client) SendTransaction(ctx context.Context, tx *types.Transaction) er
 	if err != nil {
 		return err
 	}
-	return ec.c.CallContext(ctx, nil, "eth_sendRawTransaction", hexutil.Encode(data))
+	raw := hexutil.Encode(data)
+	if err = ec.c.CallContext(ctx, nil, "eth_sendRawTransaction", raw); err != nil && !isKnownTx(err) {
+		// the error of this endpoint decides, like a nonce too low or insufficient funds
+		return err
+	}
+
+	ec.peersLock.RLock()
+	peers := ec.peers
+	ec.peersLock.RUnlock()
+	// The peers only speed up the propagation of the accepted tx, their errors are ignored
+	for _, p := range peers {
+		_ = p.c.CallContext(ctx, nil, "eth_sendRawTransaction", raw)
+	}
+	return nil
+}
+
+func isKnownTx(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "already known") || strings.Contains(msg, "known transaction")
 }
 
 func toBlockNumArg(number *big.Int) string {
@@ -601,7 +701,7 @@ type jsonrpcMessage struct {
 func (ec *Client) EthLatestHeaderByNumber(endpoint string, number *big.Int) (*Header, error) {
 	s := fmt.Sprintf("{\"jsonrpc\": \"2.0\",\"method\": \"eth_getBlockByNumber\",\"params\": [\"%s\",true],\"id\": 1\n}", toBlockNumArg(number))
 	body := strings.NewReader(s)
-	resp, err := http.Post(endpoint, "application/json", body)
+	resp, err := NewHTTPClient(ec.chain, endpoint, 0).Post(endpoint, "application/json", body)
 	if err != nil {
 		return nil, err
 	}
@@ -651,7 +751,7 @@ type OpReceipt struct {
 func (ec *Client) OpReceipt(ctx context.Context, txHash common.Hash) (*OpReceipt, error) {
 	s := fmt.Sprintf("{\"jsonrpc\": \"2.0\",\"method\": \"eth_getTransactionReceipt\",\"params\": [\"%s\"],\"id\": 1\n}", txHash.Hex())
 	body := strings.NewReader(s)
-	resp, err := http.Post(ec.url, "application/json", body)
+	resp, err := NewHTTPClient(ec.chain, ec.url, 0).Post(ec.url, "application/json", body)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,80 @@
+package ethclient
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/mapprotocol/compass/pkg/instrument"
+)
+
+// rpcClient records latency and errors of the calls of an rpc client, and rate limits them if limit is set
+type rpcClient struct {
+	*rpc.Client
+	endpoint string
+	limit    *bucket // set for websocket endpoints, the transport limits http ones
+	calls    int64   // calls since the last CallStats, read and reset atomically
+	failed   int64   // calls the endpoint failed to answer since the last CallStats
+}
+
+func (c *rpcClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+	done := instrument.Start("eth", c.endpoint, method, args...)
+	err := c.Client.CallContext(ctx, result, method, args...)
+	done(err)
+	c.count(err)
+	return err
+}
+
+func (c *rpcClient) wait(ctx context.Context) error {
+	if c.limit == nil {
+		return nil
+	}
+	return c.limit.wait(ctx)
+}
+
+// count records the outcome of a call. Errors returned by the node, like a reverted eth_call, and calls canceled
+// by the caller don't count as failures of the endpoint.
+func (c *rpcClient) count(err error) {
+	atomic.AddInt64(&c.calls, 1)
+	var rpcErr rpc.Error
+	if err == nil || errors.As(err, &rpcErr) || errors.Is(err, context.Canceled) {
+		return
+	}
+	atomic.AddInt64(&c.failed, 1)
+}
+
+// BatchCallContext records a batch as one call, named after the method of its elements if they share it
+func (c *rpcClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	method := "batch"
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+	params := make([]interface{}, 0, len(b))
+	for i, elem := range b {
+		if i == 0 {
+			method = "batch:" + elem.Method
+		} else if "batch:"+elem.Method != method {
+			method = "batch"
+		}
+		params = append(params, elem.Args)
+	}
+	done := instrument.Start("eth", c.endpoint, method, params...)
+	err := c.Client.BatchCallContext(ctx, b)
+	c.count(err)
+	if err != nil {
+		done(err)
+		return err
+	}
+	for _, elem := range b {
+		if elem.Error != nil {
+			done(elem.Error)
+			return nil
+		}
+	}
+	done(nil)
+	return nil
+}
@@ -0,0 +1,203 @@
+package ethclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxRetries      = 5
+	backoffBase     = 200 * time.Millisecond
+	backoffLimit    = 10 * time.Second
+	retryAfterLimit = time.Minute
+)
+
+// bucket is a token bucket limiting the requests sent to an endpoint
+type bucket struct {
+	lock   sync.Mutex
+	rate   float64 // tokens added per second, 0 means unlimited
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// wait blocks until a request may be sent or ctx is done
+func (b *bucket) wait(ctx context.Context) error {
+	for {
+		b.lock.Lock()
+		if b.rate <= 0 {
+			b.lock.Unlock()
+			return nil
+		}
+		now := time.Now()
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.lock.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.lock.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+var (
+	bucketsLock sync.Mutex
+	buckets     = make(map[string]*bucket) // chain and endpoint -> bucket shared by the clients of the chain
+)
+
+// bucketOf returns the bucket of the endpoint for the chain, chains sharing an endpoint each have their own bucket
+func bucketOf(chain, endpoint string) *bucket {
+	bucketsLock.Lock()
+	defer bucketsLock.Unlock()
+	key := chain + "|" + strings.TrimSuffix(endpoint, "/")
+	b, ok := buckets[key]
+	if !ok {
+		b = &bucket{}
+		buckets[key] = b
+	}
+	return b
+}
+
+// SetRateLimit limits the requests the clients of the chain send to the endpoint to rate per second, allowing
+// bursts of burst requests. A rate of 0 removes the limit. Clients created before the call are limited as well.
+func SetRateLimit(chain, endpoint string, rate float64, burst int) {
+	b := bucketOf(chain, endpoint)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if burst < 1 {
+		burst = 1
+	}
+	b.rate = rate
+	b.burst = float64(burst)
+	b.tokens = b.burst
+	b.last = time.Now()
+}
+
+// NewHTTPClient returns a http client whose requests to the endpoint are rate limited by the limit of the chain,
+// and retried with jittered exponential backoff when the endpoint answers 429, or 5xx to a request which doesn't
+// send a tx. The credentials set by SetAuth are sent with them. Every client talking http to a node should use it.
+func NewHTTPClient(chain, endpoint string, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &limitedTransport{
+			bucket: bucketOf(chain, endpoint),
+			base:   AuthTransport(http.DefaultTransport),
+		},
+	}
+}
+
+type limitedTransport struct {
+	bucket *bucket
+	base   http.RoundTripper
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := t.bucket.wait(req.Context()); err != nil {
+			return nil, err
+		}
+		r := req.Clone(req.Context())
+		if body != nil {
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		resp, err := t.base.RoundTrip(r)
+		if err != nil || !retryable(resp.StatusCode, req, body) || attempt == maxRetries {
+			return resp, err
+		}
+
+		delay := backoff(attempt, resp.Header.Get("Retry-After"))
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether the request may be sent again. A 429 means the request was rejected before it was
+// handled. A 5xx may come after the node handled it, so a request sending a tx isn't sent again then.
+func retryable(status int, req *http.Request, body []byte) bool {
+	if status == http.StatusTooManyRequests {
+		return true
+	}
+	return status >= http.StatusInternalServerError && readOnly(req, body)
+}
+
+// readOnly reports whether the request doesn't send a tx: a GET, or json-rpc calls of which none sends or
+// broadcasts a tx, like eth_sendRawTransaction or the broadcast_tx methods of near. Other bodies aren't known
+// to be read only.
+func readOnly(req *http.Request, body []byte) bool {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return true
+	}
+	if strings.Contains(strings.ToLower(req.URL.Path), "broadcast") {
+		return false
+	}
+	var calls []struct {
+		Method string `json:"method"`
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] != '[' {
+		body = append(append([]byte{'['}, body...), ']')
+	}
+	if err := json.Unmarshal(body, &calls); err != nil || len(calls) == 0 {
+		return false
+	}
+	for _, c := range calls {
+		m := strings.ToLower(c.Method)
+		if c.Method == "" || strings.Contains(m, "send") || strings.Contains(m, "broadcast") {
+			return false
+		}
+	}
+	return true
+}
+
+// backoff returns the delay before the next attempt, the Retry-After header of the endpoint takes precedence
+func backoff(attempt int, retryAfter string) time.Duration {
+	if secs, err := strconv.Atoi(retryAfter); err == nil && secs > 0 {
+		if d := time.Duration(secs) * time.Second; d < retryAfterLimit {
+			return d
+		}
+		return retryAfterLimit
+	}
+	d := backoffBase << uint(attempt)
+	if d > backoffLimit {
+		d = backoffLimit
+	}
+	// jitter in [d/2, d), so clients throttled together don't retry together
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
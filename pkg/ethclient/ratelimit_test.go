@@ -0,0 +1,191 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// newStubNode answers eth_blockNumber, the first throttled requests are answered with 429
+func newStubNode(throttled int32, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Id json.RawMessage `json:"id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if atomic.AddInt32(calls, 1) <= throttled {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x64"}`, req.Id)
+	}))
+}
+
+func TestBackoffOnThrottle(t *testing.T) {
+	var calls int32
+	srv := newStubNode(2, &calls)
+	defer srv.Close()
+
+	c, err := Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := c.BlockNumber(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != 100 || atomic.LoadInt32(&calls) != 3 {
+		t.Fatalf("Expected head 100 after 3 calls, got head %d after %d calls", head, calls)
+	}
+
+	// A throttle lasting longer than the retries is reported
+	calls = 0
+	srv2 := newStubNode(maxRetries+1, &calls)
+	defer srv2.Close()
+	c, err = Dial(srv2.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.BlockNumber(context.Background()); err == nil {
+		t.Fatal("Expected error when the endpoint keeps throttling")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	var calls int32
+	srv := newStubNode(0, &calls)
+	defer srv.Close()
+	SetRateLimit("test", srv.URL, 20, 2)
+
+	c, err := DialClient(context.Background(), "test", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if _, err = c.BlockNumber(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 2 requests are served by the burst, the other 4 wait 50ms each
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Fatalf("Expected requests to be rate limited, took %s", elapsed)
+	}
+}
+
+func TestRateLimitPerChain(t *testing.T) {
+	var calls int32
+	srv := newStubNode(0, &calls)
+	defer srv.Close()
+	SetRateLimit("slow", srv.URL, 1, 1)
+	SetRateLimit("fast", srv.URL, 0, 0)
+
+	// The chain configured last doesn't change the limit of the other chain sharing the endpoint
+	c, err := DialClient(context.Background(), "fast", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err = c.BlockNumber(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("Expected requests of the unlimited chain not to wait, took %s", elapsed)
+	}
+}
+
+type stubService struct{}
+
+func (stubService) BlockNumber() hexutil.Uint64 {
+	return 100
+}
+
+func TestRateLimitWebsocket(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", stubService{}); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(server.WebsocketHandler([]string{"*"}))
+	defer srv.Close()
+	endpoint := "ws" + strings.TrimPrefix(srv.URL, "http")
+	SetRateLimit("test", endpoint, 20, 2)
+
+	c, err := DialClient(context.Background(), "test", endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if _, err = c.BlockNumber(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Fatalf("Expected websocket requests to be rate limited, took %s", elapsed)
+	}
+}
+
+func TestNoRetrySendOn5xx(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	key, _ := crypto.GenerateKey()
+	tx, err := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil),
+		types.HomesteadSigner{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The node may have taken the tx before failing, it isn't sent again
+	if err = c.SendTransaction(context.Background(), tx); err == nil {
+		t.Fatal("Expected the send to fail")
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("Expected the tx to be sent once, got %d calls", n)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	for _, tc := range []struct {
+		method, path, body string
+		want               bool
+	}{
+		{http.MethodGet, "/eth/v1/beacon/headers/head", "", true},
+		{http.MethodPost, "/", `{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[]}`, true},
+		{http.MethodPost, "/", `[{"method":"eth_getTransactionReceipt"},{"method":"eth_getTransactionReceipt"}]`, true},
+		{http.MethodPost, "/", `{"jsonrpc":"2.0","id":1,"method":"eth_sendRawTransaction","params":["0x"]}`, false},
+		{http.MethodPost, "/", `[{"method":"eth_blockNumber"},{"method":"eth_sendRawTransaction"}]`, false},
+		{http.MethodPost, "/", `{"jsonrpc":"2.0","id":1,"method":"broadcast_tx_commit","params":[]}`, false},
+		{http.MethodPost, "/wallet/broadcasttransaction", `{"txID":"00"}`, false},
+		{http.MethodPost, "/", `not json`, false},
+	} {
+		req := httptest.NewRequest(tc.method, "http://node"+tc.path, nil)
+		if got := readOnly(req, []byte(tc.body)); got != tc.want {
+			t.Errorf("readOnly(%s %s %s) = %v, want %v", tc.method, tc.path, tc.body, got, tc.want)
+		}
+	}
+}
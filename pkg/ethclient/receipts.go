@@ -0,0 +1,130 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// DefaultReceiptBatchSize is the number of receipts requested in one batch call
+const DefaultReceiptBatchSize = 100
+
+// JSON-RPC error codes of a node which doesn't support a method
+const (
+	methodNotFound = -32601
+	invalidParams  = -32602
+)
+
+// Support of eth_getBlockReceipts by the node
+const (
+	blockReceiptsUnknown int32 = iota
+	blockReceiptsSupported
+	blockReceiptsUnsupported
+)
+
+// SetReceiptBatchSize sets the number of receipts requested in one batch call, 1 disables batching
+func (ec *Client) SetReceiptBatchSize(size int) {
+	atomic.StoreInt32(&ec.receiptBatch, int32(size))
+}
+
+// BlockReceipts returns the receipts of txsHash, the txs of the block in order. It uses eth_getBlockReceipts if
+// the node supports it, the first call probes the method and the result is cached. Otherwise the receipts are
+// requested in batches by TransactionReceipts, and the receipt of a tx the node doesn't know yet is nil.
+func (ec *Client) BlockReceipts(ctx context.Context, number *big.Int, txsHash []common.Hash) ([]*types.Receipt, error) {
+	if atomic.LoadInt32(&ec.blockReceipts) != blockReceiptsUnsupported {
+		var rs []*types.Receipt
+		err := ec.c.CallContext(ctx, &rs, "eth_getBlockReceipts", toBlockNumArg(number))
+		switch {
+		case err == nil && sameTxs(rs, txsHash):
+			atomic.StoreInt32(&ec.blockReceipts, blockReceiptsSupported)
+			return rs, nil
+		case isUnsupported(err):
+			atomic.StoreInt32(&ec.blockReceipts, blockReceiptsUnsupported)
+		}
+		// transport errors and incomplete answers fall back to the receipts of each tx, without caching
+	}
+
+	return ec.TransactionReceipts(ctx, txsHash)
+}
+
+// TransactionReceipts returns the receipts of txsHash in order, in batch calls of SetReceiptBatchSize receipts.
+// The receipt of a tx the node doesn't know is nil.
+func (ec *Client) TransactionReceipts(ctx context.Context, txsHash []common.Hash) ([]*types.Receipt, error) {
+	size := int(atomic.LoadInt32(&ec.receiptBatch))
+	if size == 0 {
+		size = DefaultReceiptBatchSize
+	}
+	rs := make([]*types.Receipt, len(txsHash))
+	if size == 1 {
+		for i, h := range txsHash {
+			if err := ec.c.CallContext(ctx, &rs[i], "eth_getTransactionReceipt", h); err != nil {
+				return nil, err
+			}
+		}
+		return rs, nil
+	}
+
+	for start := 0; start < len(txsHash); start += size {
+		end := start + size
+		if end > len(txsHash) {
+			end = len(txsHash)
+		}
+		reqs := make([]rpc.BatchElem, 0, end-start)
+		for i := start; i < end; i++ {
+			reqs = append(reqs, rpc.BatchElem{
+				Method: "eth_getTransactionReceipt",
+				Args:   []interface{}{txsHash[i]},
+				Result: &rs[i],
+			})
+		}
+		if err := ec.c.BatchCallContext(ctx, reqs); err != nil {
+			return nil, err
+		}
+		for _, req := range reqs {
+			if req.Error != nil {
+				return nil, req.Error
+			}
+		}
+	}
+	return rs, nil
+}
+
+func sameTxs(rs []*types.Receipt, txsHash []common.Hash) bool {
+	if len(rs) != len(txsHash) {
+		return false
+	}
+	for i, r := range rs {
+		if r == nil || r.TxHash != txsHash[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// isUnsupported reports whether err is the node refusing the method or answering something other than receipts,
+// rather than a transient failure
+func isUnsupported(err error) bool {
+	if err == nil {
+		return false
+	}
+	var (
+		rpcErr  rpc.Error
+		typeErr *json.UnmarshalTypeError
+	)
+	if errors.As(err, &rpcErr) {
+		if rpcErr.ErrorCode() == methodNotFound || rpcErr.ErrorCode() == invalidParams {
+			return true
+		}
+		msg := strings.ToLower(rpcErr.Error())
+		return strings.Contains(msg, "not supported") || strings.Contains(msg, "does not exist") ||
+			strings.Contains(msg, "not available")
+	}
+	return errors.As(err, &typeErr) || strings.Contains(err.Error(), "missing required field")
+}
@@ -0,0 +1,136 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type rpcRequest struct {
+	Id     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+func receiptJSON(tx common.Hash) string {
+	return fmt.Sprintf(`{"transactionHash":"%s","cumulativeGasUsed":"0x5208","gasUsed":"0x5208","status":"0x1",`+
+		`"logsBloom":"0x%0512x","logs":[]}`, tx.Hex(), 0)
+}
+
+// newReceiptNode serves receipts of txs, as a batch or by eth_getBlockReceipts if blockReceipts is set
+func newReceiptNode(txs []common.Hash, blockReceipts bool, requests *int32) *httptest.Server {
+	answer := func(req rpcRequest) string {
+		switch req.Method {
+		case "eth_getBlockReceipts":
+			if !blockReceipts {
+				return fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"error":{"code":-32601,"message":"the method eth_getBlockReceipts does not exist"}}`, req.Id)
+			}
+			rs := ""
+			for i, tx := range txs {
+				if i > 0 {
+					rs += ","
+				}
+				rs += receiptJSON(tx)
+			}
+			return fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":[%s]}`, req.Id, rs)
+		case "eth_getTransactionReceipt":
+			var tx common.Hash
+			_ = json.Unmarshal(req.Params[0], &tx)
+			return fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":%s}`, req.Id, receiptJSON(tx))
+		}
+		return fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"error":{"code":-32601,"message":"method not found"}}`, req.Id)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		var batch []rpcRequest
+		if json.Unmarshal(body, &batch) == nil {
+			out := "["
+			for i, req := range batch {
+				if i > 0 {
+					out += ","
+				}
+				out += answer(req)
+			}
+			fmt.Fprint(w, out+"]")
+			return
+		}
+		var req rpcRequest
+		_ = json.Unmarshal(body, &req)
+		fmt.Fprint(w, answer(req))
+	}))
+}
+
+func testTxs(n int) []common.Hash {
+	txs := make([]common.Hash, n)
+	for i := range txs {
+		txs[i] = common.BigToHash(big.NewInt(int64(i + 1)))
+	}
+	return txs
+}
+
+func TestBlockReceipts(t *testing.T) {
+	var requests int32
+	txs := testTxs(250)
+	srv := newReceiptNode(txs, true, &requests)
+	defer srv.Close()
+
+	c, err := Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := c.BlockReceipts(context.Background(), big.NewInt(1), txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != len(txs) || rs[249].TxHash != txs[249] {
+		t.Fatal("Unexpected receipts")
+	}
+	if requests != 1 {
+		t.Fatalf("Expected one round-trip, got %d", requests)
+	}
+}
+
+func TestBlockReceiptsFallback(t *testing.T) {
+	var requests int32
+	txs := testTxs(5)
+	srv := newReceiptNode(txs, false, &requests)
+	defer srv.Close()
+
+	c, err := Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetReceiptBatchSize(2)
+	rs, err := c.BlockReceipts(context.Background(), big.NewInt(1), txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range rs {
+		if r.TxHash != txs[i] {
+			t.Fatalf("Expected receipt of %s at %d got %s", txs[i].Hex(), i, r.TxHash.Hex())
+		}
+	}
+	// the probe and 3 batches
+	if requests != 4 {
+		t.Fatalf("Expected: %d got: %d", 4, requests)
+	}
+
+	// The unsupported method is not probed again
+	requests = 0
+	if _, err = c.BlockReceipts(context.Background(), big.NewInt(1), txs); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 {
+		t.Fatalf("Expected: %d got: %d", 3, requests)
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mapprotocol/compass/mapprotocol"
+	"github.com/mapprotocol/compass/msg"
+)
+
+// Default thresholds
+const (
+	DefaultMaxBlockAge   = 30 * time.Minute
+	DefaultMaxMessageAge = time.Hour
+)
+
+// States of a listener
+const (
+	ListenerNone    = "none"    // the chain has no listener, or it has not started yet
+	ListenerRunning = "running" // polling blocks
+	ListenerIdle    = "idle"    // started but does not sync the chain with the configured opts
+	ListenerStopped = "stopped" // returned, the chain is no longer followed
+)
+
+// Config holds the thresholds a chain is reported unhealthy at
+type Config struct {
+	MaxBlockAge   time.Duration // longest time a running listener may go without processing a block
+	MaxMessageAge time.Duration // longest time a writer may spend on a single message
+}
+
+// ChainStatus is the health of a single chain
+type ChainStatus struct {
+	Chain          string      `json:"chain"`
+	ChainId        msg.ChainId `json:"chainId"`
+	Connected      bool        `json:"connected"`
+	Listener       string      `json:"listener"`
+	ListenerErr    string      `json:"listenerErr,omitempty"`
+	LastProcessed  *time.Time  `json:"lastProcessed,omitempty"`
+	SinceProcessed string      `json:"sinceProcessed,omitempty"`
+	Pending        int         `json:"pending"`                  // messages the writer is working on
+	OldestMessage  string      `json:"oldestMessage,omitempty"` // time the writer spent on its oldest pending message
+	Problems       []string    `json:"problems,omitempty"`
+}
+
+// Report is the health of the process
+type Report struct {
+	Role    mapprotocol.Role `json:"role"`
+	Healthy bool             `json:"healthy"`
+	Ready   bool             `json:"ready"`
+	Chains  []ChainStatus    `json:"chains"`
+}
+
+type chainState struct {
+	disconnected  bool
+	listener      string
+	listenerErr   error
+	started       time.Time
+	lastProcessed time.Time
+	pending       map[uint64]time.Time
+}
+
+var (
+	lock   sync.Mutex
+	role   mapprotocol.Role
+	config = Config{MaxBlockAge: DefaultMaxBlockAge, MaxMessageAge: DefaultMaxMessageAge}
+	chains = make(map[msg.ChainId]*chainState)
+	ready  bool
+	nextId uint64
+)
+
+// Init sets the role of the process and the thresholds of the reports
+func Init(r mapprotocol.Role, cfg Config) {
+	lock.Lock()
+	defer lock.Unlock()
+	role, config = r, cfg
+}
+
+// state returns the state of chain, the lock must be held
+func state(chain msg.ChainId) *chainState {
+	s, ok := chains[chain]
+	if !ok {
+		s = &chainState{listener: ListenerNone, pending: make(map[uint64]time.Time)}
+		chains[chain] = s
+	}
+	return s
+}
+
+// SetReady marks the process ready once every chain is started
+func SetReady() {
+	lock.Lock()
+	defer lock.Unlock()
+	ready = true
+}
+
+// Started records the listener of chain started polling blocks
+func Started(chain msg.ChainId) {
+	lock.Lock()
+	defer lock.Unlock()
+	s := state(chain)
+	s.listener, s.listenerErr, s.started = ListenerRunning, nil, time.Now()
+}
+
+// Idle records the listener of chain does not sync it with the configured opts, so it never processes blocks
+func Idle(chain msg.ChainId) {
+	lock.Lock()
+	defer lock.Unlock()
+	state(chain).listener = ListenerIdle
+}
+
+// Stopped records the listener of chain returned with err
+func Stopped(chain msg.ChainId, err error) {
+	lock.Lock()
+	defer lock.Unlock()
+	s := state(chain)
+	s.listener, s.listenerErr = ListenerStopped, err
+}
+
+// Processed records the listener of chain processed a block
+func Processed(chain msg.ChainId) {
+	lock.Lock()
+	defer lock.Unlock()
+	state(chain).lastProcessed = time.Now()
+}
+
+// Connected records whether any endpoint of chain is connected
+func Connected(chain msg.ChainId, connected bool) {
+	lock.Lock()
+	defer lock.Unlock()
+	state(chain).disconnected = !connected
+}
+
+// Handling records the writer of chain started on a message, the returned func records it is done with it
+func Handling(chain msg.ChainId) func() {
+	lock.Lock()
+	defer lock.Unlock()
+	nextId++
+	id := nextId
+	state(chain).pending[id] = time.Now()
+	return func() {
+		lock.Lock()
+		defer lock.Unlock()
+		delete(state(chain).pending, id)
+	}
+}
+
+func chainName(id msg.ChainId) string {
+	if name, ok := mapprotocol.OnlineChaId[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", id)
+}
+
+// Current returns the health of the process and its chains
+func Current() Report {
+	lock.Lock()
+	defer lock.Unlock()
+	now := time.Now()
+	r := Report{Role: role, Healthy: true, Ready: ready, Chains: make([]ChainStatus, 0, len(chains))}
+	for id, s := range chains {
+		cs := ChainStatus{Chain: chainName(id), ChainId: id, Connected: !s.disconnected, Listener: s.listener,
+			Pending: len(s.pending)}
+		if s.listenerErr != nil {
+			cs.ListenerErr = s.listenerErr.Error()
+		}
+		if s.listener == ListenerStopped {
+			cs.Problems = append(cs.Problems, "listener stopped")
+		}
+		if s.listener == ListenerRunning {
+			// a listener which has not processed a block yet is measured from its start
+			last := s.started
+			if !s.lastProcessed.IsZero() {
+				processed := s.lastProcessed
+				cs.LastProcessed, last = &processed, processed
+			}
+			cs.SinceProcessed = now.Sub(last).Round(time.Second).String()
+			if config.MaxBlockAge > 0 && now.Sub(last) > config.MaxBlockAge {
+				cs.Problems = append(cs.Problems, fmt.Sprintf("no block processed for %s", cs.SinceProcessed))
+			}
+		}
+		var oldest time.Duration
+		for _, start := range s.pending {
+			if age := now.Sub(start); age > oldest {
+				oldest = age
+			}
+		}
+		if len(s.pending) > 0 {
+			cs.OldestMessage = oldest.Round(time.Second).String()
+		}
+		if config.MaxMessageAge > 0 && oldest > config.MaxMessageAge {
+			cs.Problems = append(cs.Problems, fmt.Sprintf("writer stuck on a message for %s", cs.OldestMessage))
+		}
+		if len(cs.Problems) != 0 {
+			r.Healthy = false
+		}
+		if !cs.Connected {
+			r.Ready = false
+		}
+		r.Chains = append(r.Chains, cs)
+	}
+	if !r.Healthy {
+		r.Ready = false
+	}
+	sort.Slice(r.Chains, func(i, j int) bool { return r.Chains[i].ChainId < r.Chains[j].ChainId })
+	return r
+}
+
+// HealthHandler serves /healthz, it fails when a listener stopped, fell behind or a writer is stuck, which a
+// restart may resolve
+func HealthHandler(w http.ResponseWriter, _ *http.Request) {
+	r := Current()
+	serve(w, r, r.Healthy)
+}
+
+// ReadyHandler serves /readyz, it fails until every chain is started, and while a chain is disconnected or unhealthy
+func ReadyHandler(w http.ResponseWriter, _ *http.Request) {
+	r := Current()
+	serve(w, r, r.Ready)
+}
+
+func serve(w http.ResponseWriter, r Report, ok bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(r)
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package health
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mapprotocol/compass/mapprotocol"
+	"github.com/mapprotocol/compass/msg"
+)
+
+func reset(cfg Config) {
+	lock.Lock()
+	chains, ready = make(map[msg.ChainId]*chainState), false
+	lock.Unlock()
+	Init(mapprotocol.RoleOfMessenger, cfg)
+}
+
+func status(t *testing.T, handler http.HandlerFunc) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	return rec.Code
+}
+
+func TestReadiness(t *testing.T) {
+	reset(Config{MaxBlockAge: time.Hour, MaxMessageAge: time.Hour})
+	Started(1)
+	Idle(2)
+	if status(t, ReadyHandler) != http.StatusServiceUnavailable {
+		t.Error("Expected not ready before every chain is started")
+	}
+	SetReady()
+	Processed(1)
+	if code := status(t, ReadyHandler); code != http.StatusOK {
+		t.Errorf("Expected ready, got %d", code)
+	}
+	Connected(1, false)
+	if status(t, ReadyHandler) != http.StatusServiceUnavailable || status(t, HealthHandler) != http.StatusOK {
+		t.Error("Expected a disconnected chain to be healthy but not ready")
+	}
+	Connected(1, true)
+	if code := status(t, ReadyHandler); code != http.StatusOK {
+		t.Errorf("Expected ready after reconnecting, got %d", code)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	reset(Config{MaxBlockAge: time.Hour, MaxMessageAge: time.Hour})
+	Started(1)
+	Stopped(1, errors.New("polling failed"))
+	r := Current()
+	if r.Healthy || r.Chains[0].Listener != ListenerStopped || r.Chains[0].ListenerErr != "polling failed" {
+		t.Errorf("Expected a stopped listener to be unhealthy, got %+v", r)
+	}
+	if status(t, HealthHandler) != http.StatusServiceUnavailable {
+		t.Error("Expected /healthz to fail")
+	}
+
+	reset(Config{MaxBlockAge: time.Millisecond, MaxMessageAge: time.Millisecond})
+	Started(1)
+	Idle(2)
+	done := Handling(3)
+	time.Sleep(5 * time.Millisecond)
+	r = Current()
+	if r.Healthy || len(r.Chains) != 3 {
+		t.Fatalf("Expected a stale listener and a stuck writer, got %+v", r)
+	}
+	if len(r.Chains[0].Problems) != 1 || len(r.Chains[1].Problems) != 0 || len(r.Chains[2].Problems) != 1 {
+		t.Errorf("Unexpected problems %+v", r.Chains)
+	}
+	done()
+	Processed(1)
+	Init(mapprotocol.RoleOfMessenger, Config{MaxBlockAge: time.Hour, MaxMessageAge: time.Hour})
+	if r = Current(); !r.Healthy || r.Chains[2].Pending != 0 {
+		t.Errorf("Expected healthy once the block is processed and the message done, got %+v", r)
+	}
+
+	reset(Config{})
+	Started(1)
+	Handling(1)
+	time.Sleep(time.Millisecond)
+	if r = Current(); !r.Healthy {
+		t.Errorf("Expected disabled thresholds to be ignored, got %+v", r)
+	}
+}
@@ -0,0 +1,101 @@
+package instrument
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	log "github.com/ChainSafe/log15"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultSlowThreshold is the latency above which a call is logged with its parameters
+const DefaultSlowThreshold = 5 * time.Second
+
+// maxParamsLen is the length slow-call parameters are truncated to in logs
+const maxParamsLen = 512
+
+var (
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "compass",
+		Name:      "rpc_request_duration_seconds",
+		Help:      "Latency of rpc requests per client, endpoint and method",
+		Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"client", "endpoint", "method"})
+	requestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "compass",
+		Name:      "rpc_request_errors_total",
+		Help:      "Number of failed rpc requests per client, endpoint and method",
+	}, []string{"client", "endpoint", "method"})
+	requestsInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "compass",
+		Name:      "rpc_requests_in_flight",
+		Help:      "Number of rpc requests waiting for an answer per client and endpoint",
+	}, []string{"client", "endpoint"})
+)
+
+func init() {
+	prometheus.MustRegister(requestDuration, requestErrors, requestsInFlight)
+}
+
+var slowThreshold = int64(DefaultSlowThreshold)
+
+// SetSlowThreshold sets the latency above which calls are logged with their parameters, 0 disables the log
+func SetSlowThreshold(d time.Duration) {
+	atomic.StoreInt64(&slowThreshold, int64(d))
+}
+
+// Start records the start of a call of method, the returned func records its end and must be called once with
+// the error of the call. endpoint should be a label returned by Endpoint.
+func Start(client, endpoint, method string, params ...interface{}) func(err error) {
+	inFlight := requestsInFlight.WithLabelValues(client, endpoint)
+	inFlight.Inc()
+	start := time.Now()
+	return func(err error) {
+		elapsed := time.Since(start)
+		inFlight.Dec()
+		requestDuration.WithLabelValues(client, endpoint, method).Observe(elapsed.Seconds())
+		if err != nil && !errors.Is(err, context.Canceled) {
+			requestErrors.WithLabelValues(client, endpoint, method).Inc()
+		}
+		if threshold := time.Duration(atomic.LoadInt64(&slowThreshold)); threshold > 0 && elapsed > threshold {
+			log.Warn("Slow rpc call", "client", client, "endpoint", endpoint, "method", method,
+				"elapsed", elapsed, "params", formatParams(params), "err", err)
+		}
+	}
+}
+
+// Endpoint returns the label of an endpoint url, without path, query and credentials which may hold api keys
+func Endpoint(rawurl string) string {
+	parsed, err := url.Parse(rawurl)
+	if err == nil && parsed.Host != "" {
+		return parsed.Scheme + "://" + parsed.Host
+	}
+	// host:port without scheme, as used by grpc
+	host := rawurl
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	if i := strings.IndexAny(host, "/?"); i >= 0 {
+		host = host[:i]
+	}
+	if host == "" {
+		return "invalid-url"
+	}
+	return host
+}
+
+func formatParams(params []interface{}) string {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err.Error()
+	}
+	if len(data) > maxParamsLen {
+		return string(data[:maxParamsLen]) + "..."
+	}
+	return string(data)
+}
@@ -0,0 +1,106 @@
+package instrument
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+var (
+	watchedLock sync.RWMutex
+	watched     = make(map[string]string) // endpoint label -> client
+)
+
+// Watch makes the transports returned by Transport instrument the requests sent to endpoint, as calls of client
+func Watch(client, endpoint string) {
+	watchedLock.Lock()
+	defer watchedLock.Unlock()
+	watched[Endpoint(endpoint)] = client
+}
+
+// Transport returns a RoundTripper instrumenting the json-rpc requests sent through base to the endpoints passed to
+// Watch, other requests pass through. It is meant for clients that do not go through the rpc package, like the
+// near client.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+type rpcRequest struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	Error json.RawMessage `json:"error"`
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := Endpoint(req.URL.String())
+	watchedLock.RLock()
+	client, ok := watched[endpoint]
+	watchedLock.RUnlock()
+	if !ok || req.Body == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	var call rpcRequest
+	if json.Unmarshal(body, &call) != nil || call.Method == "" {
+		return t.base.RoundTrip(req)
+	}
+
+	done := Start(client, endpoint, call.Method, call.Params)
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		done(fmt.Errorf("http status %d", resp.StatusCode))
+		return resp, nil
+	}
+
+	// json-rpc errors are answered with 200
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	var answer rpcResponse
+	if json.Unmarshal(data, &answer) == nil && len(answer.Error) > 0 && string(answer.Error) != "null" {
+		done(fmt.Errorf("rpc error %s", answer.Error))
+		return resp, nil
+	}
+	done(nil)
+	return resp, nil
+}
+
+// UnaryClientInterceptor instruments the unary calls of a grpc client of endpoint
+func UnaryClientInterceptor(client, endpoint string) grpc.UnaryClientInterceptor {
+	endpoint = Endpoint(endpoint)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		done := Start(client, endpoint, path.Base(method), req)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		done(err)
+		return err
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mapprotocol/compass/mapprotocol"
+	"github.com/mapprotocol/compass/msg"
+)
+
+const PathPostfix = ".compass/journal"
+
+// Entry is a signed tx which was (or is about to be) broadcast by a writer
+type Entry struct {
+	Hash      string `json:"hash"`
+	Nonce     uint64 `json:"nonce"`
+	From      string `json:"from"`
+	Message   string `json:"message"` // identity of the message the tx was sent for
+	Raw       string `json:"raw"`     // encoded signed tx, the encoding depends on the chain
+	CreatedAt int64  `json:"createdAt"`
+}
+
+// Age returns how long ago the entry was journaled
+func (e *Entry) Age() time.Duration {
+	return time.Since(time.Unix(e.CreatedAt, 0))
+}
+
+// Journal keeps the pending txs of a writer on disk, so that a restarted writer can resume
+// waiting for them instead of sending the same message again.
+type Journal struct {
+	lock     sync.Mutex
+	path     string // Path excluding filename
+	fullPath string
+	entries  map[string]*Entry // message identity -> entry
+}
+
+func New(path string, chain msg.ChainId, relayer string, role mapprotocol.Role) (*Journal, error) {
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, PathPostfix)
+	}
+	j := &Journal{
+		path:     path,
+		fullPath: filepath.Join(path, fmt.Sprintf("%s-%d-%s.journal", relayer, chain, role)),
+		entries:  make(map[string]*Entry),
+	}
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Identity returns the message identity of a tx calling method on to with input
+func Identity(method, to string, input []byte) string {
+	return fmt.Sprintf("%s-%s-%s", method, to, crypto.Keccak256Hash(input).Hex())
+}
+
+// Add records the entry and flushes the journal to disk, it must be called before the tx is broadcast
+func (j *Journal) Add(e Entry) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if e.CreatedAt == 0 {
+		e.CreatedAt = time.Now().Unix()
+	}
+	j.entries[e.Message] = &e
+	return j.flush()
+}
+
+// Get returns the entry journaled for the message
+func (j *Journal) Get(message string) (Entry, bool) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	e, ok := j.entries[message]
+	if !ok {
+		return Entry{}, false
+	}
+	return *e, true
+}
+
+// Remove drops the entry of the tx hash, it's a no-op if the hash is not journaled
+func (j *Journal) Remove(hash string) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	for k, e := range j.entries {
+		if e.Hash == hash {
+			delete(j.entries, k)
+			return j.flush()
+		}
+	}
+	return nil
+}
+
+// Entries returns all journaled entries ordered by nonce
+func (j *Journal) Entries() []Entry {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	ret := make([]Entry, 0, len(j.entries))
+	for _, e := range j.entries {
+		ret = append(ret, *e)
+	}
+	sort.Slice(ret, func(a, b int) bool {
+		if ret[a].Nonce == ret[b].Nonce {
+			return ret[a].CreatedAt < ret[b].CreatedAt
+		}
+		return ret[a].Nonce < ret[b].Nonce
+	})
+	return ret
+}
+
+func (j *Journal) load() error {
+	data, err := ioutil.ReadFile(j.fullPath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var entries []*Entry
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("journal %s is corrupted: %w", j.fullPath, err)
+	}
+	for _, e := range entries {
+		j.entries[e.Message] = e
+	}
+	return nil
+}
+
+// flush writes the journal to a temporary file and renames it, so a crash never leaves a partial journal behind
+func (j *Journal) flush() error {
+	if _, err := os.Stat(j.path); os.IsNotExist(err) {
+		if err = os.MkdirAll(j.path, os.ModePerm); err != nil {
+			return err
+		}
+	}
+	entries := make([]*Entry, 0, len(j.entries))
+	for _, e := range j.entries {
+		entries = append(entries, e)
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	tmp := j.fullPath + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.fullPath)
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package journal
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/mapprotocol/compass/internal/constant"
+	"github.com/mapprotocol/compass/mapprotocol"
+	"github.com/mapprotocol/compass/msg"
+)
+
+func TestAddAndReload(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	chain := msg.ChainId(10)
+	j, err := New(dir, chain, constant.ZeroAddress.String(), mapprotocol.RoleOfMaintainer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(j.Entries()) != 0 {
+		t.Fatalf("Expected empty journal got %d entries", len(j.Entries()))
+	}
+
+	first := Identity("updateBlockHeader", "0x01", []byte{1})
+	second := Identity("updateBlockHeader", "0x01", []byte{2})
+	if first == second {
+		t.Fatal("Expected different identities for different input")
+	}
+	err = j.Add(Entry{Hash: "0xaa", Nonce: 2, From: "0x02", Message: second, Raw: "0x1234"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = j.Add(Entry{Hash: "0xbb", Nonce: 1, From: "0x02", Message: first, Raw: "0x5678"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A restarted writer sees the same entries
+	j, err = New(dir, chain, constant.ZeroAddress.String(), mapprotocol.RoleOfMaintainer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := j.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Expected: %d got: %d", 2, len(entries))
+	}
+	if entries[0].Hash != "0xbb" || entries[1].Hash != "0xaa" {
+		t.Fatalf("Expected entries ordered by nonce, got %s %s", entries[0].Hash, entries[1].Hash)
+	}
+	e, ok := j.Get(second)
+	if !ok || e.Raw != "0x1234" || e.CreatedAt == 0 {
+		t.Fatalf("Unexpected entry %+v", e)
+	}
+
+	// Another role has its own journal
+	other, err := New(dir, chain, constant.ZeroAddress.String(), mapprotocol.RoleOfMessenger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(other.Entries()) != 0 {
+		t.Fatalf("Expected empty journal got %d entries", len(other.Entries()))
+	}
+
+	if err = j.Remove("0xaa"); err != nil {
+		t.Fatal(err)
+	}
+	j, err = New(dir, chain, constant.ZeroAddress.String(), mapprotocol.RoleOfMaintainer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok = j.Get(second); ok {
+		t.Fatal("Expected removed entry to be gone after reload")
+	}
+	if _, ok = j.Get(first); !ok {
+		t.Fatal("Expected remaining entry after reload")
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package metrics
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/mapprotocol/compass/mapprotocol"
+	"github.com/mapprotocol/compass/msg"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LightClientInterval is how often the light client heights are polled
+const LightClientInterval = time.Minute
+
+// Results of handled messages
+const (
+	ResultOk     = "ok"
+	ResultFailed = "failed"
+)
+
+var (
+	headHeight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "compass",
+		Name:      "chain_head_height",
+		Help:      "Latest block of the chain seen by its listener",
+	}, []string{"chain"})
+	processedHeight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "compass",
+		Name:      "chain_processed_height",
+		Help:      "Latest block of the chain processed by its listener",
+	}, []string{"chain"})
+	lightClientHeight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "compass",
+		Name:      "light_client_height",
+		Help:      "Height of the light client of chain deployed on chain on",
+	}, []string{"chain", "on"})
+	messagesRouted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "compass",
+		Name:      "messages_routed_total",
+		Help:      "Number of messages routed from listeners to writers",
+	}, []string{"source", "destination", "type"})
+	messagesHandled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "compass",
+		Name:      "messages_handled_total",
+		Help:      "Number of messages handled by writers, by result",
+	}, []string{"source", "destination", "type", "result"})
+	writerRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "compass",
+		Name:      "writer_retries_total",
+		Help:      "Number of times writers retried a message",
+	}, []string{"chain", "type"})
+	txConfirmation = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "compass",
+		Name:      "writer_tx_confirmation_seconds",
+		Help:      "Time from broadcasting a writer transaction to its successful receipt",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	}, []string{"chain"})
+)
+
+func init() {
+	prometheus.MustRegister(headHeight, processedHeight, lightClientHeight, messagesRouted, messagesHandled,
+		writerRetries, txConfirmation)
+}
+
+func chainName(id msg.ChainId) string {
+	if name, ok := mapprotocol.OnlineChaId[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", id)
+}
+
+func height(h *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(h).Float64()
+	return f
+}
+
+// Head records the latest block of chain seen by its listener
+func Head(chain msg.ChainId, h *big.Int) {
+	headHeight.WithLabelValues(chainName(chain)).Set(height(h))
+}
+
+// Processed records the latest block of chain processed by its listener
+func Processed(chain msg.ChainId, h *big.Int) {
+	processedHeight.WithLabelValues(chainName(chain)).Set(height(h))
+}
+
+// Routed counts a message passed to the writer of its destination
+func Routed(m msg.Message) {
+	messagesRouted.WithLabelValues(chainName(m.Source), chainName(m.Destination), string(m.Type)).Inc()
+}
+
+// Handled counts a message the writer of its destination is done with
+func Handled(m msg.Message, ok bool) {
+	result := ResultOk
+	if !ok {
+		result = ResultFailed
+	}
+	messagesHandled.WithLabelValues(chainName(m.Source), chainName(m.Destination), string(m.Type), result).Inc()
+}
+
+// Retry counts a message of type t the writer of chain retries
+func Retry(chain msg.ChainId, t msg.TransferType) {
+	writerRetries.WithLabelValues(chainName(chain), string(t)).Inc()
+}
+
+// Confirmed records how long a tx of chain took from its broadcast to its successful receipt
+func Confirmed(chain msg.ChainId, d time.Duration) {
+	txConfirmation.WithLabelValues(chainName(chain)).Observe(d.Seconds())
+}
+
+// WatchLightClients polls the heights of the light clients of chains on MAP and of MAP on chains every interval
+// until stop is closed. The light client heights are read through mapprotocol, which the chains set up.
+func WatchLightClients(mapChain msg.ChainId, chains []msg.ChainId, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, id := range chains {
+			if id == mapChain {
+				continue
+			}
+			if h, err := mapprotocol.Get2MapHeight(id); err == nil && h != nil {
+				lightClientHeight.WithLabelValues(chainName(id), chainName(mapChain)).Set(height(h))
+			}
+			if fn, ok := mapprotocol.Map2OtherHeight[id]; ok {
+				if h, err := fn(); err == nil && h != nil {
+					lightClientHeight.WithLabelValues(chainName(mapChain), chainName(id)).Set(height(h))
+				}
+			}
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package metrics
+
+import (
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/log15"
+	"github.com/mapprotocol/compass/mapprotocol"
+	"github.com/mapprotocol/compass/msg"
+)
+
+func TestServeMetrics(t *testing.T) {
+	mapprotocol.OnlineChaId[1] = "eth"
+	mapprotocol.OnlineChaId[22776] = "map"
+	get2Map := mapprotocol.Get2MapHeight
+	defer func() { mapprotocol.Get2MapHeight = get2Map }()
+	mapprotocol.Get2MapHeight = func(msg.ChainId) (*big.Int, error) { return big.NewInt(90), nil }
+	mapprotocol.Map2OtherHeight[1] = func() (*big.Int, error) { return big.NewInt(800), nil }
+	defer delete(mapprotocol.Map2OtherHeight, 1)
+
+	m := msg.Message{Source: 1, Destination: 22776, Type: msg.SwapWithProof}
+	Head(1, big.NewInt(100))
+	Processed(1, big.NewInt(95))
+	Routed(m)
+	Handled(m, false)
+	Retry(22776, m.Type)
+	Confirmed(22776, 3*time.Second)
+	stop := make(chan struct{})
+	close(stop)
+	WatchLightClients(22776, []msg.ChainId{1, 22776}, time.Minute, stop)
+
+	s := NewServer("127.0.0.1:0", log15.New())
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	resp, err := http.Get("http://" + s.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`compass_chain_head_height{chain="eth"} 100`,
+		`compass_chain_processed_height{chain="eth"} 95`,
+		`compass_light_client_height{chain="eth",on="map"} 90`,
+		`compass_light_client_height{chain="map",on="eth"} 800`,
+		`compass_messages_routed_total{destination="map",source="eth",type="SwapWithProof"} 1`,
+		`compass_messages_handled_total{destination="map",result="failed",source="eth",type="SwapWithProof"} 1`,
+		`compass_writer_retries_total{chain="map",type="SwapWithProof"} 1`,
+		`compass_writer_tx_confirmation_seconds_count{chain="map"} 1`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %s in the metrics", want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ChainSafe/log15"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Server serves the prometheus metrics of the process on /metrics
+type Server struct {
+	addr     string
+	log      log15.Logger
+	mux      *http.ServeMux
+	listener net.Listener
+	srv      *http.Server
+}
+
+func NewServer(addr string, log log15.Logger) *Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return &Server{addr: addr, log: log, mux: mux}
+}
+
+// Handle serves handler on pattern next to the metrics, it must be called before Start
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Start serves the metrics, an error is returned if the address can't be bound
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("metrics server listen on %s failed: %w", s.addr, err)
+	}
+	s.listener = ln
+	s.srv = &http.Server{Handler: s.mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Error("Metrics server stopped", "err", err)
+		}
+	}()
+	s.log.Info("Metrics server started", "addr", ln.Addr())
+	return nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+func (s *Server) Stop() {
+	if s.srv != nil {
+		_ = s.srv.Shutdown(context.Background())
+	}
+}
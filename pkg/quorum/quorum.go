@@ -0,0 +1,146 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package quorum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/mapprotocol/compass/pkg/ethclient"
+)
+
+const requestTimeout = 10 * time.Second
+
+var (
+	// ErrDisagree is returned when endpoints answer the same read differently
+	ErrDisagree = errors.New("endpoints disagree")
+	// ErrNoQuorum is returned when fewer than k endpoints answered
+	ErrNoQuorum = errors.New("not enough endpoints answered")
+)
+
+// Endpoint is an independent rpc provider of a chain
+type Endpoint struct {
+	Name   string // used in errors, must not carry credentials
+	Client *ethclient.Client
+}
+
+// Header reads the hash and receipts root of the block from every endpoint. At least k endpoints must
+// answer and every answer must be the same, otherwise ErrNoQuorum or ErrDisagree is returned.
+func Header(ctx context.Context, k int, endpoints []Endpoint, number *big.Int) (*ethclient.BlockRoots, error) {
+	answers, err := ask(ctx, k, endpoints, func(ctx context.Context, c *ethclient.Client) (string, interface{}, error) {
+		roots, err := c.BlockRootsByNumber(ctx, number)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("hash=%s receiptsRoot=%s", roots.Hash.Hex(), roots.ReceiptsRoot.Hex()), roots, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("block %s: %w", number, err)
+	}
+	return answers.(*ethclient.BlockRoots), nil
+}
+
+// Logs runs the filter query on every endpoint, at least k endpoints must answer and every answer
+// must contain the same logs, otherwise ErrNoQuorum or ErrDisagree is returned.
+func Logs(ctx context.Context, k int, endpoints []Endpoint, q ethereum.FilterQuery) ([]types.Log, error) {
+	answers, err := ask(ctx, k, endpoints, func(ctx context.Context, c *ethclient.Client) (string, interface{}, error) {
+		logs, err := c.FilterLogs(ctx, q)
+		if err != nil {
+			return "", nil, err
+		}
+		digest, err := logsDigest(logs)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("logs=%d digest=%s", len(logs), digest.Hex()), logs, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("logs of blocks %s-%s: %w", q.FromBlock, q.ToBlock, err)
+	}
+	return answers.([]types.Log), nil
+}
+
+// logsDigest hashes the rlp of the logs, their address, topics and data, with the block, tx and position
+// they were emitted at
+func logsDigest(logs []types.Log) (common.Hash, error) {
+	ids := make([][]byte, 0, len(logs)*4)
+	for i := range logs {
+		l := &logs[i]
+		enc, err := rlp.EncodeToBytes(l)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		ids = append(ids, enc, l.BlockHash.Bytes(), l.TxHash.Bytes(), new(big.Int).SetUint64(uint64(l.Index)).Bytes())
+	}
+	return crypto.Keccak256Hash(ids...), nil
+}
+
+type query func(ctx context.Context, c *ethclient.Client) (string, interface{}, error)
+
+// ask runs the query on every endpoint concurrently, answers are compared by the key the query returns
+func ask(ctx context.Context, k int, endpoints []Endpoint, fn query) (interface{}, error) {
+	type answer struct {
+		name  string
+		key   string
+		value interface{}
+		err   error
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	answers := make([]answer, len(endpoints))
+	wg := sync.WaitGroup{}
+	for i, e := range endpoints {
+		wg.Add(1)
+		go func(i int, e Endpoint) {
+			defer wg.Done()
+			a := answer{name: e.Name}
+			if e.Client == nil {
+				a.err = errors.New("not connected")
+			} else {
+				a.key, a.value, a.err = fn(ctx, e.Client)
+			}
+			answers[i] = a
+		}(i, e)
+	}
+	wg.Wait()
+
+	groups := make(map[string][]string)
+	var (
+		value  interface{}
+		failed []string
+	)
+	for _, a := range answers {
+		if a.err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", a.name, a.err))
+			continue
+		}
+		groups[a.key] = append(groups[a.key], a.name)
+		value = a.value
+	}
+	if len(groups) > 1 {
+		keys := make([]string, 0, len(groups))
+		for key, names := range groups {
+			keys = append(keys, fmt.Sprintf("[%s] %s", strings.Join(names, ","), key))
+		}
+		sort.Strings(keys)
+		return nil, fmt.Errorf("%w: %s", ErrDisagree, strings.Join(keys, "; "))
+	}
+	answered := len(answers) - len(failed)
+	if answered < k {
+		return nil, fmt.Errorf("%w: %d of %d required, %s", ErrNoQuorum, answered, k, strings.Join(failed, "; "))
+	}
+	return value, nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package quorum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mapprotocol/compass/pkg/ethclient"
+)
+
+// stubNode is a json-rpc server answering eth_getBlockByNumber and eth_getLogs
+type stubNode struct {
+	*httptest.Server
+	hash         common.Hash
+	receiptsRoot common.Hash
+	logTxs       []common.Hash // a log is returned for each tx
+	logData      string
+	down         bool
+}
+
+func newStubNode(hash, receiptsRoot common.Hash, logTxs ...common.Hash) *stubNode {
+	n := &stubNode{hash: hash, receiptsRoot: receiptsRoot, logTxs: logTxs, logData: "0x"}
+	n.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Id     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || n.down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		var result interface{}
+		switch req.Method {
+		case "eth_getBlockByNumber":
+			result = map[string]interface{}{"number": "0x64", "hash": n.hash, "receiptsRoot": n.receiptsRoot}
+		case "eth_getLogs":
+			logs := make([]map[string]interface{}, 0)
+			for i, tx := range n.logTxs {
+				logs = append(logs, map[string]interface{}{
+					"address":          common.Address{},
+					"topics":           []common.Hash{},
+					"data":             n.logData,
+					"blockNumber":      "0x64",
+					"transactionHash":  tx,
+					"transactionIndex": fmt.Sprintf("0x%x", i),
+					"blockHash":        n.hash,
+					"logIndex":         fmt.Sprintf("0x%x", i),
+					"removed":          false,
+				})
+			}
+			result = logs
+		}
+		data, _ := json.Marshal(result)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.Id, data)
+	}))
+	return n
+}
+
+func endpointsOf(t *testing.T, nodes ...*stubNode) []Endpoint {
+	ret := make([]Endpoint, 0, len(nodes))
+	for i, n := range nodes {
+		c, err := ethclient.Dial(n.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret = append(ret, Endpoint{Name: fmt.Sprintf("node%d", i), Client: c})
+	}
+	return ret
+}
+
+func TestHeader(t *testing.T) {
+	hash, root := common.HexToHash("0x01"), common.HexToHash("0x02")
+	a, b, c := newStubNode(hash, root), newStubNode(hash, root), newStubNode(hash, root)
+	defer a.Close()
+	defer b.Close()
+	defer c.Close()
+	endpoints := endpointsOf(t, a, b, c)
+	number := big.NewInt(100)
+
+	roots, err := Header(context.Background(), 2, endpoints, number)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roots.Hash != hash || roots.ReceiptsRoot != root {
+		t.Fatalf("Unexpected roots %+v", roots)
+	}
+
+	// An endpoint which is down doesn't break the quorum as long as k endpoints answer
+	c.down = true
+	if _, err = Header(context.Background(), 2, endpoints, number); err != nil {
+		t.Fatal(err)
+	}
+	b.down = true
+	if _, err = Header(context.Background(), 2, endpoints, number); !errors.Is(err, ErrNoQuorum) {
+		t.Fatalf("Expected ErrNoQuorum got %v", err)
+	}
+
+	// Endpoints agreeing on the hash but not on the receipts root disagree
+	b.down, c.down = false, false
+	c.receiptsRoot = common.HexToHash("0x03")
+	if _, err = Header(context.Background(), 2, endpoints, number); !errors.Is(err, ErrDisagree) {
+		t.Fatalf("Expected ErrDisagree got %v", err)
+	}
+}
+
+func TestLogs(t *testing.T) {
+	hash, root := common.HexToHash("0x01"), common.HexToHash("0x02")
+	tx1, tx2 := common.HexToHash("0x11"), common.HexToHash("0x12")
+	a, b := newStubNode(hash, root, tx1, tx2), newStubNode(hash, root, tx1, tx2)
+	defer a.Close()
+	defer b.Close()
+	endpoints := endpointsOf(t, a, b)
+	q := ethereum.FilterQuery{FromBlock: big.NewInt(100), ToBlock: big.NewInt(100)}
+
+	logs, err := Logs(context.Background(), 2, endpoints, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 {
+		t.Fatalf("Expected: %d got: %d", 2, len(logs))
+	}
+
+	// An endpoint changing the data of an event disagrees
+	b.logData = "0x01"
+	if _, err = Logs(context.Background(), 2, endpoints, q); !errors.Is(err, ErrDisagree) {
+		t.Fatalf("Expected ErrDisagree got %v", err)
+	}
+
+	// An endpoint hiding an event disagrees
+	b.logData = "0x"
+	b.logTxs = []common.Hash{tx1}
+	if _, err = Logs(context.Background(), 2, endpoints, q); !errors.Is(err, ErrDisagree) {
+		t.Fatalf("Expected ErrDisagree got %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package secret
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	ethkeystore "github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/mapprotocol/compass/keystore"
+)
+
+// DefaultExecTimeout is how long the helper of an exec secret may run
+const DefaultExecTimeout = 30 * time.Second
+
+// PasswordKey is the key name the password of encrypted secrets is looked up with, its env var is
+// KEYSTORE_PASSWORD_CONFIG
+const PasswordKey = "config"
+
+// Redacted replaces secrets in logs
+const Redacted = "***"
+
+// Provider resolves the argument of a ${secret:<provider>:<argument>} reference to the secret
+type Provider interface {
+	Resolve(arg string) (string, error)
+}
+
+// scrypt parameters of Encrypt, lowered by tests
+var scryptN, scryptP = ethkeystore.StandardScryptN, ethkeystore.StandardScryptP
+
+var (
+	lock      sync.RWMutex
+	providers = map[string]Provider{
+		"env":  Env{},
+		"file": File{},
+		"exec": Exec{Timeout: DefaultExecTimeout},
+		"enc":  &Encrypted{Password: keystore.PasswordSource{}},
+	}
+)
+
+// Register makes a provider available under name, replacing the provider of that name if any
+func Register(name string, p Provider) {
+	lock.Lock()
+	defer lock.Unlock()
+	providers[name] = p
+}
+
+var refRe = regexp.MustCompile(`\$\{secret:([a-zA-Z0-9_-]+):([^}]*)\}`)
+
+// Interpolate replaces the secret references of v by the secrets, which are returned too so they can be redacted
+func Interpolate(v string) (string, []string, error) {
+	var (
+		secrets  []string
+		firstErr error
+	)
+	ret := refRe.ReplaceAllStringFunc(v, func(ref string) string {
+		if firstErr != nil {
+			return ref
+		}
+		m := refRe.FindStringSubmatch(ref)
+		lock.RLock()
+		p, ok := providers[m[1]]
+		lock.RUnlock()
+		if !ok {
+			firstErr = fmt.Errorf("unknown secret provider %s", m[1])
+			return ref
+		}
+		s, err := p.Resolve(m[2])
+		if err != nil {
+			firstErr = fmt.Errorf("resolve %s secret failed: %w", m[1], err)
+			return ref
+		}
+		if s != "" {
+			secrets = append(secrets, s)
+		}
+		return s
+	})
+	if firstErr != nil {
+		return "", nil, firstErr
+	}
+	return ret, secrets, nil
+}
+
+// Redact replaces the secrets in v
+func Redact(v string, secrets []string) string {
+	for _, s := range secrets {
+		v = strings.ReplaceAll(v, s, Redacted)
+	}
+	return v
+}
+
+// Env reads the secret from an environment variable
+type Env struct{}
+
+func (Env) Resolve(name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return v, nil
+}
+
+// File reads the secret from a file, without trailing whitespace
+type File struct{}
+
+func (File) Resolve(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Exec runs a helper printing the secret to stdout, the argument is the command line of the helper, which is not
+// run through a shell
+type Exec struct {
+	Timeout time.Duration
+}
+
+func (e Exec) Resolve(cmdline string) (string, error) {
+	args := strings.Fields(cmdline)
+	if len(args) == 0 {
+		return "", fmt.Errorf("no helper command")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.Timeout)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("helper %s failed: %v %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Encrypted decrypts a secret encrypted with scrypt like an ethereum keystore, the argument is the base64 of the
+// crypto field of the keystore. The password is looked up once as the password of the PasswordKey key.
+type Encrypted struct {
+	Password keystore.PasswordSource
+
+	once     sync.Once
+	password []byte
+	err      error
+}
+
+func (e *Encrypted) Resolve(arg string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(arg)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted secret: %v", err)
+	}
+	var cj ethkeystore.CryptoJSON
+	if err = json.Unmarshal(data, &cj); err != nil {
+		return "", fmt.Errorf("invalid encrypted secret: %v", err)
+	}
+	e.once.Do(func() {
+		e.password, e.err = e.Password.Password(PasswordKey, "encrypted config secrets")
+	})
+	if e.err != nil {
+		return "", e.err
+	}
+	plain, err := ethkeystore.DecryptDataV3(cj, string(e.password))
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// Encrypt returns the argument of an enc secret holding value, encrypted with password
+func Encrypt(value, password []byte) (string, error) {
+	cj, err := ethkeystore.EncryptDataV3(value, password, scryptN, scryptP)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(cj)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ethkeystore "github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/mapprotocol/compass/keystore"
+)
+
+func init() {
+	scryptN, scryptP = ethkeystore.LightScryptN, ethkeystore.LightScryptP
+}
+
+func TestInterpolate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "redis")
+	if err := os.WriteFile(file, []byte("redis-pass\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_RPC_KEY", "rpc-key")
+	t.Setenv("KEYSTORE_PASSWORD_TEST_CONFIG", "config-pass")
+	enc, err := Encrypt([]byte("webhook-token"), []byte("config-pass"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Register("test-enc", &Encrypted{Password: keystore.PasswordSource{Env: "KEYSTORE_PASSWORD_TEST_CONFIG"}})
+
+	for v, want := range map[string]string{
+		"https://eth.example/v3/${secret:env:TEST_RPC_KEY}":       "https://eth.example/v3/rpc-key",
+		"redis://:${secret:file:" + file + "}@localhost:6379":     "redis://:redis-pass@localhost:6379",
+		"${secret:exec:echo exec-secret}":                         "exec-secret",
+		"https://hooks.example/${secret:test-enc:" + enc + "}":    "https://hooks.example/webhook-token",
+		"${secret:env:TEST_RPC_KEY}/${secret:env:TEST_RPC_KEY}/x": "rpc-key/rpc-key/x",
+		"no secret": "no secret",
+	} {
+		got, secrets, err := Interpolate(v)
+		if err != nil {
+			t.Fatalf("%s: %v", v, err)
+		}
+		if got != want {
+			t.Errorf("Interpolate(%s) = %s, want %s", v, got, want)
+		}
+		if redacted := Redact(got, secrets); strings.Contains(v, "${secret:") && redacted == got {
+			t.Errorf("Expected the secrets of %s to be redacted, got %s", v, redacted)
+		}
+	}
+}
+
+func TestInterpolateErrors(t *testing.T) {
+	t.Setenv("KEYSTORE_PASSWORD_TEST_WRONG", "wrong")
+	enc, err := Encrypt([]byte("value"), []byte("right"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Register("test-wrong", &Encrypted{Password: keystore.PasswordSource{Env: "KEYSTORE_PASSWORD_TEST_WRONG"}})
+
+	for _, v := range []string{
+		"${secret:env:TEST_UNSET_SECRET}",
+		"${secret:file:" + filepath.Join(t.TempDir(), "none") + "}",
+		"${secret:exec:false}",
+		"${secret:unknown:x}",
+		"${secret:test-wrong:" + enc + "}",
+		"${secret:test-wrong:not base64}",
+	} {
+		if got, _, err := Interpolate(v); err == nil {
+			t.Errorf("Expected %s to fail, got %s", v, got)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package signer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mapprotocol/compass/pkg/alert"
+)
+
+// AnyMethod allows every method of a contract in the allowed calls of a policy
+const AnyMethod = "*"
+
+var selectorRe = regexp.MustCompile(`^0x[0-9a-fA-F]{8}$`)
+
+// Policy restricts what the relayer keys of a chain will sign. Violations are refused and raise an alarm.
+type Policy struct {
+	Chain       string
+	Calls       map[string]map[string]bool // allowed methods by contract, any call is allowed if nil
+	MaxValue    *big.Int                   // value sent with a call, zero if nil
+	MaxGasPrice *big.Int                   // gas price or fee cap of a tx, not capped if nil
+}
+
+// ParseCalls parses the allowed calls of a policy, a JSON object of contract to the methods which may be called on it.
+// Methods are 4 byte selectors, solidity signatures such as "transferIn(uint256,bytes)", NEAR method names or "*".
+func ParseCalls(s string) (map[string]map[string]bool, error) {
+	var raw map[string][]string
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		return nil, err
+	}
+	ret := make(map[string]map[string]bool, len(raw))
+	for contract, methods := range raw {
+		if len(methods) == 0 {
+			return nil, fmt.Errorf("no method allowed on contract %s", contract)
+		}
+		set := make(map[string]bool, len(methods))
+		for _, m := range methods {
+			set[normalizeMethod(strings.TrimSpace(m))] = true
+		}
+		ret[normalizeContract(contract)] = set
+	}
+	return ret, nil
+}
+
+// AllowContracts returns the allowed calls of any method of contracts, empty and zero addresses are skipped
+func AllowContracts(contracts []string) map[string]map[string]bool {
+	ret := make(map[string]map[string]bool, len(contracts))
+	for _, contract := range contracts {
+		contract = normalizeContract(contract)
+		if contract == "" || contract == strings.ToLower(common.Address{}.Hex()) {
+			continue
+		}
+		ret[contract] = map[string]bool{AnyMethod: true}
+	}
+	return ret
+}
+
+func normalizeContract(contract string) string {
+	contract = strings.TrimSpace(contract)
+	if common.IsHexAddress(contract) {
+		return strings.ToLower(common.HexToAddress(contract).Hex())
+	}
+	return contract
+}
+
+func normalizeMethod(method string) string {
+	switch {
+	case selectorRe.MatchString(method):
+		return strings.ToLower(method)
+	case strings.Contains(method, "("):
+		return Selector(crypto.Keccak256([]byte(strings.ReplaceAll(method, " ", ""))))
+	}
+	return method
+}
+
+// Selector returns the method of EVM calldata, its 4 byte selector in hex
+func Selector(data []byte) string {
+	if len(data) < 4 {
+		return hexutil.Encode(data)
+	}
+	return hexutil.Encode(data[:4])
+}
+
+// Violation is a tx refused by a policy
+type Violation struct {
+	Chain    string
+	Contract string
+	Method   string
+	Reason   string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("signing policy of %s refused call of %s on %s: %s", v.Chain, v.Method, v.Contract, v.Reason)
+}
+
+// Check returns a Violation if the policy doesn't allow calling method of contract with value and gasPrice, a nil
+// gasPrice isn't checked
+func (p *Policy) Check(ctx context.Context, contract, method string, value, gasPrice *big.Int) error {
+	maxValue := p.MaxValue
+	if maxValue == nil {
+		maxValue = new(big.Int)
+	}
+	switch {
+	case value != nil && value.Cmp(maxValue) > 0:
+		return p.Refuse(ctx, contract, method, fmt.Sprintf("value %s is above %s", value, maxValue))
+	case gasPrice != nil && p.MaxGasPrice != nil && gasPrice.Cmp(p.MaxGasPrice) > 0:
+		return p.Refuse(ctx, contract, method, fmt.Sprintf("gas price %s is above %s", gasPrice, p.MaxGasPrice))
+	case p.Calls == nil:
+		return nil
+	}
+	methods, ok := p.Calls[normalizeContract(contract)]
+	if !ok {
+		return p.Refuse(ctx, contract, method, "contract is not allowed")
+	}
+	if !methods[AnyMethod] && !methods[normalizeMethod(method)] {
+		return p.Refuse(ctx, contract, method, "method is not allowed")
+	}
+	return nil
+}
+
+// Refuse returns the Violation of a call refused for reason, and raises a critical alert for it
+func (p *Policy) Refuse(ctx context.Context, contract, method, reason string) error {
+	v := &Violation{Chain: p.Chain, Contract: contract, Method: method, Reason: reason}
+	alert.Fire(ctx, alert.Critical, p.Chain, fmt.Sprintf("%s/%s", alert.KeySignerPolicy, contract), v.Error())
+	return v
+}
+
+// Guard checks every tx against a policy before the wrapped signer signs it
+type Guard struct {
+	Signer
+	policy *Policy
+}
+
+func NewGuard(s Signer, policy *Policy) *Guard {
+	return &Guard{Signer: s, policy: policy}
+}
+
+func (g *Guard) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if tx.To() == nil {
+		return nil, g.policy.Refuse(ctx, "", Selector(tx.Data()), "contract creation is not allowed")
+	}
+	if err := g.policy.Check(ctx, tx.To().Hex(), Selector(tx.Data()), tx.Value(), tx.GasFeeCap()); err != nil {
+		return nil, err
+	}
+	return g.Signer.SignTx(ctx, tx, chainID)
+}
@@ -0,0 +1,140 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package signer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestParseCalls(t *testing.T) {
+	calls, err := ParseCalls(`{"0x0000000000000000000000000000000000001234": ["transferIn(uint256, bytes)", "0xABCDEF01"],
+		"mos.map007.near": ["swap_in"], "0x00000000000000000000000000000000000000aa": ["*"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transferIn := Selector(crypto.Keccak256([]byte("transferIn(uint256,bytes)")))
+	for contract, method := range map[string]string{
+		"0x0000000000000000000000000000000000001234": transferIn,
+		"0x00000000000000000000000000000000000000aa": AnyMethod,
+		"mos.map007.near": "swap_in",
+	} {
+		if !calls[contract][method] {
+			t.Errorf("Expected %s to be allowed on %s, got %v", method, contract, calls[contract])
+		}
+	}
+	if !calls["0x0000000000000000000000000000000000001234"]["0xabcdef01"] {
+		t.Error("Expected the selector to be lower-cased")
+	}
+
+	for _, s := range []string{`[]`, `{"0x1234": []}`, `{"0x1234": "transferIn"}`} {
+		if _, err = ParseCalls(s); err == nil {
+			t.Errorf("Expected %s to be refused", s)
+		}
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	mcs := "0x0000000000000000000000000000000000001234"
+	calls, err := ParseCalls(`{"` + mcs + `": ["0x12345678"], "mos.near": ["swap_in"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &Policy{Chain: "map", Calls: calls, MaxGasPrice: big.NewInt(100)}
+	ctx := context.Background()
+
+	for _, c := range []struct {
+		contract, method string
+		value, gasPrice  int64
+		ok               bool
+	}{
+		{mcs, "0x12345678", 0, 100, true},
+		{"0x0000000000000000000000000000000000001234", "0x12345678", 0, 0, true},
+		{"mos.near", "swap_in", 0, 0, true},
+		{mcs, "0x12345679", 0, 1, false},
+		{"0x0000000000000000000000000000000000005678", "0x12345678", 0, 1, false},
+		{mcs, "0x12345678", 1, 1, false},
+		{mcs, "0x12345678", 0, 101, false},
+		{"mos.near", "transfer_in", 0, 0, false},
+	} {
+		err := policy.Check(ctx, c.contract, c.method, big.NewInt(c.value), big.NewInt(c.gasPrice))
+		var v *Violation
+		if c.ok && err != nil || !c.ok && !errors.As(err, &v) {
+			t.Errorf("%s %s value %d gas price %d: unexpected result %v", c.contract, c.method, c.value, c.gasPrice, err)
+		}
+	}
+
+	open := &Policy{Chain: "near", MaxValue: big.NewInt(10)}
+	if err = open.Check(ctx, "any.near", "any", big.NewInt(10), nil); err != nil {
+		t.Errorf("Expected a policy without calls to allow any call, got %v", err)
+	}
+	if err = open.Check(ctx, "any.near", "any", big.NewInt(11), nil); err == nil {
+		t.Error("Expected a value above the cap to be refused")
+	}
+}
+
+func TestAllowContracts(t *testing.T) {
+	mcs := "0x0000000000000000000000000000000000001234"
+	policy := &Policy{Chain: "eth", Calls: AllowContracts([]string{" " + strings.ToUpper(mcs[2:]), "", common.Address{}.Hex()})}
+	if len(policy.Calls) != 1 {
+		t.Fatalf("Expected only the mcs to be allowed, got %v", policy.Calls)
+	}
+	ctx := context.Background()
+	if err := policy.Check(ctx, mcs, "0x12345678", nil, nil); err != nil {
+		t.Errorf("Expected any method of the mcs to be allowed, got %v", err)
+	}
+	if err := policy.Check(ctx, "0x0000000000000000000000000000000000005678", "0x12345678", nil, nil); err == nil {
+		t.Error("Expected another contract to be refused")
+	}
+}
+
+func TestGuard(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	local := NewLocal(&keystore.Key{Address: crypto.PubkeyToAddress(key.PublicKey), PrivateKey: key})
+	txs := testTxs()
+	calls, err := ParseCalls(`{"` + txs[0].To().Hex() + `": ["` + Selector(txs[1].Data()) + `"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guard := NewGuard(local, &Policy{Chain: "map", Calls: calls, MaxGasPrice: big.NewInt(3e10)})
+	if guard.Address() != local.Address() {
+		t.Errorf("Guard signs for %s, want %s", guard.Address(), local.Address())
+	}
+
+	// txs[0] sends value, txs[1] is allowed
+	if _, err = guard.SignTx(context.Background(), txs[0], big.NewInt(1)); err == nil {
+		t.Error("Expected a tx with value to be refused")
+	}
+	signed, err := guard.SignTx(context.Background(), txs[1], big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, err := types.Sender(types.NewLondonSigner(big.NewInt(1)), signed)
+	if err != nil || from != local.Address() {
+		t.Errorf("Signed by %s, err %v", from, err)
+	}
+
+	expensive := types.NewTx(&types.DynamicFeeTx{To: txs[1].To(), Gas: 100000, GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(3e10 + 1), Value: new(big.Int), Data: txs[1].Data()})
+	if _, err = guard.SignTx(context.Background(), expensive, big.NewInt(1)); err == nil {
+		t.Error("Expected a fee cap above the maximum gas price to be refused")
+	}
+	creation := types.NewTx(&types.LegacyTx{Gas: 100000, GasPrice: big.NewInt(1), Data: txs[1].Data()})
+	if _, err = guard.SignTx(context.Background(), creation, big.NewInt(1)); err == nil {
+		t.Error("Expected a contract creation to be refused")
+	}
+	other := common.HexToAddress("0x5678")
+	call := types.NewTx(&types.LegacyTx{To: &other, Gas: 100000, GasPrice: big.NewInt(1), Value: new(big.Int), Data: txs[1].Data()})
+	if _, err = guard.SignTx(context.Background(), call, big.NewInt(1)); err == nil {
+		t.Error("Expected a call of another contract to be refused")
+	}
+}
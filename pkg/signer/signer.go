@@ -0,0 +1,156 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package signer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/mapprotocol/compass/pkg/ethclient"
+)
+
+// Signer signs the txs of one relayer address
+type Signer interface {
+	Address() common.Address
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// Local signs with a key decrypted from a keystore
+type Local struct {
+	key *keystore.Key
+}
+
+func NewLocal(key *keystore.Key) *Local {
+	return &Local{key: key}
+}
+
+func (l *Local) Address() common.Address {
+	return l.key.Address
+}
+
+func (l *Local) Key() *keystore.Key {
+	return l.key
+}
+
+func (l *Local) SignTx(_ context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.NewLondonSigner(chainID), l.key.PrivateKey)
+}
+
+// Remote asks a signer service holding the key to sign, through the eth_signTransaction method of
+// web3signer and clef. The credentials and TLS config set by ethclient.SetAuth for its url are used.
+type Remote struct {
+	url     string
+	address common.Address
+	client  *rpc.Client
+}
+
+// DialRemote connects to the signer service at url, which signs for address
+func DialRemote(ctx context.Context, url string, address common.Address) (*Remote, error) {
+	client, err := ethclient.DialRPC(ctx, "", url)
+	if err != nil {
+		return nil, fmt.Errorf("dial signer %s failed: %w", url, err)
+	}
+	return &Remote{url: url, address: address, client: client}, nil
+}
+
+func (r *Remote) Address() common.Address {
+	return r.address
+}
+
+// Accounts returns the addresses the signer service holds keys of
+func (r *Remote) Accounts(ctx context.Context) ([]common.Address, error) {
+	var ret []common.Address
+	err := r.client.CallContext(ctx, &ret, "eth_accounts")
+	return ret, err
+}
+
+type sendTxArgs struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to,omitempty"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	GasPrice             *hexutil.Big    `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big    `json:"value"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Data                 hexutil.Bytes   `json:"data"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+}
+
+// SignTx sends the fields of tx to the signer service, and checks the signed tx it returns is tx signed by the
+// address of r
+func (r *Remote) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := sendTxArgs{
+		From:    r.address,
+		To:      tx.To(),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   (*hexutil.Big)(tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    tx.Data(),
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	if tx.Type() == types.LegacyTxType {
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	} else {
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	}
+
+	var result json.RawMessage
+	if err := r.client.CallContext(ctx, &result, "eth_signTransaction", args); err != nil {
+		return nil, fmt.Errorf("signer %s: %w", r.url, err)
+	}
+	raw, err := decodeSignResult(result)
+	if err != nil {
+		return nil, fmt.Errorf("signer %s: %w", r.url, err)
+	}
+	signed := new(types.Transaction)
+	if err = signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("signer %s returned an invalid tx: %w", r.url, err)
+	}
+
+	londonSigner := types.NewLondonSigner(chainID)
+	from, err := types.Sender(londonSigner, signed)
+	if err != nil {
+		return nil, fmt.Errorf("signer %s returned an invalid signature: %w", r.url, err)
+	}
+	if from != r.address {
+		return nil, fmt.Errorf("signer %s signed with %s instead of %s", r.url, from, r.address)
+	}
+	if londonSigner.Hash(signed) != londonSigner.Hash(tx) {
+		return nil, fmt.Errorf("signer %s signed a different tx", r.url)
+	}
+	return signed, nil
+}
+
+func (r *Remote) Close() {
+	r.client.Close()
+}
+
+// decodeSignResult returns the raw signed tx, web3signer answers with the raw tx, clef and geth with an object
+// holding it
+func decodeSignResult(result json.RawMessage) ([]byte, error) {
+	var raw hexutil.Bytes
+	if err := json.Unmarshal(result, &raw); err == nil {
+		return raw, nil
+	}
+	var obj struct {
+		Raw hexutil.Bytes `json:"raw"`
+	}
+	if err := json.Unmarshal(result, &obj); err != nil {
+		return nil, err
+	}
+	if len(obj.Raw) == 0 {
+		return nil, errors.New("no signed tx in the response")
+	}
+	return obj.Raw, nil
+}
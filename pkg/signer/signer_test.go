@@ -0,0 +1,188 @@
+// Copyright 2021 Compass Systems
+// SPDX-License-Identifier: LGPL-3.0-only
+
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/pem"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/mapprotocol/compass/pkg/ethclient"
+)
+
+// standIn is a signer service holding one key, like web3signer or clef
+type standIn struct {
+	key    *ecdsa.PrivateKey
+	clef   bool // answer with an object holding the raw tx
+	tamper bool // sign another nonce than requested
+	calls  *int // number of signatures
+}
+
+func (s *standIn) Accounts() []common.Address {
+	return []common.Address{crypto.PubkeyToAddress(s.key.PublicKey)}
+}
+
+func (s *standIn) SignTransaction(args sendTxArgs) (interface{}, error) {
+	*s.calls++
+	nonce := uint64(args.Nonce)
+	if s.tamper {
+		nonce++
+	}
+	var td types.TxData
+	if args.GasPrice != nil {
+		td = &types.LegacyTx{Nonce: nonce, To: args.To, Gas: uint64(args.Gas), GasPrice: args.GasPrice.ToInt(),
+			Value: args.Value.ToInt(), Data: args.Data}
+	} else {
+		td = &types.DynamicFeeTx{ChainID: args.ChainID.ToInt(), Nonce: nonce, To: args.To, Gas: uint64(args.Gas),
+			GasTipCap: args.MaxPriorityFeePerGas.ToInt(), GasFeeCap: args.MaxFeePerGas.ToInt(), Value: args.Value.ToInt(), Data: args.Data}
+	}
+	signed, err := types.SignTx(types.NewTx(td), types.NewLondonSigner(args.ChainID.ToInt()), s.key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if s.clef {
+		return map[string]interface{}{"raw": hexutil.Bytes(raw), "tx": signed}, nil
+	}
+	return hexutil.Bytes(raw), nil
+}
+
+func newStandIn(t *testing.T, svc *standIn, tls bool) *httptest.Server {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("eth", svc); err != nil {
+		t.Fatal(err)
+	}
+	var ts *httptest.Server
+	if tls {
+		ts = httptest.NewTLSServer(srv)
+	} else {
+		ts = httptest.NewServer(srv)
+	}
+	t.Cleanup(func() {
+		ts.Close()
+		srv.Stop()
+	})
+	return ts
+}
+
+func testTxs() []*types.Transaction {
+	to := common.HexToAddress("0x1234")
+	return []*types.Transaction{
+		types.NewTx(&types.LegacyTx{Nonce: 7, To: &to, Gas: 21000, GasPrice: big.NewInt(5e9), Value: big.NewInt(1), Data: []byte{1, 2}}),
+		types.NewTx(&types.DynamicFeeTx{Nonce: 8, To: &to, Gas: 100000, GasTipCap: big.NewInt(1e9), GasFeeCap: big.NewInt(3e10),
+			Value: new(big.Int), Data: []byte{3}}),
+	}
+}
+
+func TestRemoteSign(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	local := NewLocal(&keystore.Key{Address: crypto.PubkeyToAddress(key.PublicKey), PrivateKey: key})
+	chainID := big.NewInt(22776)
+
+	for _, clef := range []bool{false, true} {
+		calls := 0
+		ts := newStandIn(t, &standIn{key: key, clef: clef, calls: &calls}, false)
+		remote, err := DialRemote(context.Background(), ts.URL, local.Address())
+		if err != nil {
+			t.Fatal(err)
+		}
+		accounts, err := remote.Accounts(context.Background())
+		if err != nil || len(accounts) != 1 || accounts[0] != local.Address() {
+			t.Fatalf("Unexpected accounts %v, err %v", accounts, err)
+		}
+		for _, tx := range testTxs() {
+			got, err := remote.SignTx(context.Background(), tx, chainID)
+			if err != nil {
+				t.Fatalf("clef %v: %v", clef, err)
+			}
+			want, err := local.SignTx(context.Background(), tx, chainID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// signatures are deterministic, both signers must produce the same tx
+			if got.Hash() != want.Hash() {
+				t.Errorf("clef %v: remote signed %s, local %s", clef, got.Hash(), want.Hash())
+			}
+		}
+		if calls != 2 {
+			t.Errorf("Expected 2 signatures, got %d", calls)
+		}
+		remote.Close()
+	}
+}
+
+func TestRemoteSignRejected(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	chainID := big.NewInt(1)
+	tx := testTxs()[1]
+
+	calls := 0
+	ts := newStandIn(t, &standIn{key: other, calls: &calls}, false)
+	remote, err := DialRemote(context.Background(), ts.URL, crypto.PubkeyToAddress(key.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = remote.SignTx(context.Background(), tx, chainID); err == nil || !strings.Contains(err.Error(), "instead of") {
+		t.Errorf("Expected a signature of another key to be rejected, got %v", err)
+	}
+
+	ts = newStandIn(t, &standIn{key: key, tamper: true, calls: &calls}, false)
+	remote, err = DialRemote(context.Background(), ts.URL, crypto.PubkeyToAddress(key.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = remote.SignTx(context.Background(), tx, chainID); err == nil || !strings.Contains(err.Error(), "different tx") {
+		t.Errorf("Expected a changed tx to be rejected, got %v", err)
+	}
+}
+
+func TestRemoteSignTLS(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	calls := 0
+	ts := newStandIn(t, &standIn{key: key, calls: &calls}, true)
+
+	remote, err := DialRemote(context.Background(), ts.URL, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = remote.SignTx(context.Background(), testTxs()[0], big.NewInt(1)); err == nil {
+		t.Fatal("Expected the self signed certificate of the signer to be rejected")
+	}
+
+	ca := filepath.Join(t.TempDir(), "ca.crt")
+	if err = os.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tlsConfig, err := ethclient.NewTLSConfig("", "", ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ethclient.SetAuth(ts.URL, &ethclient.Auth{TLS: tlsConfig})
+	defer ethclient.SetAuth(ts.URL, nil)
+
+	remote, err = DialRemote(context.Background(), ts.URL, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = remote.SignTx(context.Background(), testTxs()[0], big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,47 @@
+package remoteattestation
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/edgelesssys/ego/enclave"
+)
+
+// Provider produces the attestation reports of the enclave, binding data, the hash of the server certificate, to it
+type Provider interface {
+	Name() string
+	Report(data []byte) ([]byte, error)
+}
+
+// Enclave is the provider of an ego enclave, its reports are verified with the ego attestation tools
+type Enclave struct{}
+
+func (Enclave) Name() string {
+	return "ego"
+}
+
+func (Enclave) Report(data []byte) ([]byte, error) {
+	return enclave.GetRemoteReport(data)
+}
+
+// simulatedPrefix starts the reports of Simulated, so they can't be taken for a report of an enclave
+var simulatedPrefix = []byte("compass simulated report:")
+
+// Simulated is a provider for tests outside an enclave, its reports are the data with a prefix and attest nothing
+type Simulated struct{}
+
+func (Simulated) Name() string {
+	return "simulated"
+}
+
+func (Simulated) Report(data []byte) ([]byte, error) {
+	return append(append([]byte(nil), simulatedPrefix...), data...), nil
+}
+
+// VerifySimulated returns the data of a report of Simulated
+func VerifySimulated(report []byte) ([]byte, error) {
+	if !bytes.HasPrefix(report, simulatedPrefix) {
+		return nil, errors.New("not a simulated report")
+	}
+	return report[len(simulatedPrefix):], nil
+}
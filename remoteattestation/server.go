@@ -0,0 +1,231 @@
+package remoteattestation
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ChainSafe/log15"
+	"github.com/mapprotocol/compass/keystore"
+)
+
+const maxSecretSize = 1 << 12
+
+// Config of the attestation server
+type Config struct {
+	Addr         string        // listen address
+	CertLifetime time.Duration // validity of the self-signed certificate
+	CertRotation time.Duration // a new certificate and report are made this often, shorter than CertLifetime
+	Accounts     []string      // keys whose password may be provisioned, any key if empty
+}
+
+// attested is a certificate of the server with the report binding its hash to the enclave
+type attested struct {
+	cert   tls.Certificate
+	report []byte
+}
+
+// Server serves the attestation report of the enclave over TLS with a certificate bound to the report, and
+// accepts keystore passwords over the attested channel.
+//
+// A client fetches /cert and /report, checks the report, that its data is the sha256 of the certificate and that
+// the TLS certificate is the same one, then posts {"address": ..., "password": ...} to /secret. The certificate is
+// rotated every CertRotation, a client seeing a different certificate than the one attested fetches both again.
+type Server struct {
+	cfg      Config
+	provider Provider
+	log      log15.Logger
+	accounts map[string]bool
+
+	lock    sync.RWMutex
+	current *attested
+
+	listener net.Listener
+	srv      *http.Server
+	stop     chan struct{}
+}
+
+func NewServer(cfg Config, provider Provider, log log15.Logger) (*Server, error) {
+	if cfg.CertLifetime <= 0 || cfg.CertRotation <= 0 || cfg.CertRotation >= cfg.CertLifetime {
+		return nil, fmt.Errorf("attestation certificate rotation %s must be positive and shorter than its lifetime %s",
+			cfg.CertRotation, cfg.CertLifetime)
+	}
+	accounts := make(map[string]bool, len(cfg.Accounts))
+	for _, a := range cfg.Accounts {
+		accounts[strings.ToLower(a)] = true
+	}
+	return &Server{cfg: cfg, provider: provider, log: log, accounts: accounts, stop: make(chan struct{})}, nil
+}
+
+// Start makes the first certificate and report and serves them, an error is returned if the address can't be bound
+func (s *Server) Start() error {
+	if err := s.rotate(); err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("attestation server listen on %s failed: %w", s.cfg.Addr, err)
+	}
+	s.listener = ln
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cert", s.handleCert)
+	mux.HandleFunc("/report", s.handleReport)
+	mux.HandleFunc("/secret", s.handleSecret)
+	s.srv = &http.Server{
+		Handler:           mux,
+		TLSConfig:         &tls.Config{GetCertificate: s.certificate, MinVersion: tls.VersionTLS12},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := s.srv.ServeTLS(ln, "", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Error("Attestation server stopped", "err", err)
+		}
+	}()
+	go s.rotateLoop()
+	s.log.Info("Attestation server started", "addr", ln.Addr(), "provider", s.provider.Name(),
+		"certLifetime", s.cfg.CertLifetime, "certRotation", s.cfg.CertRotation)
+	return nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+func (s *Server) Stop() {
+	close(s.stop)
+	if s.srv != nil {
+		_ = s.srv.Shutdown(context.Background())
+	}
+}
+
+func (s *Server) rotateLoop() {
+	ticker := time.NewTicker(s.cfg.CertRotation)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.rotate(); err != nil {
+				s.log.Warn("Rotate attestation certificate failed, the current one is kept", "err", err)
+			}
+		}
+	}
+}
+
+// rotate makes a new certificate and the report binding it
+func (s *Server) rotate() error {
+	cert, err := createCertificate(s.cfg.CertLifetime)
+	if err != nil {
+		return fmt.Errorf("create attestation certificate failed: %w", err)
+	}
+	hash := sha256.Sum256(cert.Certificate[0])
+	report, err := s.provider.Report(hash[:])
+	if err != nil {
+		return fmt.Errorf("get %s attestation report failed: %w", s.provider.Name(), err)
+	}
+	s.lock.Lock()
+	s.current = &attested{cert: cert, report: report}
+	s.lock.Unlock()
+	s.log.Debug("Attestation certificate rotated", "expires", cert.Leaf.NotAfter)
+	return nil
+}
+
+func (s *Server) attested() *attested {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.current
+}
+
+func (s *Server) certificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return &s.attested().cert, nil
+}
+
+func (s *Server) handleCert(w http.ResponseWriter, _ *http.Request) {
+	_, _ = w.Write(s.attested().cert.Certificate[0])
+}
+
+func (s *Server) handleReport(w http.ResponseWriter, _ *http.Request) {
+	_, _ = w.Write(s.attested().report)
+}
+
+type secret struct {
+	Address  string `json:"address"`
+	Password string `json:"password"`
+}
+
+// handleSecret provisions the keystore password of a key, the password is never logged. The endpoint takes no
+// credentials, so only the first password of a key is accepted: a later post can't replace the password the
+// operator provisioned, and a key already provisioned by someone else is refused and reported.
+func (s *Server) handleSecret(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "secrets must be posted as a JSON body", http.StatusMethodNotAllowed)
+		return
+	}
+	var sec secret
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxSecretSize)).Decode(&sec); err != nil || sec.Address == "" ||
+		sec.Password == "" {
+		http.Error(w, "body must be {\"address\": ..., \"password\": ...}", http.StatusBadRequest)
+		return
+	}
+	if len(s.accounts) != 0 && !s.accounts[strings.ToLower(sec.Address)] {
+		s.log.Warn("Refused keystore password of an unknown key", "address", sec.Address, "remote", r.RemoteAddr)
+		http.Error(w, "unknown key", http.StatusForbidden)
+		return
+	}
+	if !keystore.Provision(sec.Address, []byte(sec.Password)) {
+		s.log.Warn("Refused keystore password of a key already provisioned", "address", sec.Address, "remote", r.RemoteAddr)
+		http.Error(w, "key already provisioned", http.StatusConflict)
+		return
+	}
+	s.log.Info("Keystore password provisioned", "address", sec.Address, "remote", r.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func createCertificate(lifetime time.Duration) (tls.Certificate, error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(lifetime),
+		DNSNames:     []string{"localhost"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: priv, Leaf: leaf}, nil
+}
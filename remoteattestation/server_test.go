@@ -0,0 +1,132 @@
+package remoteattestation
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ChainSafe/log15"
+	"github.com/mapprotocol/compass/keystore"
+)
+
+func startServer(t *testing.T, cfg Config) (*Server, *http.Client) {
+	t.Helper()
+	cfg.Addr = "127.0.0.1:0"
+	s, err := NewServer(cfg, Simulated{}, log15.New("system", "attestation"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Stop)
+	// the client trusts the server through the report, not a CA
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	return s, client
+}
+
+func get(t *testing.T, client *http.Client, s *Server, path string) ([]byte, *http.Response) {
+	t.Helper()
+	resp, err := client.Get("https://" + s.Addr().String() + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data, resp
+}
+
+func TestAttestedCertificate(t *testing.T) {
+	s, client := startServer(t, Config{CertLifetime: time.Hour, CertRotation: time.Minute})
+	cert, resp := get(t, client, s, "/cert")
+	report, _ := get(t, client, s, "/report")
+
+	data, err := VerifySimulated(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(cert)
+	if !bytes.Equal(data, hash[:]) {
+		t.Error("Report doesn't bind the certificate")
+	}
+	if !bytes.Equal(resp.TLS.PeerCertificates[0].Raw, cert) {
+		t.Error("TLS certificate isn't the attested one")
+	}
+	if left := time.Until(resp.TLS.PeerCertificates[0].NotAfter); left > time.Hour || left < 59*time.Minute {
+		t.Errorf("Unexpected certificate lifetime, %s left", left)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	if _, err := NewServer(Config{CertLifetime: time.Minute, CertRotation: time.Minute}, Simulated{}, log15.New()); err == nil {
+		t.Error("Expected a rotation as long as the lifetime to be refused")
+	}
+
+	s, client := startServer(t, Config{CertLifetime: time.Minute, CertRotation: 50 * time.Millisecond})
+	first, _ := get(t, client, s, "/cert")
+	time.Sleep(200 * time.Millisecond)
+	client.CloseIdleConnections()
+	second, resp := get(t, client, s, "/cert")
+	if bytes.Equal(first, second) {
+		t.Error("Expected the certificate to be rotated")
+	}
+	if !bytes.Equal(resp.TLS.PeerCertificates[0].Raw, second) {
+		t.Error("TLS certificate isn't the rotated one")
+	}
+}
+
+func TestProvisionSecret(t *testing.T) {
+	t.Setenv(keystore.EnvPassword, "")
+	s, client := startServer(t, Config{CertLifetime: time.Hour, CertRotation: time.Minute,
+		Accounts: []string{"0xAbCd000000000000000000000000000000000001"}})
+	url := "https://" + s.Addr().String() + "/secret"
+
+	resp, err := client.Get(url + "?s=password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected a secret in the query to be refused, got %s", resp.Status)
+	}
+
+	for body, status := range map[string]int{
+		`{"address":"0x1111000000000000000000000000000000000001","password":"x"}`: http.StatusForbidden,
+		`{"address":"0xabcd000000000000000000000000000000000001"}`:                http.StatusBadRequest,
+		`password`: http.StatusBadRequest,
+		`{"address":"0xabcd000000000000000000000000000000000001","password":"secret"}`: http.StatusNoContent,
+	} {
+		resp, err := client.Post(url, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("%s: got %s, want %d", body, resp.Status, status)
+		}
+	}
+
+	// The password can't be replaced once provisioned
+	resp, err = client.Post(url, "application/json",
+		strings.NewReader(`{"address":"0xABCD000000000000000000000000000000000001","password":"other"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected a second password to be refused, got %s", resp.Status)
+	}
+
+	pswd, err := (keystore.PasswordSource{}).Password("0xAbCd000000000000000000000000000000000001", "key.json")
+	if err != nil || string(pswd) != "secret" {
+		t.Errorf("Unexpected provisioned password %q, err %v", pswd, err)
+	}
+}